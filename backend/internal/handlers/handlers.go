@@ -3,6 +3,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"radare-datarecon/backend/internal/reconciliation"
 	"sync"
@@ -34,11 +37,194 @@ type CurrentValues struct {
 // Contém todos os dados necessários para realizar o processo de reconciliação.
 type ReconciliationRequest struct {
 	// Measurements é um slice de float64 representando os valores medidos.
-	Measurements []float64 `json:"measurements"`
-	// Tolerances é um slice de float64 representando as tolerâncias percentuais para cada medição.
+	// Um elemento null marca a variável como não medida.
+	Measurements reconciliation.Values `json:"measurements"`
+	// Tolerances é um slice de float64 representando as tolerâncias de cada medição,
+	// interpretadas de acordo com o modo de incerteza (percentuais por padrão).
 	Tolerances []float64 `json:"tolerances"`
+	// UncertaintyMode é o modo de incerteza global: "relative" (padrão), "absolute" ou "span".
+	UncertaintyMode reconciliation.UncertaintyMode `json:"uncertaintyMode,omitempty"`
+	// CoverageFactor é o fator de abrangência (k) global das tolerâncias, ex: 2 para faixas de 95%
+	// ou 3 para faixas de 99,7%. Se omitido, a tolerância é tratada como um desvio padrão (k = 1).
+	CoverageFactor float64 `json:"coverageFactor,omitempty"`
+	// Uncertainties define opcionalmente o modo de incerteza, a faixa do instrumento e o fator de
+	// abrangência de cada medição.
+	Uncertainties []reconciliation.Uncertainty `json:"uncertainties,omitempty"`
 	// Constraints é uma matriz (slice de slices de float64) que representa as equações de restrição linear.
+	// Pode ficar vazia quando NonlinearConstraints é informado.
 	Constraints [][]float64 `json:"constraints"`
+	// NonlinearConstraints lista as restrições polinomiais (ex: balanços de componente e de energia).
+	// Quando informadas, a reconciliação é não linear e as linhas de Constraints são somadas a elas.
+	NonlinearConstraints []reconciliation.Polynomial `json:"nonlinearConstraints,omitempty"`
+	// MaxIterations e ConvergenceTolerance controlam a reconciliação não linear. Se omitidos, usam os padrões.
+	MaxIterations        int     `json:"maxIterations,omitempty"`
+	ConvergenceTolerance float64 `json:"convergenceTolerance,omitempty"`
+	// StartingPoint é o ponto inicial opcional da reconciliação não linear, com um elemento por
+	// variável. Um elemento null usa a medição; as variáveis não medidas precisam de um valor.
+	StartingPoint reconciliation.Values `json:"startingPoint,omitempty"`
+	// Covariance é a matriz de covariância completa (n x n) opcional dos erros das medições.
+	// Deve ser simétrica e definida positiva; quando informada, as tolerâncias são ignoradas.
+	Covariance [][]float64 `json:"covariance,omitempty"`
+	// Covariances lista opcionalmente as covariâncias fora da diagonal entre pares de medições.
+	Covariances []reconciliation.CovarianceEntry `json:"covariances,omitempty"`
+	// Unmeasured lista os índices das variáveis não medidas, além das marcadas com null em Measurements.
+	Unmeasured []int `json:"unmeasured,omitempty"`
+	// Missing é a política das medições null: "unmeasured" (padrão) trata as variáveis como não
+	// medidas, e "reject" recusa a requisição com os índices das medições ausentes. O padrão da
+	// API difere do da biblioteca (reconciliation.MissingReject) porque null sempre indicou uma
+	// variável não medida nas requisições.
+	Missing reconciliation.MissingPolicy `json:"missing,omitempty"`
+	// Fixed lista os índices das variáveis de valor exato, além das de tolerância zero. Elas não
+	// são ajustadas e as demais são reconciliadas em torno delas.
+	Fixed []int `json:"fixed,omitempty"`
+	// Lower e Upper são os limites inferiores e superiores opcionais dos valores reconciliados,
+	// com um elemento por variável. Um elemento null indica uma variável sem aquele limite.
+	Lower reconciliation.Values `json:"lower,omitempty"`
+	Upper reconciliation.Values `json:"upper,omitempty"`
+	// RHS é o lado direito opcional (c) das restrições B*x = c, com um elemento por restrição.
+	// Se omitido, as restrições são homogêneas (B*x = 0).
+	RHS []float64 `json:"rhs,omitempty"`
+	// Confidence é o nível de confiança (entre 0 e 1) dos testes estatísticos. Se omitido, usa 0.95.
+	Confidence float64 `json:"confidence,omitempty"`
+	// SerialElimination ativa a eliminação serial para identificar as medições com erros grosseiros.
+	SerialElimination bool `json:"serialElimination,omitempty"`
+	// GLR ativa o teste da razão de verossimilhança generalizada, que estima a magnitude do erro
+	// grosseiro mais provável (viés de medição ou vazamento num nó) e o compensa.
+	GLR bool `json:"glr,omitempty"`
+	// Solver é o método de solução: "auto" (padrão), "dense" ou "sparse".
+	Solver reconciliation.Solver `json:"solver,omitempty"`
+	// OmitCovariance dispensa a matriz de covariância completa na resposta, útil em plantas grandes.
+	// Com o método "sparse" ela é sempre dispensada.
+	OmitCovariance bool `json:"omitCovariance,omitempty"`
+	// DropRedundant descarta as linhas de restrição linearmente dependentes em vez de rejeitá-las.
+	DropRedundant bool `json:"dropRedundant,omitempty"`
+	// Estimator é o estimador: "leastSquares" (padrão), "huber", "cauchy", "fair" ou "welsch".
+	Estimator reconciliation.Estimator `json:"estimator,omitempty"`
+	// TuningConstant é a constante de ajuste do estimador robusto. Se omitida, usa a padrão do estimador.
+	TuningConstant float64 `json:"tuningConstant,omitempty"`
+	// SteadyStateWindow são os valores recentes de cada variável, uma série por medição, igualmente
+	// espaçados no tempo. Quando informada, o regime permanente é verificado antes da reconciliação.
+	SteadyStateWindow [][]float64 `json:"steadyStateWindow,omitempty"`
+	// RequireSteadyState recusa a reconciliação quando a janela é transitória. Do contrário, o
+	// resultado do teste é apenas informado na resposta.
+	RequireSteadyState bool `json:"requireSteadyState,omitempty"`
+	// MonteCarlo ativa a propagação de incerteza por Monte Carlo, com as suas configurações.
+	MonteCarlo *MonteCarloRequest `json:"monteCarlo,omitempty"`
+	// Screening ativa a triagem de qualidade das medições antes da reconciliação, com a sua
+	// configuração e os dados do historiador.
+	Screening *ScreeningRequest `json:"screening,omitempty"`
+}
+
+// ScreeningRequest configura a triagem das medições: faixa física, valor congelado, idade e
+// pico. Os campos da configuração e dos dados aparecem no mesmo nível do JSON. Os registros de
+// tempo são em segundos Unix quando now é omitido, que passa a ser o instante atual do servidor.
+type ScreeningRequest struct {
+	reconciliation.ScreeningConfig
+	reconciliation.ScreeningData
+}
+
+// MonteCarloRequest configura a propagação de incerteza por Monte Carlo. Os campos omitidos
+// usam os padrões da biblioteca.
+type MonteCarloRequest struct {
+	// Samples é o número de conjuntos de medições sorteados, até maxMonteCarloSamples.
+	Samples int `json:"samples,omitempty"`
+	// Seed é a semente do gerador de números aleatórios; a mesma semente reproduz o resultado.
+	Seed *uint64 `json:"seed,omitempty"`
+	// Bins é o número de classes dos histogramas.
+	Bins int `json:"bins,omitempty"`
+}
+
+// maxMonteCarloSamples limita o número de amostras de Monte Carlo de uma requisição, para que
+// uma única requisição não ocupe o servidor por tempo indeterminado.
+const maxMonteCarloSamples = 100000
+
+// ReconciliationResponse representa o corpo da resposta do endpoint de reconciliação.
+// Os campos do resultado da reconciliação aparecem no nível superior do JSON, seguidos
+// dos diagnósticos opcionais solicitados na requisição.
+type ReconciliationResponse struct {
+	*reconciliation.Result
+	// SerialElimination é o resultado da eliminação serial, presente apenas quando solicitada.
+	SerialElimination *reconciliation.EliminationResult `json:"serialElimination,omitempty"`
+	// GLR é o resultado do teste da razão de verossimilhança generalizada, presente apenas quando solicitado.
+	GLR *reconciliation.GLRResult `json:"glr,omitempty"`
+	// Convergence descreve a convergência da reconciliação não linear, presente apenas nela.
+	Convergence *reconciliation.Convergence `json:"convergence,omitempty"`
+	// SteadyState é o resultado do teste de regime permanente, presente apenas quando a janela é informada.
+	SteadyState *reconciliation.SteadyStateResult `json:"steadyState,omitempty"`
+	// MonteCarlo é a distribuição empírica dos valores reconciliados, presente apenas quando solicitada.
+	MonteCarlo *reconciliation.MonteCarloResult `json:"monteCarlo,omitempty"`
+}
+
+// SensitivityRequest representa o corpo da requisição para o endpoint de sensibilidade. Contém
+// os campos da reconciliação e as saídas cujos ganhos são calculados.
+type SensitivityRequest struct {
+	ReconciliationRequest
+	// Outputs define opcionalmente as saídas como combinações lineares das variáveis, uma linha
+	// por saída (ex: [-1, 0, 1] para a produção líquida x2 - x0). Se omitido, as saídas são as
+	// próprias variáveis reconciliadas.
+	Outputs [][]float64 `json:"outputs,omitempty"`
+}
+
+// DesignRequest representa o corpo da requisição para o endpoint de projeto da rede de
+// instrumentos. Contém os campos da reconciliação, que descrevem a rede atual (as medições
+// são os valores nominais e as variáveis sem medidor são null), e os candidatos.
+type DesignRequest struct {
+	ReconciliationRequest
+	// Candidates são os instrumentos candidatos, com a variável, o desvio padrão e o custo.
+	Candidates []reconciliation.Candidate `json:"candidates"`
+	// Budget é o orçamento da seleção gulosa. Zero apenas avalia os candidatos.
+	Budget float64 `json:"budget,omitempty"`
+}
+
+// BatchReconciliationRequest representa o corpo da requisição para o endpoint de
+// reconciliação em lote. Contém os campos da reconciliação, compartilhados por todos os
+// conjuntos, exceto as medições e as tolerâncias, que vêm de cada conjunto.
+type BatchReconciliationRequest struct {
+	ReconciliationRequest
+	// Datasets são os conjuntos de medições, no mesmo formato {values, tolerances} enviado
+	// pelo webapp. Um elemento null em values é uma variável não medida naquele conjunto.
+	Datasets []reconciliation.Dataset `json:"unreconciledata"`
+}
+
+// maxBatchDatasets limita o número de conjuntos de uma requisição em lote.
+const maxBatchDatasets = 10000
+
+// VarianceRequest representa o corpo da requisição para o endpoint de estimação das variâncias.
+// Contém os campos da reconciliação, com as tolerâncias configuradas a comparar, e o histórico
+// de vetores de medição no lugar das medições.
+type VarianceRequest struct {
+	ReconciliationRequest
+	// History são os vetores de medição do historiador, um por instante. Os elementos das
+	// variáveis não medidas são ignorados e podem ser null.
+	History []reconciliation.Values `json:"history"`
+}
+
+// maxVarianceSamples limita o número de vetores do histórico de uma requisição.
+const maxVarianceSamples = 100000
+
+// DynamicReconciliationRequest representa o corpo da requisição para o endpoint de
+// reconciliação dinâmica, uma série temporal de medições filtrada com as restrições do processo.
+type DynamicReconciliationRequest struct {
+	// Samples são as amostras em ordem crescente de tempo. Um elemento null em uma medição é uma
+	// medição ausente naquele instante.
+	Samples []reconciliation.Sample `json:"samples"`
+	// Tolerances são as tolerâncias de cada variável, interpretadas de acordo com o modo de incerteza.
+	Tolerances []float64 `json:"tolerances"`
+	// UncertaintyMode, CoverageFactor e Uncertainties têm o mesmo significado que na reconciliação estática.
+	UncertaintyMode reconciliation.UncertaintyMode `json:"uncertaintyMode,omitempty"`
+	CoverageFactor  float64                        `json:"coverageFactor,omitempty"`
+	Uncertainties   []reconciliation.Uncertainty   `json:"uncertainties,omitempty"`
+	// Constraints são as restrições algébricas válidas a cada instante (ex: balanços dos nós sem
+	// acúmulo). Podem ficar vazias quando só há tanques.
+	Constraints [][]float64 `json:"constraints,omitempty"`
+	// RHS é o lado direito opcional das restrições, com um elemento por restrição.
+	RHS []float64 `json:"rhs,omitempty"`
+	// Tanks são os tanques cujos inventários (holdups) são estados do filtro.
+	Tanks []reconciliation.Tank `json:"tanks,omitempty"`
+	// ProcessNoise é o desvio padrão da variação de cada variável por unidade de tempo.
+	ProcessNoise []float64 `json:"processNoise"`
+	// Confidence é o nível de confiança (entre 0 e 1) das faixas de incerteza. Se omitido, usa 0.95.
+	Confidence float64 `json:"confidence,omitempty"`
 }
 
 var (
@@ -178,38 +364,577 @@ func ReconcileData(w http.ResponseWriter, r *http.Request) error {
 		return nil
 	}
 
-	// Converte a matriz de restrições de [][]float64 para o tipo *mat.Dense esperado pela biblioteca gonum.
+	constraints, opts, ok := reconciliationOptions(w, &req)
+	if !ok {
+		return nil
+	}
+	nonlinear := req.NonlinearConstraints != nil
+
+	// Verifica o regime permanente antes de reconciliar, se a janela de valores foi informada.
+	var steadyState *reconciliation.SteadyStateResult
+	if req.SteadyStateWindow != nil {
+		if len(req.SteadyStateWindow) != len(req.Measurements) {
+			http.Error(w, "A janela de regime permanente deve ter uma série por medição", http.StatusBadRequest)
+			return nil
+		}
+		confidence := req.Confidence
+		if confidence == 0 {
+			confidence = reconciliation.DefaultConfidence
+		}
+		var err error
+		if steadyState, err = reconciliation.SteadyStateTest(req.SteadyStateWindow, confidence); err != nil {
+			http.Error(w, "Janela de regime permanente inválida: "+err.Error(), http.StatusBadRequest)
+			return nil
+		}
+		if req.RequireSteadyState && !steadyState.Steady {
+			http.Error(w, fmt.Sprintf("O processo não está em regime permanente: variáveis %v", steadyState.Transient), http.StatusUnprocessableEntity)
+			return nil
+		}
+	}
+
+	if nonlinear {
+		response, ok := reconcileNonlinear(w, &req, opts)
+		if !ok {
+			return nil
+		}
+		response.SteadyState = steadyState
+		return writeJSON(w, response)
+	}
+
+	// Chama a função de reconciliação principal com os dados da requisição.
+	result, err := reconciliation.Reconcile(req.Measurements, req.Tolerances, constraints, opts...)
+	if err != nil {
+		// Se a reconciliação falhar, retorna 400 para dados inválidos e 500 para as demais falhas.
+		http.Error(w, "Erro ao reconciliar os dados: "+err.Error(), reconciliationStatus(err))
+		return nil
+	}
+
+	response := ReconciliationResponse{Result: result, SteadyState: steadyState}
+
+	// Propaga a incerteza por Monte Carlo, se solicitado.
+	if req.MonteCarlo != nil {
+		monteCarlo, err := reconciliation.MonteCarlo(req.Measurements, req.Tolerances, constraints, opts...)
+		if err != nil {
+			http.Error(w, "Erro no Monte Carlo: "+err.Error(), reconciliationStatus(err))
+			return nil
+		}
+		response.MonteCarlo = monteCarlo
+	}
+
+	// Executa a eliminação serial, se solicitada, para apontar as medições suspeitas.
+	if req.SerialElimination {
+		elimination, err := reconciliation.SerialElimination(req.Measurements, req.Tolerances, constraints, opts...)
+		if err != nil {
+			http.Error(w, "Erro na eliminação serial: "+err.Error(), reconciliationStatus(err))
+			return nil
+		}
+		response.SerialElimination = elimination
+	}
+
+	// Estima a magnitude do erro grosseiro mais provável pelo teste GLR, se solicitado.
+	if req.GLR {
+		glr, err := reconciliation.GLRTest(req.Measurements, req.Tolerances, constraints, opts...)
+		if err != nil {
+			http.Error(w, "Erro no teste GLR: "+err.Error(), reconciliationStatus(err))
+			return nil
+		}
+		response.GLR = glr
+	}
+
+	// Prepara e envia a resposta de sucesso em formato JSON, com o resultado estatístico completo.
+	return writeJSON(w, response)
+}
+
+// reconciliationOptions converte a matriz de restrições da requisição para o tipo *mat.Dense
+// esperado pela biblioteca gonum e monta as opções da reconciliação a partir dos campos
+// opcionais. Em caso de erro, a resposta já foi escrita e ok é falso.
+func reconciliationOptions(w http.ResponseWriter, req *ReconciliationRequest) (constraints *mat.Dense, opts []reconciliation.Option, ok bool) {
+	nonlinear := req.NonlinearConstraints != nil
 	rows := len(req.Constraints)
-	if rows == 0 {
+	if rows == 0 && !nonlinear {
 		http.Error(w, "A matriz de restrições não pode estar vazia", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if rows > 0 {
+		cols := len(req.Constraints[0])
+		constraints = mat.NewDense(rows, cols, nil)
+		for i, row := range req.Constraints {
+			if len(row) != cols {
+				http.Error(w, "Todas as linhas da matriz de restrições devem ter o mesmo comprimento", http.StatusBadRequest)
+				return nil, nil, false
+			}
+			constraints.SetRow(i, row)
+		}
+	}
+
+	if req.RHS != nil {
+		if len(req.RHS) != rows {
+			http.Error(w, "O lado direito deve ter um elemento por linha da matriz de restrições", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		opts = append(opts, reconciliation.WithRHS(req.RHS))
+	}
+	// As variáveis não medidas são as listadas explicitamente e, com a política padrão, as que
+	// têm medição null.
+	listed := make(map[int]bool, len(req.Unmeasured))
+	for _, i := range req.Unmeasured {
+		if i < 0 || i >= len(req.Measurements) {
+			http.Error(w, "Índice de variável não medida fora do intervalo", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		listed[i] = true
+	}
+	if len(req.Unmeasured) > 0 {
+		opts = append(opts, reconciliation.WithUnmeasured(req.Unmeasured))
+	}
+	missing := req.Missing
+	switch missing {
+	case "":
+		// O padrão da API (ver ReconciliationRequest.Missing).
+		missing = reconciliation.MissingUnmeasured
+	case reconciliation.MissingReject:
+		var indices []int
+		for i, m := range req.Measurements {
+			if math.IsNaN(m) && !listed[i] {
+				indices = append(indices, i)
+			}
+		}
+		if len(indices) > 0 {
+			http.Error(w, fmt.Sprintf("Medições ausentes nos índices %v", indices), http.StatusBadRequest)
+			return nil, nil, false
+		}
+	case reconciliation.MissingUnmeasured:
+	default:
+		http.Error(w, "A política de medições ausentes deve ser \"unmeasured\" ou \"reject\"", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	opts = append(opts, reconciliation.WithMissing(missing))
+	for _, i := range req.Fixed {
+		if i < 0 || i >= len(req.Measurements) {
+			http.Error(w, "Índice de variável fixa fora do intervalo", http.StatusBadRequest)
+			return nil, nil, false
+		}
+	}
+	if len(req.Fixed) > 0 {
+		opts = append(opts, reconciliation.WithFixed(req.Fixed))
+	}
+	if req.UncertaintyMode != "" {
+		opts = append(opts, reconciliation.WithUncertaintyMode(req.UncertaintyMode))
+	}
+	if req.CoverageFactor != 0 {
+		if req.CoverageFactor < 0 {
+			http.Error(w, "O fator de abrangência deve ser positivo", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		opts = append(opts, reconciliation.WithCoverageFactor(req.CoverageFactor))
+	}
+	if req.Uncertainties != nil {
+		if len(req.Uncertainties) != len(req.Measurements) {
+			http.Error(w, "As incertezas devem ter um elemento por medição", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		opts = append(opts, reconciliation.WithUncertainties(req.Uncertainties))
+	}
+	if req.Covariance != nil {
+		n := len(req.Measurements)
+		if len(req.Covariance) != n {
+			http.Error(w, "A matriz de covariância deve ter uma linha por medição", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		covariance := mat.NewDense(n, n, nil)
+		for i, row := range req.Covariance {
+			if len(row) != n {
+				http.Error(w, "A matriz de covariância deve ser quadrada", http.StatusBadRequest)
+				return nil, nil, false
+			}
+			covariance.SetRow(i, row)
+		}
+		opts = append(opts, reconciliation.WithCovariance(covariance))
+	}
+	if req.Covariances != nil {
+		opts = append(opts, reconciliation.WithCovariances(req.Covariances))
+	}
+	if req.Lower != nil || req.Upper != nil {
+		if (req.Lower != nil && len(req.Lower) != len(req.Measurements)) || (req.Upper != nil && len(req.Upper) != len(req.Measurements)) {
+			http.Error(w, "Os limites devem ter um elemento por medição", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		opts = append(opts, reconciliation.WithBounds(req.Lower, req.Upper))
+	}
+	if req.Solver != "" {
+		opts = append(opts, reconciliation.WithSolver(req.Solver))
+	}
+	if req.OmitCovariance {
+		opts = append(opts, reconciliation.WithReconciledCovariance(false))
+	}
+	if req.DropRedundant {
+		opts = append(opts, reconciliation.WithDropRedundantConstraints())
+	}
+	if req.Estimator != "" {
+		opts = append(opts, reconciliation.WithEstimator(req.Estimator))
+	}
+	if req.TuningConstant != 0 {
+		if req.TuningConstant < 0 {
+			http.Error(w, "A constante de ajuste deve ser positiva", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		opts = append(opts, reconciliation.WithTuningConstant(req.TuningConstant))
+	}
+	if req.Confidence != 0 {
+		if req.Confidence < 0 || req.Confidence >= 1 {
+			http.Error(w, "O nível de confiança deve estar entre 0 e 1", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		opts = append(opts, reconciliation.WithConfidence(req.Confidence))
+	}
+	if mc := req.MonteCarlo; mc != nil {
+		if mc.Samples < 0 || mc.Samples > maxMonteCarloSamples || mc.Bins < 0 {
+			http.Error(w, fmt.Sprintf("O Monte Carlo aceita até %d amostras e um número positivo de classes", maxMonteCarloSamples), http.StatusBadRequest)
+			return nil, nil, false
+		}
+		if mc.Samples != 0 {
+			opts = append(opts, reconciliation.WithSamples(mc.Samples))
+		}
+		if mc.Seed != nil {
+			opts = append(opts, reconciliation.WithSeed(*mc.Seed))
+		}
+		if mc.Bins != 0 {
+			opts = append(opts, reconciliation.WithHistogramBins(mc.Bins))
+		}
+	}
+	// Faz a triagem das medições; as sinalizadas são excluídas ou rebaixadas na reconciliação.
+	if sc := req.Screening; sc != nil {
+		data := sc.ScreeningData
+		if data.Now == 0 {
+			data.Now = float64(time.Now().Unix())
+		}
+		screening, err := reconciliation.Screen(req.Measurements, data, sc.ScreeningConfig)
+		if err != nil {
+			http.Error(w, "Triagem inválida: "+err.Error(), http.StatusBadRequest)
+			return nil, nil, false
+		}
+		opts = append(opts, reconciliation.WithScreening(screening))
+	}
+
+	return constraints, opts, true
+}
+
+// reconcileNonlinear executa a reconciliação não linear de uma requisição com restrições
+// polinomiais. As linhas de restrição linear, se houver, entram como polinômios de primeiro
+// grau. Em caso de erro, a resposta já foi escrita e ok é falso.
+func reconcileNonlinear(w http.ResponseWriter, req *ReconciliationRequest, opts []reconciliation.Option) (response ReconciliationResponse, ok bool) {
+	if req.SerialElimination || req.GLR || req.RHS != nil {
+		http.Error(w, "A eliminação serial, o teste GLR e o lado direito não se aplicam às restrições não lineares", http.StatusBadRequest)
+		return response, false
+	}
+	n := len(req.Measurements)
+	polynomials := make([]reconciliation.Polynomial, 0, len(req.Constraints)+len(req.NonlinearConstraints))
+	for _, row := range req.Constraints {
+		if len(row) != n {
+			http.Error(w, "As linhas de restrição devem ter um elemento por medição", http.StatusBadRequest)
+			return response, false
+		}
+		polynomials = append(polynomials, reconciliation.LinearPolynomial(row, 0))
+	}
+	polynomials = append(polynomials, req.NonlinearConstraints...)
+	constraints := make([]reconciliation.Constraint, len(polynomials))
+	for k, p := range polynomials {
+		if err := p.Validate(n); err != nil {
+			http.Error(w, "Restrição não linear inválida: "+err.Error(), http.StatusBadRequest)
+			return response, false
+		}
+		constraints[k] = p.Constraint()
+	}
+
+	if req.MaxIterations != 0 {
+		opts = append(opts, reconciliation.WithMaxIterations(req.MaxIterations))
+	}
+	if req.ConvergenceTolerance != 0 {
+		opts = append(opts, reconciliation.WithConvergenceTolerance(req.ConvergenceTolerance))
+	}
+	if req.StartingPoint != nil {
+		opts = append(opts, reconciliation.WithStartingPoint(req.StartingPoint))
+	}
+
+	result, err := reconciliation.ReconcileNonlinear(req.Measurements, req.Tolerances, constraints, opts...)
+	if err != nil {
+		http.Error(w, "Erro ao reconciliar os dados: "+err.Error(), reconciliationStatus(err))
+		return response, false
+	}
+	response = ReconciliationResponse{Result: result.Result, Convergence: &result.Convergence}
+	if req.MonteCarlo != nil {
+		if response.MonteCarlo, err = reconciliation.MonteCarloNonlinear(req.Measurements, req.Tolerances, constraints, opts...); err != nil {
+			http.Error(w, "Erro no Monte Carlo: "+err.Error(), reconciliationStatus(err))
+			return response, false
+		}
+	}
+	return response, true
+}
+
+// Sensitivity é o manipulador para o endpoint POST /api/reconcile/sensitivity.
+// Ele retorna a matriz de ganhos das saídas reconciliadas em relação a cada medição e a
+// contribuição de cada medição para a variância das saídas.
+func Sensitivity(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return nil
+	}
+
+	var req SensitivityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Corpo da requisição inválido: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if req.NonlinearConstraints != nil {
+		http.Error(w, "A sensibilidade só se aplica às restrições lineares", http.StatusBadRequest)
+		return nil
+	}
+	constraints, opts, ok := reconciliationOptions(w, &req.ReconciliationRequest)
+	if !ok {
+		return nil
+	}
+
+	var outputs *mat.Dense
+	if len(req.Outputs) > 0 {
+		n := len(req.Measurements)
+		outputs = mat.NewDense(len(req.Outputs), n, nil)
+		for i, row := range req.Outputs {
+			if len(row) != n {
+				http.Error(w, "As saídas devem ter um coeficiente por medição", http.StatusBadRequest)
+				return nil
+			}
+			outputs.SetRow(i, row)
+		}
+	}
+
+	result, err := reconciliation.Sensitivity(req.Measurements, req.Tolerances, constraints, outputs, opts...)
+	if err != nil {
+		http.Error(w, "Erro ao calcular a sensibilidade: "+err.Error(), reconciliationStatus(err))
+		return nil
+	}
+	return writeJSON(w, result)
+}
+
+// DesignSensors é o manipulador para o endpoint POST /api/reconcile/design.
+// Ele avalia o ganho de precisão e de observabilidade de cada instrumento candidato e escolhe
+// os candidatos dentro do orçamento.
+func DesignSensors(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return nil
+	}
+
+	var req DesignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Corpo da requisição inválido: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if req.NonlinearConstraints != nil {
+		http.Error(w, "O projeto da rede de instrumentos só se aplica às restrições lineares", http.StatusBadRequest)
+		return nil
+	}
+	constraints, opts, ok := reconciliationOptions(w, &req.ReconciliationRequest)
+	if !ok {
+		return nil
+	}
+	if len(req.Candidates) == 0 {
+		http.Error(w, "A lista de candidatos não pode estar vazia", http.StatusBadRequest)
+		return nil
+	}
+	if req.Budget < 0 {
+		http.Error(w, "O orçamento não pode ser negativo", http.StatusBadRequest)
+		return nil
+	}
+
+	result, err := reconciliation.DesignSensors(req.Measurements, req.Tolerances, constraints, req.Candidates, req.Budget, opts...)
+	if err != nil {
+		http.Error(w, "Erro no projeto da rede de instrumentos: "+err.Error(), reconciliationStatus(err))
+		return nil
+	}
+	return writeJSON(w, result)
+}
+
+// ReconcileBatch é o manipulador para o endpoint POST /api/reconcile/batch.
+// Ele reconcilia vários conjuntos de medições com a mesma matriz de restrições e retorna o
+// resultado ou o erro de cada conjunto, sem que a falha de um interrompa os demais.
+func ReconcileBatch(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return nil
+	}
+
+	var req BatchReconciliationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Corpo da requisição inválido: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if len(req.Datasets) == 0 || len(req.Datasets) > maxBatchDatasets {
+		http.Error(w, fmt.Sprintf("O lote deve ter entre 1 e %d conjuntos de medições", maxBatchDatasets), http.StatusBadRequest)
+		return nil
+	}
+	if req.Measurements != nil || req.Tolerances != nil {
+		http.Error(w, "No lote, as medições e as tolerâncias vêm de cada conjunto", http.StatusBadRequest)
+		return nil
+	}
+	if req.NonlinearConstraints != nil || req.SerialElimination || req.GLR || req.MonteCarlo != nil || req.SteadyStateWindow != nil || req.Screening != nil {
+		http.Error(w, "O lote só se aplica às restrições lineares, sem diagnósticos opcionais", http.StatusBadRequest)
+		return nil
+	}
+	// As opções por medição (incertezas, covariâncias, limites) são validadas contra o número de
+	// colunas das restrições; cada conjunto tem as suas próprias medições.
+	if len(req.Constraints) > 0 {
+		req.Measurements = make([]float64, len(req.Constraints[0]))
+	}
+	constraints, opts, ok := reconciliationOptions(w, &req.ReconciliationRequest)
+	if !ok {
+		return nil
+	}
+
+	result, err := reconciliation.ReconcileBatch(req.Datasets, constraints, opts...)
+	if err != nil {
+		http.Error(w, "Erro na reconciliação em lote: "+err.Error(), reconciliationStatus(err))
+		return nil
+	}
+	return writeJSON(w, result)
+}
+
+// EstimateVariances é o manipulador para o endpoint POST /api/reconcile/variance.
+// Ele estima os desvios padrão dos erros de medição a partir de um histórico, pelos métodos
+// direto e indireto, e os compara com as tolerâncias configuradas.
+func EstimateVariances(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return nil
+	}
+
+	var req VarianceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Corpo da requisição inválido: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if len(req.History) == 0 || len(req.History) > maxVarianceSamples {
+		http.Error(w, fmt.Sprintf("O histórico deve ter entre 1 e %d vetores de medição", maxVarianceSamples), http.StatusBadRequest)
+		return nil
+	}
+	if req.Measurements != nil {
+		http.Error(w, "Na estimação das variâncias, as medições vêm do histórico", http.StatusBadRequest)
+		return nil
+	}
+	if req.NonlinearConstraints != nil || req.SerialElimination || req.GLR || req.MonteCarlo != nil || req.SteadyStateWindow != nil || req.Screening != nil {
+		http.Error(w, "A estimação das variâncias só se aplica às restrições lineares, sem diagnósticos opcionais", http.StatusBadRequest)
+		return nil
+	}
+	// As opções por medição são validadas contra o número de colunas das restrições.
+	if len(req.Constraints) > 0 {
+		req.Measurements = make([]float64, len(req.Constraints[0]))
+	}
+	constraints, opts, ok := reconciliationOptions(w, &req.ReconciliationRequest)
+	if !ok {
 		return nil
 	}
-	cols := len(req.Constraints[0])
-	constraints := mat.NewDense(rows, cols, nil)
-	for i, row := range req.Constraints {
-		if len(row) != cols {
-			http.Error(w, "Todas as linhas da matriz de restrições devem ter o mesmo comprimento", http.StatusBadRequest)
+
+	history := make([][]float64, len(req.History))
+	for t, m := range req.History {
+		history[t] = m
+	}
+	result, err := reconciliation.EstimateVariances(history, req.Tolerances, constraints, opts...)
+	if err != nil {
+		http.Error(w, "Erro na estimação das variâncias: "+err.Error(), reconciliationStatus(err))
+		return nil
+	}
+	return writeJSON(w, result)
+}
+
+// ReconcileDynamicData é o manipulador para o endpoint POST /api/reconcile/dynamic.
+// Ele filtra uma série temporal de medições e retorna a trajetória reconciliada com as faixas
+// de incerteza.
+func ReconcileDynamicData(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return nil
+	}
+
+	var req DynamicReconciliationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Corpo da requisição inválido: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	model := reconciliation.DynamicModel{Tanks: req.Tanks, ProcessNoise: req.ProcessNoise}
+	if rows := len(req.Constraints); rows > 0 {
+		cols := len(req.Constraints[0])
+		model.Constraints = mat.NewDense(rows, cols, nil)
+		for i, row := range req.Constraints {
+			if len(row) != cols {
+				http.Error(w, "Todas as linhas da matriz de restrições devem ter o mesmo comprimento", http.StatusBadRequest)
+				return nil
+			}
+			model.Constraints.SetRow(i, row)
+		}
+	}
+
+	var opts []reconciliation.Option
+	if req.RHS != nil {
+		if len(req.RHS) != len(req.Constraints) {
+			http.Error(w, "O lado direito deve ter um elemento por linha da matriz de restrições", http.StatusBadRequest)
 			return nil
 		}
-		constraints.SetRow(i, row)
+		opts = append(opts, reconciliation.WithRHS(req.RHS))
+	}
+	if req.UncertaintyMode != "" {
+		opts = append(opts, reconciliation.WithUncertaintyMode(req.UncertaintyMode))
+	}
+	if req.CoverageFactor != 0 {
+		if req.CoverageFactor < 0 {
+			http.Error(w, "O fator de abrangência deve ser positivo", http.StatusBadRequest)
+			return nil
+		}
+		opts = append(opts, reconciliation.WithCoverageFactor(req.CoverageFactor))
+	}
+	if req.Uncertainties != nil {
+		if len(req.Uncertainties) != len(req.Tolerances) {
+			http.Error(w, "As incertezas devem ter um elemento por variável", http.StatusBadRequest)
+			return nil
+		}
+		opts = append(opts, reconciliation.WithUncertainties(req.Uncertainties))
+	}
+	if req.Confidence != 0 {
+		if req.Confidence < 0 || req.Confidence >= 1 {
+			http.Error(w, "O nível de confiança deve estar entre 0 e 1", http.StatusBadRequest)
+			return nil
+		}
+		opts = append(opts, reconciliation.WithConfidence(req.Confidence))
 	}
 
-	// Chama a função de reconciliação principal com os dados da requisição.
-	reconciledData, err := reconciliation.Reconcile(req.Measurements, req.Tolerances, constraints)
+	result, err := reconciliation.ReconcileDynamic(req.Samples, req.Tolerances, model, opts...)
 	if err != nil {
-		// Se a reconciliação falhar, retorna um erro de servidor interno.
-		http.Error(w, "Erro ao reconciliar os dados: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Erro ao reconciliar os dados: "+err.Error(), reconciliationStatus(err))
 		return nil
 	}
+	return writeJSON(w, result)
+}
 
-	// Prepara e envia a resposta de sucesso em formato JSON.
+// reconciliationStatus retorna o código HTTP de um erro da biblioteca de reconciliação: 400
+// para os dados inválidos da requisição (medições ausentes, restrições dependentes que não
+// podem ser descartadas, dimensões incompatíveis e as demais entradas rejeitadas pela
+// validação) e 500 para as falhas da solução.
+func reconciliationStatus(err error) int {
+	var missing *reconciliation.MissingMeasurementError
+	var rank *reconciliation.RankDeficiencyError
+	if errors.As(err, &missing) || errors.As(err, &rank) || errors.Is(err, reconciliation.ErrDimensionMismatch) ||
+		errors.Is(err, reconciliation.ErrInvalidInput) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// writeJSON escreve a resposta de sucesso em formato JSON.
+func writeJSON(w http.ResponseWriter, response any) error {
 	w.Header().Set("Content-Type", "application/json")
-	response := map[string][]float64{"reconciled": reconciledData}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		// Se a codificação da resposta falhar, o erro é retornado para o middleware.
 		return err
 	}
-
 	return nil
 }
 
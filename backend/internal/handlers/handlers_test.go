@@ -3,12 +3,16 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"testing"
+
 	"radare-datarecon/backend/internal/database"
 	"radare-datarecon/backend/internal/middleware"
 	"radare-datarecon/backend/internal/models"
-	"testing"
+	"radare-datarecon/backend/internal/reconciliation"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -145,4 +149,428 @@ func TestAuthMiddleware(t *testing.T) {
 	if status := rr.Code; status != http.StatusOK {
 		t.Errorf("middleware returned wrong status code for valid token: got %v want %v", status, http.StatusOK)
 	}
-}
\ No newline at end of file
+}
+
+// postJSON posts body to handler, marshalling it unless it is already a JSON string, and
+// decodes a successful response into T.
+func postJSON[T any](t *testing.T, handler middleware.AppHandler, body any) (*httptest.ResponseRecorder, T) {
+	t.Helper()
+	raw, ok := body.(string)
+	if !ok {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to marshal the request: %v", err)
+		}
+		raw = string(b)
+	}
+	req, _ := http.NewRequest("POST", "/api/reconcile", strings.NewReader(raw))
+	rr := httptest.NewRecorder()
+	middleware.ErrorHandler(handler).ServeHTTP(rr, req)
+
+	var resp T
+	if rr.Code == http.StatusOK {
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("handler returned invalid JSON: %v", err)
+		}
+	}
+	return rr, resp
+}
+
+// handlerCase is a request to a handler with its expected status, an optional text the
+// response body must contain and an optional check of the decoded response.
+type handlerCase[T any] struct {
+	name     string
+	body     any
+	status   int
+	contains string
+	check    func(t *testing.T, resp T)
+}
+
+// runHandlerCases runs each case as a subtest, so one failure does not hide the others.
+func runHandlerCases[T any](t *testing.T, handler middleware.AppHandler, cases []handlerCase[T]) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr, resp := postJSON[T](t, handler, tc.body)
+			if rr.Code != tc.status {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, tc.status, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tc.contains) {
+				t.Errorf("handler returned a body without %q: %s", tc.contains, rr.Body.String())
+			}
+			if tc.check != nil {
+				tc.check(t, resp)
+			}
+		})
+	}
+}
+
+func TestReconcileData(t *testing.T) {
+	reqBody := ReconciliationRequest{
+		Measurements: []float64{161, 79, 80},
+		Tolerances:   []float64{0.05, 0.01, 0.01},
+		Constraints:  [][]float64{{1, -1, -1}},
+	}
+	// A gross error on the third measurement
+	elimReq := ReconciliationRequest{
+		Measurements:      []float64{100, 60, 55, 40, 100},
+		Tolerances:        []float64{0.01, 0.01, 0.01, 0.01, 0.01},
+		Constraints:       [][]float64{{1, -1, -1, 0, 0}, {0, 0, 1, -1, 0}, {0, 1, 0, 1, -1}},
+		SerialElimination: true,
+	}
+	robustReq := elimReq
+	robustReq.SerialElimination = false
+	robustReq.Estimator = reconciliation.EstimatorWelsch
+
+	fixedReq := ReconciliationRequest{
+		Measurements: []float64{161, 79, 80},
+		Tolerances:   []float64{0, 0.01, 0.01},
+		Constraints:  [][]float64{{1, -1, -1}},
+	}
+	badFixedReq := fixedReq
+	badFixedReq.Fixed = []int{3}
+
+	covReq := reqBody
+	covReq.Covariance = [][]float64{{1, 0, 0}, {0, 1}, {0, 0, 1}}
+	rhsReq := reqBody
+	rhsReq.RHS = []float64{1, 2}
+
+	// The third tag ramps up over the window
+	steadyReq := reqBody
+	steadyReq.SteadyStateWindow = [][]float64{
+		{161, 160, 162, 161, 160, 161, 162, 160},
+		{79, 80, 79, 78, 79, 80, 79, 79},
+		{70, 72, 74, 76, 78, 80, 82, 84},
+	}
+	requiredSteadyReq := steadyReq
+	requiredSteadyReq.RequireSteadyState = true
+
+	seed := uint64(42)
+	mcReq := reqBody
+	mcReq.MonteCarlo = &MonteCarloRequest{Samples: 300, Seed: &seed, Bins: 10}
+	bigMCReq := reqBody
+	bigMCReq.MonteCarlo = &MonteCarloRequest{Samples: maxMonteCarloSamples + 1}
+
+	glrReq := elimReq
+	glrReq.SerialElimination = false
+	glrReq.GLR = true
+
+	// The third measurement is above its physical range and is excluded
+	nan := math.NaN()
+	screenReq := elimReq
+	screenReq.SerialElimination = false
+	screenReq.Screening = &ScreeningRequest{ScreeningConfig: reconciliation.ScreeningConfig{Upper: reconciliation.Values{nan, nan, 50, nan, nan}}}
+	badScreenReq := screenReq
+	badScreenReq.Screening = &ScreeningRequest{ScreeningConfig: reconciliation.ScreeningConfig{FrozenSamples: 1}}
+
+	confidenceReq := reqBody
+	confidenceReq.Confidence = 1.2
+
+	runHandlerCases(t, ReconcileData, []handlerCase[ReconciliationResponse]{
+		{name: "full result", body: reqBody, status: http.StatusOK, check: func(t *testing.T, resp ReconciliationResponse) {
+			if len(resp.Reconciled) != 3 || len(resp.Adjustments) != 3 || len(resp.Lambda) != 1 || len(resp.Covariance) != 3 {
+				t.Errorf("handler returned an incomplete result: %+v", resp)
+			}
+			if resp.Objective <= 0 {
+				t.Errorf("handler returned a non-positive objective: %v", resp.Objective)
+			}
+			if resp.GlobalTest == nil || !resp.GlobalTest.Passed || resp.GlobalTest.Confidence != 0.95 {
+				t.Errorf("handler returned a wrong global test: %+v", resp.GlobalTest)
+			}
+			if resp.NodalTest == nil || len(resp.NodalTest.Flags) != 1 || resp.NodalTest.Flags[0] {
+				t.Errorf("handler returned a wrong nodal test: %+v", resp.NodalTest)
+			}
+			if resp.SerialElimination != nil {
+				t.Errorf("handler returned a serial elimination that was not requested")
+			}
+		}},
+		{name: "serial elimination", body: elimReq, status: http.StatusOK, check: func(t *testing.T, resp ReconciliationResponse) {
+			if resp.SerialElimination == nil || len(resp.SerialElimination.Suspects) != 1 || resp.SerialElimination.Suspects[0].Index != 2 {
+				t.Errorf("handler returned a wrong serial elimination: %+v", resp.SerialElimination)
+			}
+		}},
+		{name: "robust estimator", body: robustReq, status: http.StatusOK, check: func(t *testing.T, resp ReconciliationResponse) {
+			if resp.Robust == nil || resp.Robust.Estimator != reconciliation.EstimatorWelsch || resp.Robust.Weights[2] > 0.1 {
+				t.Errorf("handler returned a wrong robust result: %+v", resp.Robust)
+			}
+		}},
+		{name: "zero reading with absolute uncertainties", body: ReconciliationRequest{
+			Measurements:    []float64{80, 79, 0},
+			Tolerances:      []float64{1, 1, 0.5},
+			Constraints:     [][]float64{{1, -1, -1}},
+			UncertaintyMode: reconciliation.UncertaintyAbsolute,
+		}, status: http.StatusOK},
+		// A zero tolerance fixes the certified inlet instead of failing
+		{name: "fixed variable", body: fixedReq, status: http.StatusOK, check: func(t *testing.T, resp ReconciliationResponse) {
+			if resp.Result == nil || resp.Reconciled[0] != 161 || resp.Classification[0] != reconciliation.ClassFixed ||
+				math.Abs(resp.Reconciled[1]+resp.Reconciled[2]-161) > 1e-6 {
+				t.Errorf("handler returned a wrong result for a fixed variable: %+v", resp.Result)
+			}
+		}},
+		{name: "out-of-range fixed index", body: badFixedReq, status: http.StatusBadRequest},
+		{name: "null measurement", body: `{
+			"measurements": [161, null, 80],
+			"tolerances": [0.05, 0, 0.01],
+			"constraints": [[1, -1, -1], [0, 1, -1]]
+		}`, status: http.StatusOK, check: func(t *testing.T, resp ReconciliationResponse) {
+			if resp.Classification[1] != reconciliation.ClassObservable {
+				t.Errorf("handler returned a wrong classification: %v", resp.Classification)
+			}
+		}},
+		{name: "rejected null measurements", body: `{
+			"measurements": [161, null, 80, null],
+			"tolerances": [0.05, 0.01, 0.01, 0.01],
+			"constraints": [[1, -1, -1, 0], [0, 1, -1, 0], [0, 0, 1, -1]],
+			"missing": "reject"
+		}`, status: http.StatusBadRequest, contains: "[1 3]"},
+		{name: "unknown missing policy", body: `{
+			"measurements": [161, 79, 80], "tolerances": [0.05, 0.01, 0.01], "constraints": [[1, -1, -1]], "missing": "ignore"
+		}`, status: http.StatusBadRequest},
+		{name: "mismatched tolerances", body: `{"measurements": [161, 79, 80], "tolerances": [0.05, 0.01], "constraints": [[1, -1, -1]]}`, status: http.StatusBadRequest},
+		{name: "unknown solver", body: `{
+			"measurements": [161, 79, 80], "tolerances": [0.05, 0.01, 0.01], "constraints": [[1, -1, -1]], "solver": "lu"
+		}`, status: http.StatusBadRequest},
+		{name: "zero measurement with relative tolerance", body: `{
+			"measurements": [80, 0, 80], "tolerances": [0.05, 0.01, 0.01], "constraints": [[1, -1, -1]]
+		}`, status: http.StatusBadRequest},
+		{name: "lower bound above upper bound", body: `{
+			"measurements": [161, 79, 80], "tolerances": [1, 1, 1], "uncertaintyMode": "absolute",
+			"constraints": [[1, -1, -1]], "lower": [0, 90, 0], "upper": [null, 50, null]
+		}`, status: http.StatusBadRequest},
+		{name: "dependent constraints", body: `{
+			"measurements": [100, 61, 41, 38], "tolerances": [1, 1, 1, 1], "uncertaintyMode": "absolute",
+			"constraints": [[1, -1, -1, 0], [0, 0, 1, -1], [1, -1, 0, -1]]
+		}`, status: http.StatusBadRequest},
+		{name: "non-negativity bounds", body: `{
+			"measurements": [10, 12, 0.5],
+			"tolerances": [1, 1, 1],
+			"uncertaintyMode": "absolute",
+			"constraints": [[1, -1, -1]],
+			"lower": [0, 0, 0],
+			"upper": [null, null, 100]
+		}`, status: http.StatusOK, check: func(t *testing.T, resp ReconciliationResponse) {
+			if len(resp.ActiveBounds) != 1 || resp.ActiveBounds[0].Index != 2 {
+				t.Errorf("handler returned wrong active bounds: %+v", resp.ActiveBounds)
+			}
+		}},
+		{name: "bilinear component balance", body: `{
+			"measurements": [101, 49, 148, 0.21, 0.58, 0.34],
+			"tolerances": [0.02, 0.02, 0.02, 0.02, 0.02, 0.02],
+			"constraints": [[1, 1, -1, 0, 0, 0]],
+			"nonlinearConstraints": [{"terms": [
+				{"coefficient": 1, "variables": [0, 3]},
+				{"coefficient": 1, "variables": [1, 4]},
+				{"coefficient": -1, "variables": [2, 5]}
+			]}]
+		}`, status: http.StatusOK, check: func(t *testing.T, resp ReconciliationResponse) {
+			if resp.Convergence == nil || !resp.Convergence.Converged || len(resp.ResidualsAfter) != 2 {
+				t.Errorf("handler returned a wrong nonlinear result: %+v", resp.Convergence)
+			}
+		}},
+		{name: "nonlinear term with an out-of-range variable", body: `{
+			"measurements": [161, 79, 80],
+			"tolerances": [0.05, 0.01, 0.01],
+			"constraints": [],
+			"nonlinearConstraints": [{"terms": [{"coefficient": 1, "variables": [0, 3]}]}]
+		}`, status: http.StatusBadRequest},
+		// The whole-plant balance repeats the unit balances
+		{name: "dropped redundant constraint", body: `{
+			"measurements": [100, 61, 41, 38],
+			"tolerances": [0.01, 0.01, 0.01, 0.01],
+			"constraints": [[1, -1, -1, 0], [0, 0, 1, -1], [1, -1, 0, -1]],
+			"dropRedundant": true
+		}`, status: http.StatusOK, check: func(t *testing.T, resp ReconciliationResponse) {
+			if resp.RankDiagnosis == nil || len(resp.RankDiagnosis.DependentRows) != 1 || resp.RankDiagnosis.DependentRows[0].Row != 2 {
+				t.Errorf("handler returned a wrong rank diagnosis: %+v", resp.RankDiagnosis)
+			}
+		}},
+		{name: "non-square covariance", body: covReq, status: http.StatusBadRequest},
+		{name: "rhs with the wrong length", body: rhsReq, status: http.StatusBadRequest},
+		{name: "steady-state warning", body: steadyReq, status: http.StatusOK, check: func(t *testing.T, resp ReconciliationResponse) {
+			if resp.SteadyState == nil || resp.SteadyState.Steady || len(resp.SteadyState.Transient) != 1 || resp.SteadyState.Transient[0] != 2 {
+				t.Errorf("handler returned a wrong steady-state warning: %+v", resp.SteadyState)
+			}
+		}},
+		{name: "required steady state", body: requiredSteadyReq, status: http.StatusUnprocessableEntity},
+		{name: "Monte Carlo", body: mcReq, status: http.StatusOK, check: func(t *testing.T, resp ReconciliationResponse) {
+			if mc := resp.MonteCarlo; mc == nil || mc.Samples != 300 || mc.Seed != 42 || len(mc.Variables) != 3 || len(mc.Variables[0].Histogram.Counts) != 10 {
+				t.Errorf("handler returned a wrong Monte Carlo result: %+v", resp.MonteCarlo)
+			}
+		}},
+		{name: "too many Monte Carlo samples", body: bigMCReq, status: http.StatusBadRequest},
+		{name: "generalized likelihood ratio", body: glrReq, status: http.StatusOK, check: func(t *testing.T, resp ReconciliationResponse) {
+			if glr := resp.GLR; glr == nil || !glr.Detected || glr.MostLikely.Kind != reconciliation.HypothesisBias || glr.MostLikely.Index != 2 ||
+				math.Abs(glr.MostLikely.Magnitude-15) > 1e-6 || glr.Compensated == nil || math.Abs(glr.Compensated.Reconciled[2]-40) > 1e-6 {
+				t.Errorf("handler returned a wrong GLR result: %+v", resp.GLR)
+			}
+		}},
+		{name: "screening", body: screenReq, status: http.StatusOK, check: func(t *testing.T, resp ReconciliationResponse) {
+			if s := resp.Screening; s == nil || len(s.Flagged) != 1 || s.Flagged[0] != 2 || s.Tags[2].Flags[0] != reconciliation.FlagRange {
+				t.Fatalf("handler returned a wrong screening result: %+v", resp.Screening)
+			}
+			if resp.Classification[2] != reconciliation.ClassObservable || math.Abs(resp.Reconciled[2]-40) > 1e-6 {
+				t.Errorf("handler did not exclude the flagged measurement: %v %v", resp.Classification, resp.Reconciled)
+			}
+		}},
+		{name: "invalid screening", body: badScreenReq, status: http.StatusBadRequest},
+		{name: "invalid confidence", body: confidenceReq, status: http.StatusBadRequest},
+	})
+
+	t.Run("Monte Carlo seed", func(t *testing.T) {
+		first, _ := postJSON[ReconciliationResponse](t, ReconcileData, mcReq)
+		second, _ := postJSON[ReconciliationResponse](t, ReconcileData, mcReq)
+		if first.Body.String() != second.Body.String() {
+			t.Errorf("handler returned different Monte Carlo results for the same seed")
+		}
+	})
+}
+
+func TestReconcileBatch(t *testing.T) {
+	runHandlerCases(t, ReconcileBatch, []handlerCase[reconciliation.BatchResult]{
+		// The second set has an unmeasured stream and the third has the wrong number of values.
+		{name: "mixed sets", body: `{
+			"constraints": [[1, -1, -1, 0, 0], [0, 0, 1, -1, 0], [0, 1, 0, 1, -1]],
+			"unreconciledata": [
+				{"values": [100, 60, 41, 40, 101], "tolerances": [0.01, 0.01, 0.01, 0.01, 0.01]},
+				{"values": [101, 59, null, 40, 99], "tolerances": [0.01, 0.01, 0.01, 0.01, 0.01]},
+				{"values": [100, 60, 40], "tolerances": [0.01, 0.01, 0.01]}
+			]
+		}`, status: http.StatusOK, check: func(t *testing.T, resp reconciliation.BatchResult) {
+			if len(resp.Items) != 3 || resp.Failed != 1 || resp.Items[2].Error == "" || resp.Items[2].Result != nil {
+				t.Fatalf("handler returned a wrong batch result: %+v", resp)
+			}
+			for _, item := range resp.Items[:2] {
+				if item.Result == nil || math.Abs(item.Result.Reconciled[0]-item.Result.Reconciled[4]) > 1e-6 {
+					t.Errorf("handler returned a wrong set result: %+v", item)
+				}
+			}
+			if resp.Items[1].Result.Classification[2] != reconciliation.ClassObservable {
+				t.Errorf("handler did not treat a null value as unmeasured: %v", resp.Items[1].Result.Classification)
+			}
+		}},
+		{name: "top-level measurements", body: `{"constraints": [[1, -1]], "measurements": [1, 1], "unreconciledata": [{"values": [1, 1], "tolerances": [0.1, 0.1]}]}`,
+			status: http.StatusBadRequest},
+		{name: "empty batch", body: `{"constraints": [[1, -1]], "unreconciledata": []}`, status: http.StatusBadRequest},
+	})
+}
+
+func TestEstimateVariances(t *testing.T) {
+	// A splitter whose feed varies, with deterministic meter noise; the second stream is unmeasured.
+	history := make([]reconciliation.Values, 200)
+	for k := range history {
+		feed := 100 + 10*math.Sin(float64(k)/7)
+		history[k] = reconciliation.Values{feed + math.Sin(float64(k)*1.3), math.NaN(), 0.4*feed + 0.5*math.Cos(float64(k)*2.1), 0.4*feed + 0.5*math.Sin(float64(k)*0.9)}
+	}
+	reqBody := VarianceRequest{
+		ReconciliationRequest: ReconciliationRequest{
+			Tolerances:      []float64{1, 1, 1, 1},
+			UncertaintyMode: reconciliation.UncertaintyAbsolute,
+			Constraints:     [][]float64{{1, -1, -1, 0}, {0, 0, 1, -1}},
+			Unmeasured:      []int{1},
+		},
+		History: history,
+	}
+	topLevelReq := reqBody
+	topLevelReq.Measurements = reconciliation.Values{100, 60, 40, 40}
+
+	runHandlerCases(t, EstimateVariances, []handlerCase[reconciliation.VarianceEstimate]{
+		{name: "estimate", body: reqBody, status: http.StatusOK, check: func(t *testing.T, resp reconciliation.VarianceEstimate) {
+			if resp.Samples != 200 || len(resp.Suggested) != 4 || !math.IsNaN(resp.Suggested[1]) || resp.Configured[0] != 1 {
+				t.Fatalf("handler returned a wrong variance estimate: %+v", resp)
+			}
+			// Without x1, only x2 = x3 remains, which identifies the sum of their variances alone.
+			if len(resp.Unidentifiable) != 3 || resp.Suggested[0] != resp.Direct[0] {
+				t.Errorf("handler returned wrong unidentifiable tags: %v", resp.Unidentifiable)
+			}
+		}},
+		{name: "top-level measurements", body: topLevelReq, status: http.StatusBadRequest},
+	})
+}
+
+func TestReconcileDynamicData(t *testing.T) {
+	runHandlerCases(t, ReconcileDynamicData, []handlerCase[reconciliation.DynamicResult]{
+		// A splitter feeding a tank whose holdup rises 2 units per sample; the holdup is missing at t=2.
+		{name: "tank holdup", body: `{
+			"samples": [
+				{"time": 0, "measurements": [10.1, 9.9, 8.0, 50.2]},
+				{"time": 1, "measurements": [9.9, 10.0, 8.1, 51.9]},
+				{"time": 2, "measurements": [10.0, 10.1, 7.9, null]},
+				{"time": 3, "measurements": [10.1, 9.9, 8.0, 56.1]}
+			],
+			"tolerances": [0.02, 0.02, 0.02, 0.02],
+			"constraints": [[1, -1, 0, 0]],
+			"tanks": [{"holdup": 3, "inlets": [1], "outlets": [2]}],
+			"processNoise": [0.01, 0.01, 0.01, 0.01]
+		}`, status: http.StatusOK, check: func(t *testing.T, resp reconciliation.DynamicResult) {
+			if len(resp.Trajectory) != 4 || resp.Confidence != 0.95 {
+				t.Fatalf("handler returned an incomplete trajectory: %+v", resp)
+			}
+			for _, e := range resp.Trajectory {
+				if math.Abs(e.Estimates[0]-e.Estimates[1]) > 1e-9 || len(e.Lower) != 4 || len(e.Upper) != 4 {
+					t.Errorf("handler returned an inconsistent estimate: %+v", e)
+				}
+			}
+			if !math.IsNaN(resp.Trajectory[2].Innovations[3]) {
+				t.Errorf("handler returned an innovation for a missing measurement: %v", resp.Trajectory[2].Innovations)
+			}
+		}},
+		{name: "decreasing sample times", body: `{
+			"samples": [{"time": 1, "measurements": [10, 10]}, {"time": 0, "measurements": [10, 10]}],
+			"tolerances": [0.02, 0.02],
+			"processNoise": [0.01, 0.01]
+		}`, status: http.StatusBadRequest},
+	})
+}
+
+func TestSensitivity(t *testing.T) {
+	reqBody := SensitivityRequest{
+		ReconciliationRequest: ReconciliationRequest{
+			Measurements: []float64{161, 79, 80},
+			Tolerances:   []float64{0.05, 0.01, 0.01},
+			Constraints:  [][]float64{{1, -1, -1}},
+		},
+		Outputs: [][]float64{{0, 1, 0}, {0, 1, 1}},
+	}
+	badOutputsReq := reqBody
+	badOutputsReq.Outputs = [][]float64{{1, 0}}
+
+	runHandlerCases(t, Sensitivity, []handlerCase[reconciliation.SensitivityResult]{
+		{name: "ranking", body: reqBody, status: http.StatusOK, check: func(t *testing.T, resp reconciliation.SensitivityResult) {
+			if len(resp.Gain) != 2 || len(resp.Gain[0]) != 3 || len(resp.Contributions) != 2 || len(resp.Ranking) != 2 {
+				t.Fatalf("handler returned an incomplete result: %+v", resp)
+			}
+			// The second output, x1 + x2, equals x0 through the balance. It is set by the precise meters
+			// on x1 and x2, so the meter on x0 (5% tolerance) has the least impact.
+			if resp.Ranking[1][2] != 0 || resp.Gain[1][0] >= resp.Gain[1][1] {
+				t.Errorf("handler returned a wrong ranking: %v", resp.Ranking[1])
+			}
+		}},
+		{name: "outputs with the wrong length", body: badOutputsReq, status: http.StatusBadRequest},
+	})
+}
+
+func TestDesignSensors(t *testing.T) {
+	runHandlerCases(t, DesignSensors, []handlerCase[reconciliation.DesignResult]{
+		// The streams x1, x2 and x3 have no meter; only x1 + x2 follows from the balances.
+		{name: "selection", body: `{
+			"measurements": [100, null, null, null, 101],
+			"tolerances": [1, 0, 0, 0, 1],
+			"uncertaintyMode": "absolute",
+			"constraints": [[1, -1, -1, 0, 0], [0, 0, 1, -1, 0], [0, 1, 0, 1, -1]],
+			"candidates": [
+				{"name": "FT-1", "variable": 1, "deviation": 0.5, "cost": 5},
+				{"name": "FT-2", "variable": 2, "deviation": 0.5, "cost": 10}
+			],
+			"budget": 12
+		}`, status: http.StatusOK, check: func(t *testing.T, resp reconciliation.DesignResult) {
+			if len(resp.Candidates) != 2 || len(resp.Candidates[0].NewlyObservable) != 3 {
+				t.Errorf("handler returned a wrong candidate evaluation: %+v", resp.Candidates)
+			}
+			if len(resp.Selected) != 1 || resp.Selected[0].Candidate != 0 || resp.TotalCost != 5 {
+				t.Errorf("handler returned a wrong selection: %+v", resp.Selected)
+			}
+		}},
+		{name: "no candidates", body: `{"measurements": [100, 101], "tolerances": [1, 1], "constraints": [[1, -1]], "candidates": []}`,
+			status: http.StatusBadRequest},
+	})
+}
@@ -0,0 +1,198 @@
+package reconciliation
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Dataset é um conjunto de medições reconciliado por ReconcileBatch.
+type Dataset struct {
+	// Measurements são os valores medidos; NaN (null em JSON) é uma medição ausente (ver WithMissing).
+	Measurements Values `json:"values"`
+	// Tolerances são as tolerâncias das medições, como em Reconcile.
+	Tolerances []float64 `json:"tolerances"`
+}
+
+// BatchItem é o resultado da reconciliação de um conjunto de medições.
+type BatchItem struct {
+	// Result é a solução do conjunto; nula quando a reconciliação falhou.
+	Result *Result `json:"result,omitempty"`
+	// Error descreve a falha da reconciliação do conjunto, se houver.
+	Error string `json:"error,omitempty"`
+}
+
+// BatchResult é o resultado da reconciliação de vários conjuntos de medições.
+type BatchResult struct {
+	// Items são os resultados de cada conjunto, na ordem informada.
+	Items []BatchItem `json:"items"`
+	// Failed é o número de conjuntos cuja reconciliação falhou.
+	Failed int `json:"failed"`
+}
+
+// ReconcileBatch reconcilia vários conjuntos de medições com a mesma matriz de restrições e
+// as mesmas opções, por exemplo uma série de instantes de uma planta.
+//
+// A parte que só depende das restrições, a projeção das variáveis não medidas (a decomposição
+// em valores singulares de B_U), é calculada uma vez para cada padrão de variáveis não medidas
+// e fixas e compartilhada entre os conjuntos. A fatoração de Cholesky do complemento de Schur
+// B*V*B^T depende também dos pesos: ela é reaproveitada entre os conjuntos com o mesmo padrão
+// e os mesmos pesos (ex: tolerâncias absolutas ou incertezas informadas), e recalculada
+// quando os pesos mudam com as medições (tolerâncias relativas). Os conjuntos são resolvidos
+// em paralelo por um pool de workers (WithWorkers).
+//
+// As medições ausentes de cada conjunto seguem a política de WithMissing: com
+// MissingUnmeasured, cada conjunto tem as suas próprias variáveis não medidas. A falha de um
+// conjunto (ex: dimensões incompatíveis, medições ausentes rejeitadas) é informada no seu
+// item, sem interromper os demais; o erro retornado se limita às opções
+// inválidas e a uma lista vazia.
+func ReconcileBatch(datasets []Dataset, constraints *mat.Dense, opts ...Option) (*BatchResult, error) {
+	if len(datasets) == 0 {
+		return nil, fmt.Errorf("%w: a lista de conjuntos de medições não pode estar vazia", ErrInvalidInput)
+	}
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	shared := append(append([]Option(nil), opts...), withBatchCache(newBatchCache()))
+
+	workers := o.workers
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	result := &BatchResult{Items: make([]BatchItem, len(datasets))}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range jobs {
+				d := datasets[k]
+				r, err := Reconcile(d.Measurements, d.Tolerances, constraints, shared...)
+				if err != nil {
+					result.Items[k].Error = err.Error()
+					continue
+				}
+				result.Items[k].Result = r
+			}
+		}()
+	}
+	for k := range datasets {
+		jobs <- k
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, item := range result.Items {
+		if item.Result == nil {
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+// batchCache guarda o que só depende das restrições e dos pesos, compartilhado entre os
+// conjuntos de um lote: as projeções, uma por padrão de variáveis não medidas, de variáveis
+// fixas e de linhas descartadas, e a fatoração do complemento de Schur de cada padrão, com os
+// pesos com que foi calculada. É seguro para uso concorrente; as projeções e os fatores não
+// são alterados depois de construídos.
+type batchCache struct {
+	mu          sync.Mutex
+	projections map[string]*projection
+	factors     map[string]*cachedFactor
+}
+
+// cachedFactor é a fatoração do complemento de Schur de um padrão, com os pesos usados.
+type cachedFactor struct {
+	weights []float64
+	factor  *schurFactor
+}
+
+// newBatchCache cria um cache vazio.
+func newBatchCache() *batchCache {
+	return &batchCache{projections: make(map[string]*projection), factors: make(map[string]*cachedFactor)}
+}
+
+// patternKey identifica o padrão de variáveis não medidas, de variáveis fixas e de linhas
+// descartadas de uma reconciliação.
+func patternKey(unmeasured, fixed, dropped map[int]bool) string {
+	return indexKey(unmeasured) + "|" + indexKey(fixed) + "|" + indexKey(dropped)
+}
+
+// projection retorna a projeção das restrições (já sem as linhas descartadas e com as colunas
+// das variáveis fixas zeradas) para o padrão informado, construindo-a na primeira vez.
+func (c *batchCache) projection(pattern string, constraints *mat.Dense, unmeasured, fixed map[int]bool) *projection {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if pr, ok := c.projections[pattern]; ok {
+		return pr
+	}
+	pr := newProjection(constraints, unmeasured, fixed)
+	c.projections[pattern] = pr
+	return pr
+}
+
+// factor retorna a fatoração do complemento de Schur das restrições reduzidas a do padrão
+// informado com a covariância das medições. Cada padrão guarda apenas o fator dos últimos
+// pesos: com tolerâncias absolutas, os pesos são os mesmos em todos os conjuntos e o fator é
+// calculado uma vez; com tolerâncias relativas, eles mudam com as medições e o fator é
+// recalculado, sem que o cache cresça com o número de conjuntos.
+func (c *batchCache) factor(pattern string, a *mat.Dense, sparse *sparseMatrix, covariance mat.Symmetric, solver Solver) (*schurFactor, error) {
+	weights := symmetricValues(covariance)
+	c.mu.Lock()
+	cached, ok := c.factors[pattern]
+	c.mu.Unlock()
+	if ok && slices.Equal(cached.weights, weights) {
+		return cached.factor, nil
+	}
+
+	f, err := factorSchur(a, sparse, covariance, solver)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.factors[pattern] = &cachedFactor{weights: weights, factor: f}
+	c.mu.Unlock()
+	return f, nil
+}
+
+// symmetricValues retorna os elementos de uma matriz simétrica: a diagonal, se ela for
+// diagonal, ou o triângulo superior, por linhas.
+func symmetricValues(s mat.Symmetric) []float64 {
+	n := s.SymmetricDim()
+	if diagonal, ok := s.(*mat.DiagDense); ok {
+		values := make([]float64, n)
+		for i := range values {
+			values[i] = diagonal.At(i, i)
+		}
+		return values
+	}
+	values := make([]float64, 0, n*(n+1)/2)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			values = append(values, s.At(i, j))
+		}
+	}
+	return values
+}
+
+// indexKey representa um conjunto de índices por uma string, em ordem crescente.
+func indexKey(set map[int]bool) string {
+	indices := make([]int, 0, len(set))
+	for i, marked := range set {
+		if marked {
+			indices = append(indices, i)
+		}
+	}
+	slices.Sort(indices)
+	var b strings.Builder
+	for _, i := range indices {
+		fmt.Fprintf(&b, "%d,", i)
+	}
+	return b.String()
+}
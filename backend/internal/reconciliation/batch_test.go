@@ -0,0 +1,158 @@
+package reconciliation
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestReconcileBatch(t *testing.T) {
+	tolerances := []float64{0.01, 0.01, 0.01, 0.01, 0.01}
+	datasets := []Dataset{
+		{Measurements: []float64{100, 60, 41, 40, 101}, Tolerances: tolerances},
+		{Measurements: []float64{101, 59, math.NaN(), 40, 99}, Tolerances: tolerances},
+		{Measurements: []float64{100, 60, 40}, Tolerances: tolerances},
+		{Measurements: []float64{99, math.NaN(), 40, 41, 100}, Tolerances: tolerances},
+		{Measurements: []float64{102, 61, 40, 40, 100}, Tolerances: tolerances},
+	}
+
+	result, err := ReconcileBatch(datasets, networkConstraints(), WithWorkers(3), WithMissing(MissingUnmeasured))
+	if err != nil {
+		t.Fatalf("ReconcileBatch retornou um erro inesperado: %v", err)
+	}
+
+	t.Run("Resultados Iguais aos Individuais", func(t *testing.T) {
+		// Os conjuntos com padrões diferentes de variáveis não medidas não podem compartilhar a projeção.
+		unmeasured := map[int][]int{1: {2}, 3: {1}}
+		for k, d := range datasets {
+			if k == 2 {
+				continue
+			}
+			expected, err := Reconcile(d.Measurements, d.Tolerances, networkConstraints(), WithUnmeasured(unmeasured[k]))
+			if err != nil {
+				t.Fatalf("Reconcile retornou um erro inesperado no conjunto %d: %v", k, err)
+			}
+			item := result.Items[k]
+			if item.Result == nil {
+				t.Fatalf("O conjunto %d não deveria falhar: %s", k, item.Error)
+			}
+			if !equal(item.Result.Reconciled, expected.Reconciled, 1e-9) || !equal(item.Result.ReconciledDeviations, expected.ReconciledDeviations, 1e-9) {
+				t.Errorf("Resultado incorreto no conjunto %d.\nEsperado: %v\nObtido:   %v", k, expected.Reconciled, item.Result.Reconciled)
+			}
+		}
+	})
+
+	t.Run("Falha de um Conjunto", func(t *testing.T) {
+		if result.Failed != 1 || result.Items[2].Result != nil || result.Items[2].Error == "" {
+			t.Errorf("Apenas o conjunto de dimensão incompatível deveria falhar: %+v", result.Items[2])
+		}
+	})
+
+	t.Run("Medições Ausentes Rejeitadas", func(t *testing.T) {
+		rejected, err := ReconcileBatch(datasets, networkConstraints())
+		if err != nil {
+			t.Fatalf("ReconcileBatch retornou um erro inesperado: %v", err)
+		}
+		if rejected.Failed != 3 || rejected.Items[1].Result != nil || rejected.Items[0].Result == nil {
+			t.Errorf("Os conjuntos com medições ausentes deveriam falhar: %+v", rejected.Items)
+		}
+	})
+
+	t.Run("Reaproveitamento da Fatoração", func(t *testing.T) {
+		// Com tolerâncias absolutas, os pesos não dependem das medições: o complemento de Schur
+		// é fatorado uma vez por padrão de variáveis não medidas, e os conjuntos 0 e 4 usam o
+		// mesmo fator.
+		absolute := WithUncertaintyMode(UncertaintyAbsolute)
+		cache := newBatchCache()
+		factors := make(map[string]*schurFactor)
+		for k, d := range datasets {
+			if k == 2 {
+				continue
+			}
+			opts := []Option{absolute, WithMissing(MissingUnmeasured)}
+			got, err := Reconcile(d.Measurements, tolerances, networkConstraints(), append(opts, withBatchCache(cache))...)
+			if err != nil {
+				t.Fatalf("Reconcile retornou um erro inesperado no conjunto %d: %v", k, err)
+			}
+			expected, err := Reconcile(d.Measurements, tolerances, networkConstraints(), opts...)
+			if err != nil {
+				t.Fatalf("Reconcile retornou um erro inesperado no conjunto %d: %v", k, err)
+			}
+			if !equal(got.Reconciled, expected.Reconciled, 1e-12) || !equal(got.ReconciledDeviations, expected.ReconciledDeviations, 1e-12) {
+				t.Errorf("Resultado incorreto no conjunto %d.\nEsperado: %v\nObtido:   %v", k, expected.Reconciled, got.Reconciled)
+			}
+			for pattern, cached := range cache.factors {
+				if f, ok := factors[pattern]; ok && f != cached.factor {
+					t.Errorf("O fator do padrão %q foi recalculado no conjunto %d", pattern, k)
+				}
+				factors[pattern] = cached.factor
+			}
+		}
+		if len(factors) != 3 {
+			t.Errorf("Esperado um fator por padrão (3), obtidos %d", len(factors))
+		}
+
+		// Com tolerâncias relativas, os pesos mudam com as medições e o fator é recalculado,
+		// substituindo o anterior.
+		cache = newBatchCache()
+		var previous *schurFactor
+		for _, k := range []int{0, 4} {
+			if _, err := Reconcile(datasets[k].Measurements, tolerances, networkConstraints(), withBatchCache(cache)); err != nil {
+				t.Fatalf("Reconcile retornou um erro inesperado no conjunto %d: %v", k, err)
+			}
+			if len(cache.factors) != 1 {
+				t.Fatalf("Esperado um fator guardado, obtidos %d", len(cache.factors))
+			}
+			for _, cached := range cache.factors {
+				if cached.factor == previous {
+					t.Errorf("O fator deveria ser recalculado no conjunto %d", k)
+				}
+				previous = cached.factor
+			}
+		}
+	})
+
+	t.Run("Entradas Inválidas", func(t *testing.T) {
+		if _, err := ReconcileBatch(nil, networkConstraints()); err == nil {
+			t.Error("Esperado um erro para uma lista vazia")
+		}
+		if _, err := ReconcileBatch(datasets, networkConstraints(), WithWorkers(-1)); err == nil {
+			t.Error("Esperado um erro para um número de workers negativo")
+		}
+	})
+}
+
+// BenchmarkReconcileBatch mede o lote de uma planta sintética com tolerâncias absolutas, em
+// que a fatoração é compartilhada, contra as mesmas reconciliações feitas uma a uma. Sem a
+// covariância completa, a fatoração é a parte mais cara de cada reconciliação.
+func BenchmarkReconcileBatch(b *testing.B) {
+	constraints, measurements, tolerances := syntheticNetwork(100, 1)
+	rng := rand.New(rand.NewSource(2))
+	datasets := make([]Dataset, 50)
+	for k := range datasets {
+		values := make(Values, len(measurements))
+		for i, m := range measurements {
+			values[i] = m + rng.NormFloat64()
+		}
+		datasets[k] = Dataset{Measurements: values, Tolerances: tolerances}
+	}
+	absolute := WithUncertaintyMode(UncertaintyAbsolute)
+	diagonal := WithReconciledCovariance(false)
+
+	b.Run("lote", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := ReconcileBatch(datasets, constraints, absolute, diagonal, WithWorkers(1)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("individual", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, d := range datasets {
+				if _, err := Reconcile(d.Measurements, d.Tolerances, constraints, absolute, diagonal); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
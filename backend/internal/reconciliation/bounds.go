@@ -0,0 +1,321 @@
+package reconciliation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/optimize/convex/lp"
+)
+
+// BoundSide indica qual limite de uma variável está ativo.
+type BoundSide string
+
+const (
+	// BoundLower indica que a variável está no seu limite inferior.
+	BoundLower BoundSide = "lower"
+	// BoundUpper indica que a variável está no seu limite superior.
+	BoundUpper BoundSide = "upper"
+)
+
+// ActiveBound descreve um limite ativo na solução do problema com desigualdades.
+type ActiveBound struct {
+	// Index é o índice da variável cujo limite está ativo.
+	Index int `json:"index"`
+	// Side indica se o limite ativo é o inferior ou o superior.
+	Side BoundSide `json:"side"`
+	// Value é o valor do limite, igual ao valor reconciliado da variável.
+	Value float64 `json:"value"`
+	// Multiplier é o multiplicador de Lagrange do limite: quanto maior o seu módulo, mais
+	// o limite "puxa" a solução para longe do ótimo sem limites.
+	Multiplier float64 `json:"multiplier"`
+}
+
+// activeBound é um limite ativo durante as iterações do método de conjunto ativo.
+type activeBound struct {
+	index int
+	side  BoundSide
+	value float64
+}
+
+// maxActiveSetIterations limita o número de iterações do método de conjunto ativo por variável.
+const maxActiveSetIterations = 4
+
+// boundTolerance é a violação relativa máxima de um limite considerada numericamente nula.
+const boundTolerance = 1e-9
+
+// hasBound indica se o limite v está definido (NaN e ±Inf significam ausência de limite).
+func hasBound(v []float64, i int) bool {
+	return v != nil && !math.IsNaN(v[i]) && !math.IsInf(v[i], 0)
+}
+
+// validateBounds verifica as dimensões dos limites e se cada limite inferior não excede o superior.
+func validateBounds(o options, numMeasurements int) error {
+	if o.lower != nil && len(o.lower) != numMeasurements {
+		return fmt.Errorf("%w: medições (%d) e limites inferiores (%d)", ErrDimensionMismatch, numMeasurements, len(o.lower))
+	}
+	if o.upper != nil && len(o.upper) != numMeasurements {
+		return fmt.Errorf("%w: medições (%d) e limites superiores (%d)", ErrDimensionMismatch, numMeasurements, len(o.upper))
+	}
+	for i := 0; i < numMeasurements; i++ {
+		if hasBound(o.lower, i) && hasBound(o.upper, i) && o.lower[i] > o.upper[i] {
+			return fmt.Errorf("%w: o limite inferior da variável %d (%v) é maior que o superior (%v)", ErrInvalidInput, i, o.lower[i], o.upper[i])
+		}
+	}
+	return nil
+}
+
+// withActiveBounds anexa às restrições uma linha x_i = limite para cada limite ativo.
+// Sem limites ativos, retorna as próprias restrições e o próprio lado direito.
+func withActiveBounds(constraints *mat.Dense, rhs []float64, active []activeBound) (*mat.Dense, []float64) {
+	if len(active) == 0 {
+		return constraints, rhs
+	}
+	numConstraints, numVariables := constraints.Dims()
+	augmented := mat.NewDense(numConstraints+len(active), numVariables, nil)
+	augmented.Slice(0, numConstraints, 0, numVariables).(*mat.Dense).Copy(constraints)
+	augmentedRHS := make([]float64, numConstraints+len(active))
+	copy(augmentedRHS, rhs)
+	for k, b := range active {
+		augmented.Set(numConstraints+k, b.index, 1)
+		augmentedRHS[numConstraints+k] = b.value
+	}
+	return augmented, augmentedRHS
+}
+
+// reconcileBounded resolve o problema de reconciliação com limites inferiores e superiores
+// por um método primal de conjunto ativo.
+//
+// Cada limite ativo é tratado como uma restrição de igualdade adicional, x_i = limite, e o
+// problema de igualdades é resolvido como em Reconcile. O ponto de partida é a solução sem
+// limites, se ela for viável, ou um ponto viável obtido por programação linear (fase 1, ver
+// feasiblePoint). A cada iteração, o problema com o conjunto ativo atual fornece a direção
+// p = x* - x, e o passo α = min(1, min (limite - x_i)/p_i) sobre os limites que p cruzaria
+// (teste da razão) leva x até o primeiro limite que bloqueia o caminho, que é ativado. Assim,
+// x nunca deixa a região viável, e um limite que bloqueia o caminho é sempre linearmente
+// independente das restrições e dos limites já ativos (p os respeita, mas não a ele), de modo
+// que o problema de igualdades nunca fica singular. Com o passo completo, x = x*, e o limite
+// ativo cujo multiplicador tem o sinal errado (o limite "empurra" a variável para dentro da
+// região viável) de maior módulo é liberado. O processo termina quando todos os
+// multiplicadores têm o sinal correto, o que caracteriza o ótimo do problema convexo.
+//
+// O teste global trata os limites ativos como restrições de igualdade, com um grau de
+// liberdade a mais por limite ativo. Como os limites são desigualdades, a função objetivo não
+// segue exatamente essa qui-quadrado, e o teste é apenas aproximado.
+func reconcileBounded(measurements, tolerances []float64, constraints *mat.Dense, o options) (*Result, error) {
+	numMeasurements := len(measurements)
+	o.active = nil
+	result, err := reconcile(measurements, tolerances, constraints, o)
+	if err != nil {
+		return nil, err
+	}
+	x := append([]float64(nil), result.Reconciled...)
+	for i, v := range x {
+		if violatesLower(o, i, v) || violatesUpper(o, i, v) {
+			if x, err = feasiblePoint(measurements, tolerances, constraints, o); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	isActive := make(map[int]bool)
+
+	maxIterations := maxActiveSetIterations*numMeasurements + 1
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		if iteration > 0 {
+			if result, err = reconcile(measurements, tolerances, constraints, o); err != nil {
+				return nil, err
+			}
+		}
+
+		// Teste da razão: o menor passo ao longo de p que leva uma variável de limite inativo
+		// até esse limite.
+		alpha := 1.0
+		var blocking *activeBound
+		for i, v := range x {
+			target := result.Reconciled[i]
+			if isActive[i] || math.IsNaN(v) || math.IsNaN(target) {
+				continue
+			}
+			p := target - v
+			if math.Abs(p) <= boundTolerance*math.Max(1, math.Abs(v)) {
+				continue
+			}
+			if p < 0 && hasBound(o.lower, i) {
+				if step := math.Max(0, (o.lower[i]-v)/p); step < alpha {
+					alpha, blocking = step, &activeBound{index: i, side: BoundLower, value: o.lower[i]}
+				}
+			}
+			if p > 0 && hasBound(o.upper, i) {
+				if step := math.Max(0, (o.upper[i]-v)/p); step < alpha {
+					alpha, blocking = step, &activeBound{index: i, side: BoundUpper, value: o.upper[i]}
+				}
+			}
+		}
+		if blocking != nil {
+			for i, v := range x {
+				if !math.IsNaN(v) && !math.IsNaN(result.Reconciled[i]) {
+					x[i] = v + alpha*(result.Reconciled[i]-v)
+				}
+			}
+			x[blocking.index] = blocking.value
+			o.active = append(append([]activeBound(nil), o.active...), *blocking)
+			isActive[blocking.index] = true
+			continue
+		}
+		for i, v := range result.Reconciled {
+			if !math.IsNaN(v) {
+				x[i] = v
+			}
+		}
+
+		// Passo completo: libera o limite ativo com o multiplicador de sinal errado de maior
+		// módulo. No limite inferior o multiplicador deve ser negativo ou nulo; no superior,
+		// positivo ou nulo.
+		release := -1
+		worst := 0.0
+		for k, b := range o.active {
+			multiplier := result.boundMultipliers[k]
+			if b.side == BoundLower {
+				multiplier = -multiplier
+			}
+			if multiplier < -boundTolerance && -multiplier > worst {
+				release, worst = k, -multiplier
+			}
+		}
+		if release >= 0 {
+			delete(isActive, o.active[release].index)
+			o.active = append(append([]activeBound(nil), o.active[:release]...), o.active[release+1:]...)
+			continue
+		}
+
+		result.ActiveBounds = make([]ActiveBound, len(o.active))
+		for k, b := range o.active {
+			result.ActiveBounds[k] = ActiveBound{Index: b.index, Side: b.side, Value: b.value, Multiplier: result.boundMultipliers[k]}
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("o método de conjunto ativo não convergiu em %d iterações", maxIterations)
+}
+
+// feasiblePoint encontra um ponto que satisfaz as restrições, as variáveis fixas e os limites
+// (fase 1 do método de conjunto ativo), por programação linear. As variáveis fixas vão ao
+// lado direito e as linhas dependentes são descartadas (ver fixVariables e diagnoseRank), e
+// cada variável restante é escrita com variáveis não negativas: x = l + s, com s + t = u - l
+// se houver os dois limites, x = u - s, ou x = s⁺ - s⁻ sem limites. Uma variável que não
+// aparece em nenhuma restrição recebe a sua medição, ou zero, levada aos limites. Retorna um
+// erro apenas se não houver ponto viável.
+func feasiblePoint(measurements, tolerances []float64, constraints *mat.Dense, o options) ([]float64, error) {
+	numMeasurements := len(measurements)
+	fixed := make(map[int]bool)
+	for i := range measurements {
+		if !o.unmeasured[i] && o.isFixed(i, tolerances[i]) {
+			fixed[i] = true
+		}
+	}
+	free, rhs, dropped, err := fixVariables(measurements, constraints, fixed, o)
+	if err != nil {
+		return nil, err
+	}
+	kept, keptRHS := withoutRows(free, rhs, dropped)
+	diagnosis := diagnoseRank(kept, keptRHS)
+	if !diagnosis.consistent() {
+		return nil, errors.New("os limites são incompatíveis com as restrições: as restrições são inconsistentes")
+	}
+	dependent := make(map[int]bool, len(diagnosis.DependentRows))
+	for _, row := range diagnosis.DependentRows {
+		dependent[row.Row] = true
+	}
+	kept, keptRHS = withoutRows(kept, keptRHS, dependent)
+	numRows, _ := kept.Dims()
+
+	// Cada variável livre é x_j = offset_j + Σ sinal * s, sobre as colunas do programa linear.
+	type column struct {
+		variable int
+		sign     float64
+	}
+	x := make([]float64, numMeasurements)
+	var columns []column
+	var twoSided []int
+	for j := 0; j < numMeasurements; j++ {
+		if fixed[j] {
+			x[j] = measurements[j]
+			continue
+		}
+		used := false
+		for k := 0; k < numRows; k++ {
+			if kept.At(k, j) != 0 {
+				used = true
+				break
+			}
+		}
+		lower, upper := hasBound(o.lower, j), hasBound(o.upper, j)
+		switch {
+		case !used:
+			if !o.unmeasured[j] {
+				x[j] = measurements[j]
+			}
+			if lower {
+				x[j] = math.Max(x[j], o.lower[j])
+			}
+			if upper {
+				x[j] = math.Min(x[j], o.upper[j])
+			}
+		case lower:
+			x[j] = o.lower[j]
+			if upper {
+				twoSided = append(twoSided, len(columns))
+			}
+			columns = append(columns, column{j, 1})
+		case upper:
+			x[j] = o.upper[j]
+			columns = append(columns, column{j, -1})
+		default:
+			columns = append(columns, column{j, 1}, column{j, -1})
+		}
+	}
+	if numRows == 0 && len(twoSided) == 0 {
+		return x, nil
+	}
+
+	// Restrições B*x = c em termos de s, seguidas de s + t = u - l para os limites duplos.
+	a := mat.NewDense(numRows+len(twoSided), len(columns)+len(twoSided), nil)
+	b := make([]float64, numRows+len(twoSided))
+	for k := 0; k < numRows; k++ {
+		b[k] = keptRHS[k]
+		for j, v := range kept.RawRowView(k) {
+			b[k] -= v * x[j]
+		}
+		for c, col := range columns {
+			a.Set(k, c, col.sign*kept.At(k, col.variable))
+		}
+	}
+	for l, c := range twoSided {
+		j := columns[c].variable
+		a.Set(numRows+l, c, 1)
+		a.Set(numRows+l, len(columns)+l, 1)
+		b[numRows+l] = o.upper[j] - o.lower[j]
+	}
+	_, s, err := lp.Simplex(make([]float64, len(columns)+len(twoSided)), a, b, 0, nil)
+	if errors.Is(err, lp.ErrInfeasible) {
+		return nil, errors.New("os limites são incompatíveis com as restrições: não há ponto viável")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("não foi possível encontrar um ponto viável para os limites: %w", err)
+	}
+	for c, col := range columns {
+		x[col.variable] += col.sign * s[c]
+	}
+	return x, nil
+}
+
+// violatesLower indica se o valor v viola o limite inferior da variável i além da tolerância.
+func violatesLower(o options, i int, v float64) bool {
+	return hasBound(o.lower, i) && o.lower[i]-v > boundTolerance*math.Max(1, math.Abs(o.lower[i]))
+}
+
+// violatesUpper indica se o valor v viola o limite superior da variável i além da tolerância.
+func violatesUpper(o options, i int, v float64) bool {
+	return hasBound(o.upper, i) && v-o.upper[i] > boundTolerance*math.Max(1, math.Abs(o.upper[i]))
+}
@@ -0,0 +1,150 @@
+package reconciliation
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestBounds(t *testing.T) {
+	constraints := mat.NewDense(1, 3, []float64{1, -1, -1})
+
+	t.Run("Vazão Não Negativa", func(t *testing.T) {
+		// Sem limites, a solução leva x3 a -1/3.
+		measurements := []float64{10, 12, 0.5}
+		tolerances := []float64{1, 1, 1}
+		free, err := Reconcile(measurements, tolerances, constraints, WithUncertaintyMode(UncertaintyAbsolute))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if free.Reconciled[2] >= 0 {
+			t.Fatalf("O caso de teste deveria produzir um valor negativo sem limites: %v", free.Reconciled)
+		}
+		if free.ActiveBounds != nil {
+			t.Errorf("Sem limites, nenhum limite deveria ser informado: %v", free.ActiveBounds)
+		}
+
+		// Com x >= 0, x3 fica no limite e x1 = x2 = 11.
+		result, err := Reconcile(measurements, tolerances, constraints, WithUncertaintyMode(UncertaintyAbsolute), WithBounds([]float64{0, 0, 0}, nil))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, []float64{11, 11, 0}, 1e-9) {
+			t.Errorf("O resultado reconciliado estava incorreto.\nEsperado: %v\nObtido:   %v", []float64{11, 11, 0}, result.Reconciled)
+		}
+		if len(result.ActiveBounds) != 1 || result.ActiveBounds[0].Index != 2 || result.ActiveBounds[0].Side != BoundLower {
+			t.Fatalf("Limites ativos incorretos: %+v", result.ActiveBounds)
+		}
+		if math.Abs(result.ActiveBounds[0].Multiplier+0.5) > 1e-9 {
+			t.Errorf("Multiplicador do limite incorreto.\nEsperado: -0.5\nObtido:   %v", result.ActiveBounds[0].Multiplier)
+		}
+		if len(result.Lambda) != 1 || len(result.ResidualsAfter) != 1 {
+			t.Errorf("As linhas dos limites não deveriam aparecer nas restrições: λ %v, resíduos %v", result.Lambda, result.ResidualsAfter)
+		}
+	})
+
+	t.Run("Capacidade Máxima", func(t *testing.T) {
+		// A bomba da corrente x1 não passa de 159, abaixo da solução sem limites (159.04).
+		result, err := Reconcile([]float64{161, 79, 80}, []float64{0.05, 0.01, 0.01}, constraints,
+			WithBounds(nil, []float64{159, math.Inf(1), math.NaN()}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if math.Abs(result.Reconciled[0]-159) > 1e-9 || math.Abs(result.ResidualsAfter[0]) > 1e-9 {
+			t.Errorf("x1 deveria estar no limite e as restrições satisfeitas: %v", result.Reconciled)
+		}
+		if len(result.ActiveBounds) != 1 || result.ActiveBounds[0].Side != BoundUpper || result.ActiveBounds[0].Multiplier < 0 {
+			t.Errorf("Limites ativos incorretos: %+v", result.ActiveBounds)
+		}
+	})
+
+	t.Run("Limites Inativos", func(t *testing.T) {
+		base, _ := Reconcile([]float64{161, 79, 80}, []float64{0.05, 0.01, 0.01}, constraints)
+		result, err := Reconcile([]float64{161, 79, 80}, []float64{0.05, 0.01, 0.01}, constraints,
+			WithBounds([]float64{0, 0, 0}, []float64{200, 200, 200}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, base.Reconciled, 1e-9) || len(result.ActiveBounds) != 0 {
+			t.Errorf("Limites folgados não deveriam alterar a solução: %v, %+v", result.Reconciled, result.ActiveBounds)
+		}
+	})
+
+	t.Run("Limites Interdependentes", func(t *testing.T) {
+		// Divisor x0 = x1 + x2 + x3. Sem limites, x = (105, 45, 35, 25), que viola os limites de
+		// x0, x1, x2 e x3. Com x0 no limite superior, as saídas caem e o limite de x1 deixa
+		// de ser necessário.
+		splitter := mat.NewDense(1, 4, []float64{1, -1, -1, -1})
+		nan := math.NaN()
+		result, err := Reconcile([]float64{100, 50, 40, 30}, []float64{1, 1, 1, 1}, splitter, WithUncertaintyMode(UncertaintyAbsolute),
+			WithBounds([]float64{nan, nan, 36, 28}, []float64{102, 44, nan, nan}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, []float64{102, 38, 36, 28}, 1e-9) {
+			t.Errorf("O resultado reconciliado estava incorreto.\nEsperado: %v\nObtido:   %v", []float64{102, 38, 36, 28}, result.Reconciled)
+		}
+		expected := map[int]ActiveBound{
+			0: {Index: 0, Side: BoundUpper, Value: 102, Multiplier: 10},
+			2: {Index: 2, Side: BoundLower, Value: 36, Multiplier: -8},
+			3: {Index: 3, Side: BoundLower, Value: 28, Multiplier: -10},
+		}
+		if len(result.ActiveBounds) != len(expected) {
+			t.Fatalf("Limites ativos incorretos: %+v", result.ActiveBounds)
+		}
+		for _, b := range result.ActiveBounds {
+			e := expected[b.Index]
+			if b.Side != e.Side || b.Value != e.Value || math.Abs(b.Multiplier-e.Multiplier) > 1e-9 {
+				t.Errorf("Limite ativo incorreto.\nEsperado: %+v\nObtido:   %+v", e, b)
+			}
+		}
+	})
+
+	t.Run("Limites Dependentes", func(t *testing.T) {
+		// A solução sem limites viola os limites inferiores de x2, x3 e x4, e os limites de x3 e
+		// x4 junto com as restrições determinam x2: partir da solução sem limites e ativar os
+		// limites violados tornava o sistema singular. O ótimo, verificado por enumeração dos
+		// conjuntos ativos, tem x3 e x4 nos limites.
+		constraints := mat.NewDense(2, 5, []float64{1, -1, -1, 1, -1, 1, -1, 1, -1, 0})
+		measurements := []float64{-6.54, 0.49, -11.66, -3.69, -7.45}
+		deviations := []float64{1.16, 0.51, 3.17, 1.65, 2.70}
+		lower := []float64{math.NaN(), -3.56, -3.23, -3.96, -4.11}
+		upper := []float64{math.Inf(1), 7.73, 7.02, 7.75, 7.29}
+		result, err := Reconcile(measurements, deviations, constraints, WithUncertaintyMode(UncertaintyAbsolute), WithBounds(lower, upper))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if math.Abs(result.Objective-26.440960618) > 1e-6 {
+			t.Errorf("Função objetivo incorreta: esperado 26.440960618, obtido %v", result.Objective)
+		}
+		for i, v := range result.Reconciled {
+			if (hasBound(lower, i) && v < lower[i]-1e-9) || v > upper[i]+1e-9 {
+				t.Errorf("A variável %d viola os seus limites: %v", i, v)
+			}
+		}
+		if len(result.ActiveBounds) != 2 {
+			t.Fatalf("Limites ativos incorretos: %+v", result.ActiveBounds)
+		}
+		for _, b := range result.ActiveBounds {
+			if b.Side != BoundLower || (b.Index != 3 && b.Index != 4) {
+				t.Errorf("Limite ativo incorreto: %+v", b)
+			}
+		}
+	})
+
+	t.Run("Limites Inválidos", func(t *testing.T) {
+		measurements := []float64{161, 79, 80}
+		tolerances := []float64{0.05, 0.01, 0.01}
+		invalid := map[string]Option{
+			"Inferior Maior que Superior":  WithBounds([]float64{0, 100, 0}, []float64{200, 90, 200}),
+			"Dimensão Incompatível":        WithBounds([]float64{0, 0}, nil),
+			"Incompatíveis com Restrições": WithBounds([]float64{0, 100, 0}, []float64{90, 200, 200}),
+		}
+		for name, opt := range invalid {
+			if _, err := Reconcile(measurements, tolerances, constraints, opt); err == nil {
+				t.Errorf("%s: esperava-se um erro, mas nenhum foi retornado", name)
+			}
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// CovarianceEntry é um elemento fora da diagonal da matriz de covariância das medições,
+// usado para descrever de forma esparsa os erros correlacionados entre duas medições
+// (ex: medidores que compartilham um transmissor ou uma entrada de densidade).
+type CovarianceEntry struct {
+	// Row e Col são os índices das duas medições correlacionadas (Row != Col).
+	Row int `json:"row"`
+	Col int `json:"col"`
+	// Value é a covariância entre as duas medições, na unidade das medições ao quadrado.
+	Value float64 `json:"value"`
+}
+
+// symmetryTolerance é a diferença relativa máxima admitida entre V_ij e V_ji.
+const symmetryTolerance = 1e-9
+
+// covarianceScale é o fator que reescala a covariância entre as medições i e j: 1/sqrt(w_i*w_j)
+// pelos pesos robustos da iteração atual (ver reconcileRobust), vezes f_i*f_j pelos fatores das
+// medições rebaixadas pela triagem (ver WithScreening); 1 sem pesos nem fatores.
+func (o options) covarianceScale(i, j int) float64 {
+	scale := 1.0
+	if o.robustWeights != nil {
+		scale /= math.Sqrt(o.robustWeights[i] * o.robustWeights[j])
+	}
+	if o.inflation != nil {
+		scale *= o.inflation[i] * o.inflation[j]
+	}
+	return scale
+}
+
+// weightMatrix constrói a matriz de covariância das variáveis medidas (V_M) e a matriz de
+// pesos correspondente (W = V_M^-1).
+//
+// Sem correlações, ambas são diagonais, com V_ii = σ_i^2, e W é obtida elemento a elemento;
+// este é o caminho padrão e mais rápido. Com uma matriz de covariância completa
+// (WithCovariance) ou com covariâncias esparsas (WithCovariances), V_M é densa, a sua
+// fatoração de Cholesky valida que ela é definida positiva e W é obtida a partir dela.
+func weightMatrix(o options, deviations Values, measured []int) (weights, covariance mat.Symmetric, err error) {
+	numMeasured := len(measured)
+	if o.covariance == nil && len(o.covariances) == 0 {
+		variances := make([]float64, numMeasured)
+		weightsData := make([]float64, numMeasured)
+		for k, i := range measured {
+			variances[k] = deviations[i] * deviations[i]
+			weightsData[k] = 1 / variances[k]
+		}
+		return mat.NewDiagDense(numMeasured, weightsData), mat.NewDiagDense(numMeasured, variances), nil
+	}
+
+	// Posição de cada variável medida em V_M; variáveis não medidas ficam de fora.
+	position := make(map[int]int, numMeasured)
+	for k, i := range measured {
+		position[i] = k
+	}
+	cov := mat.NewSymDense(numMeasured, nil)
+	for k, i := range measured {
+		if o.covariance != nil {
+			for l, j := range measured[k:] {
+				cov.SetSym(k, k+l, o.covariance.At(i, j)*o.covarianceScale(i, j))
+			}
+		} else {
+			cov.SetSym(k, k, deviations[i]*deviations[i])
+		}
+	}
+	for _, e := range o.covariances {
+		k, okRow := position[e.Row]
+		l, okCol := position[e.Col]
+		if okRow && okCol {
+			cov.SetSym(k, l, e.Value*o.covarianceScale(e.Row, e.Col))
+		}
+	}
+
+	var chol mat.Cholesky
+	if ok := chol.Factorize(cov); !ok {
+		return nil, nil, fmt.Errorf("%w: a matriz de covariância das medições não é definida positiva", ErrInvalidInput)
+	}
+	var inv mat.SymDense
+	if err := chol.InverseTo(&inv); err != nil {
+		return nil, nil, fmt.Errorf("%w: a matriz de covariância das medições é mal condicionada: %v", ErrInvalidInput, err)
+	}
+	return &inv, cov, nil
+}
+
+// validateCovariance verifica as dimensões e a simetria da matriz de covariância completa e
+// os índices das covariâncias esparsas.
+func validateCovariance(o options, numMeasurements int) error {
+	if o.covariance != nil {
+		rows, cols := o.covariance.Dims()
+		if rows != numMeasurements || cols != numMeasurements {
+			return fmt.Errorf("%w: a matriz de covariância (%dx%d) deve ser %dx%d", ErrDimensionMismatch, rows, cols, numMeasurements, numMeasurements)
+		}
+		for i := 0; i < numMeasurements; i++ {
+			if o.covariance.At(i, i) <= 0 {
+				return fmt.Errorf("%w: a variância da medição %d deve ser positiva: %v", ErrInvalidInput, i, o.covariance.At(i, i))
+			}
+			for j := i + 1; j < numMeasurements; j++ {
+				a, b := o.covariance.At(i, j), o.covariance.At(j, i)
+				if math.Abs(a-b) > symmetryTolerance*math.Max(math.Abs(a), math.Abs(b)) {
+					return fmt.Errorf("%w: a matriz de covariância não é simétrica nos índices (%d, %d)", ErrInvalidInput, i, j)
+				}
+			}
+		}
+	}
+	for _, e := range o.covariances {
+		if e.Row < 0 || e.Row >= numMeasurements || e.Col < 0 || e.Col >= numMeasurements {
+			return fmt.Errorf("%w: índice de covariância fora do intervalo: (%d, %d)", ErrInvalidInput, e.Row, e.Col)
+		}
+		if e.Row == e.Col {
+			return fmt.Errorf("%w: covariâncias esparsas devem estar fora da diagonal: (%d, %d)", ErrInvalidInput, e.Row, e.Col)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package reconciliation
+
+import (
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestCorrelatedMeasurements(t *testing.T) {
+	measurements := []float64{161, 79, 80}
+	tolerances := []float64{0.05, 0.01, 0.01}
+	constraints := mat.NewDense(1, 3, []float64{1, -1, -1})
+
+	t.Run("Covariância Diagonal Equivalente", func(t *testing.T) {
+		base, err := Reconcile(measurements, tolerances, constraints)
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		cov := mat.NewDense(3, 3, []float64{
+			8.05 * 8.05, 0, 0,
+			0, 0.79 * 0.79, 0,
+			0, 0, 0.8 * 0.8,
+		})
+		result, err := Reconcile(measurements, []float64{0, 0, 0}, constraints, WithCovariance(cov))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, base.Reconciled, 1e-9) || !equal(result.Deviations, base.Deviations, 1e-9) {
+			t.Errorf("Uma covariância diagonal deveria reproduzir o caminho padrão.\nEsperado: %v\nObtido:   %v", base.Reconciled, result.Reconciled)
+		}
+		if !equal([]float64{result.Objective}, []float64{base.Objective}, 1e-9) {
+			t.Errorf("Função objetivo incorreta.\nEsperado: %v\nObtido:   %v", base.Objective, result.Objective)
+		}
+	})
+
+	t.Run("Medições Correlacionadas", func(t *testing.T) {
+		// x2 e x3 compartilham um transmissor, com covariância 0.5.
+		v := []float64{
+			64, 0, 0,
+			0, 1, 0.5,
+			0, 0.5, 1,
+		}
+		result, err := Reconcile(measurements, tolerances, constraints, WithCovariance(mat.NewDense(3, 3, v)))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+
+		// Com uma única restrição b, x = m - V*b * r / (b^T*V*b), com r = b^T*m = 2.
+		// V*b = (64, -1.5, -1.5) e b^T*V*b = 64 + 1 + 1 + 2*0.5 = 67.
+		expected := []float64{161 - 64*2.0/67, 79 + 1.5*2.0/67, 80 + 1.5*2.0/67}
+		if !equal(result.Reconciled, expected, 1e-9) {
+			t.Errorf("O resultado reconciliado estava incorreto.\nEsperado: %v\nObtido:   %v", expected, result.Reconciled)
+		}
+		if !equal([]float64{result.Objective}, []float64{4.0 / 67}, 1e-9) {
+			t.Errorf("Função objetivo incorreta.\nEsperado: %v\nObtido:   %v", 4.0/67, result.Objective)
+		}
+		if !equal(result.Deviations, []float64{8, 1, 1}, 1e-9) {
+			t.Errorf("Desvios padrão incorretos: %v", result.Deviations)
+		}
+
+		// As covariâncias esparsas, somadas à diagonal das tolerâncias, dão o mesmo resultado.
+		sparse, err := Reconcile(measurements, []float64{8, 1, 1}, constraints,
+			WithUncertaintyMode(UncertaintyAbsolute),
+			WithCovariances([]CovarianceEntry{{Row: 1, Col: 2, Value: 0.5}}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(sparse.Reconciled, expected, 1e-9) {
+			t.Errorf("O resultado com covariâncias esparsas estava incorreto.\nEsperado: %v\nObtido:   %v", expected, sparse.Reconciled)
+		}
+	})
+
+	t.Run("Covariâncias Inválidas", func(t *testing.T) {
+		invalid := map[string]Option{
+			"Não Simétrica": WithCovariance(mat.NewDense(3, 3, []float64{
+				1, 0.5, 0,
+				0.2, 1, 0,
+				0, 0, 1,
+			})),
+			"Não Definida Positiva": WithCovariance(mat.NewDense(3, 3, []float64{
+				1, 2, 0,
+				2, 1, 0,
+				0, 0, 1,
+			})),
+			"Dimensão Incompatível": WithCovariance(mat.NewDense(2, 2, []float64{1, 0, 0, 1})),
+			"Elemento na Diagonal":  WithCovariances([]CovarianceEntry{{Row: 1, Col: 1, Value: 0.5}}),
+			"Índice Fora":           WithCovariances([]CovarianceEntry{{Row: 1, Col: 3, Value: 0.5}}),
+		}
+		for name, opt := range invalid {
+			if _, err := Reconcile(measurements, tolerances, constraints, opt); err == nil {
+				t.Errorf("%s: esperava-se um erro, mas nenhum foi retornado", name)
+			}
+		}
+	})
+}
@@ -0,0 +1,243 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// minDesignBenefit é o menor benefício que justifica instalar um instrumento na seleção gulosa.
+const minDesignBenefit = 1e-9
+
+// Candidate é um instrumento candidato à instalação.
+type Candidate struct {
+	// Name identifica opcionalmente o candidato (ex: a tag do medidor).
+	Name string `json:"name,omitempty"`
+	// Variable é o índice da variável que o instrumento mediria.
+	Variable int `json:"variable"`
+	// Deviation é o desvio padrão do instrumento, na unidade da variável.
+	Deviation float64 `json:"deviation"`
+	// Cost é o custo do instrumento, em qualquer unidade consistente com o orçamento.
+	Cost float64 `json:"cost"`
+}
+
+// CandidateEvaluation é o efeito de instalar um candidato sobre a rede de instrumentos.
+type CandidateEvaluation struct {
+	// Candidate é o índice do candidato na lista informada.
+	Candidate int `json:"candidate"`
+	// Deviations são os desvios padrão das estimativas reconciliadas com o candidato instalado.
+	// Variáveis não observáveis não têm desvio (NaN, null em JSON).
+	Deviations Values `json:"deviations"`
+	// PrecisionGain é a soma, sobre as variáveis, da redução relativa do desvio padrão,
+	// 1 - σ_depois/σ_antes. Uma variável que se torna observável conta 1.
+	PrecisionGain float64 `json:"precisionGain"`
+	// GainPerCost é o ganho de precisão por unidade de custo, o critério da seleção gulosa.
+	GainPerCost float64 `json:"gainPerCost"`
+	// NewlyObservable são as variáveis que se tornam observáveis.
+	NewlyObservable []int `json:"newlyObservable"`
+	// NewlyRedundant são as variáveis medidas que se tornam redundantes.
+	NewlyRedundant []int `json:"newlyRedundant"`
+}
+
+// NetworkState descreve a precisão e a classificação das variáveis de uma rede de instrumentos.
+type NetworkState struct {
+	// Deviations são os desvios padrão das estimativas reconciliadas.
+	Deviations Values `json:"deviations"`
+	// Classification é a classificação de cada variável quanto à redundância e à observabilidade.
+	Classification []VariableClass `json:"classification"`
+}
+
+// DesignResult é o resultado do projeto da rede de instrumentos.
+type DesignResult struct {
+	// Baseline é a rede com os instrumentos atuais.
+	Baseline NetworkState `json:"baseline"`
+	// Candidates avalia cada candidato isoladamente sobre a rede atual (análise what-if).
+	Candidates []CandidateEvaluation `json:"candidates"`
+	// Budget é o orçamento da seleção gulosa.
+	Budget float64 `json:"budget"`
+	// Selected são as avaliações dos candidatos escolhidos, na ordem da seleção, cada uma
+	// relativa à rede com os escolhidos anteriores.
+	Selected []CandidateEvaluation `json:"selected"`
+	// TotalCost é o custo dos candidatos escolhidos.
+	TotalCost float64 `json:"totalCost"`
+	// Final é a rede com os instrumentos atuais e os escolhidos.
+	Final NetworkState `json:"final"`
+}
+
+// DesignSensors avalia onde novos instrumentos mais melhoram a reconciliação e escolhe, de
+// forma gulosa, um conjunto dentro do orçamento.
+//
+// A precisão das estimativas não depende dos valores medidos, apenas das restrições e da
+// matriz de pesos: cada rede é avaliada por Reconcile, com as medições nominais informadas e
+// os desvios padrão efetivos dos instrumentos atuais (as tolerâncias e opções de incerteza,
+// como em Reconcile). As variáveis não medidas (WithUnmeasured) são candidatas a
+// receber um instrumento; um candidato numa variável já medida é um segundo instrumento,
+// independente do primeiro, e os dois se combinam com 1/σ^2 = 1/σ_1^2 + 1/σ_2^2.
+//
+// Cada candidato é avaliado isoladamente sobre a rede atual. A seleção gulosa escolhe, a cada
+// passo, o candidato que cabe no orçamento restante com o maior ganho de precisão por custo
+// sobre a rede já escolhida, até que nenhum caiba ou traga ganho. Orçamento zero apenas avalia
+// os candidatos.
+func DesignSensors(measurements, tolerances []float64, constraints *mat.Dense, candidates []Candidate, budget float64, opts ...Option) (*DesignResult, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if o.covariance != nil {
+		return nil, fmt.Errorf("%w: o projeto da rede de instrumentos não aceita a matriz de covariância completa, use covariâncias esparsas", ErrInvalidInput)
+	}
+	if budget < 0 {
+		return nil, fmt.Errorf("%w: o orçamento não pode ser negativo, obtido %v", ErrInvalidInput, budget)
+	}
+	n := len(measurements)
+	for k, c := range candidates {
+		if c.Variable < 0 || c.Variable >= n {
+			return nil, fmt.Errorf("%w: candidato %d: índice de variável fora do intervalo: %d", ErrInvalidInput, k, c.Variable)
+		}
+		if c.Deviation <= 0 || c.Cost <= 0 {
+			return nil, fmt.Errorf("%w: candidato %d: o desvio padrão e o custo devem ser positivos", ErrInvalidInput, k)
+		}
+	}
+
+	// Os desvios efetivos dos instrumentos atuais vêm da reconciliação da rede atual.
+	current, err := Reconcile(measurements, tolerances, constraints, opts...)
+	if err != nil {
+		return nil, err
+	}
+	network := &sensorNetwork{measurements: measurements, constraints: constraints, o: o}
+	deviations := append([]float64(nil), current.Deviations...)
+	baseline, err := network.evaluate(deviations)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DesignResult{
+		Baseline:   *baseline,
+		Candidates: make([]CandidateEvaluation, len(candidates)),
+		Budget:     budget,
+		Selected:   []CandidateEvaluation{},
+	}
+	for k, c := range candidates {
+		evaluation, _, _, err := network.install(deviations, baseline, k, c)
+		if err != nil {
+			return nil, err
+		}
+		result.Candidates[k] = *evaluation
+	}
+
+	state := baseline
+	chosen := make(map[int]bool)
+	for {
+		best := -1
+		var bestEvaluation *CandidateEvaluation
+		var bestDeviations []float64
+		var bestState *NetworkState
+		for k, c := range candidates {
+			if chosen[k] || result.TotalCost+c.Cost > budget {
+				continue
+			}
+			evaluation, installed, after, err := network.install(deviations, state, k, c)
+			if err != nil {
+				return nil, err
+			}
+			if evaluation.PrecisionGain <= minDesignBenefit {
+				continue
+			}
+			if best < 0 || evaluation.GainPerCost > bestEvaluation.GainPerCost {
+				best, bestEvaluation, bestDeviations, bestState = k, evaluation, installed, after
+			}
+		}
+		if best < 0 {
+			break
+		}
+		chosen[best] = true
+		deviations, state = bestDeviations, bestState
+		result.Selected = append(result.Selected, *bestEvaluation)
+		result.TotalCost += candidates[best].Cost
+	}
+	result.Final = *state
+	return result, nil
+}
+
+// sensorNetwork avalia redes de instrumentos sobre as mesmas restrições.
+type sensorNetwork struct {
+	measurements []float64
+	constraints  *mat.Dense
+	o            options
+}
+
+// evaluate reconcilia a rede com os desvios padrão absolutos informados, um por variável; as
+// variáveis com desvio NaN não são medidas. Variáveis sem valor nominal recebem zero, que não
+// altera a precisão.
+func (s *sensorNetwork) evaluate(deviations []float64) (*NetworkState, error) {
+	n := len(deviations)
+	values := make([]float64, n)
+	var unmeasured []int
+	for i, d := range deviations {
+		values[i] = s.measurements[i]
+		if math.IsNaN(d) {
+			unmeasured = append(unmeasured, i)
+			continue
+		}
+		if math.IsNaN(values[i]) {
+			values[i] = 0
+		}
+	}
+	opts := []Option{
+		WithUncertaintyMode(UncertaintyAbsolute),
+		WithUnmeasured(unmeasured),
+		WithRHS(s.o.rhs),
+		WithCovariances(s.o.covariances),
+		WithSolver(s.o.solver),
+		WithReconciledCovariance(false),
+	}
+	if s.o.dropRedundant {
+		opts = append(opts, WithDropRedundantConstraints())
+	}
+	result, err := Reconcile(values, deviations, s.constraints, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &NetworkState{Deviations: result.ReconciledDeviations, Classification: result.Classification}, nil
+}
+
+// install avalia o candidato k sobre a rede com os desvios informados, cujo estado é before,
+// e retorna a avaliação, os desvios e o estado da rede com o candidato instalado.
+func (s *sensorNetwork) install(deviations []float64, before *NetworkState, k int, c Candidate) (*CandidateEvaluation, []float64, *NetworkState, error) {
+	installed := append([]float64(nil), deviations...)
+	if existing := installed[c.Variable]; math.IsNaN(existing) {
+		installed[c.Variable] = c.Deviation
+	} else {
+		installed[c.Variable] = 1 / math.Sqrt(1/(existing*existing)+1/(c.Deviation*c.Deviation))
+	}
+	after, err := s.evaluate(installed)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("candidato %d: %w", k, err)
+	}
+
+	evaluation := &CandidateEvaluation{
+		Candidate:       k,
+		Deviations:      after.Deviations,
+		NewlyObservable: []int{},
+		NewlyRedundant:  []int{},
+	}
+	for i, sigma := range after.Deviations {
+		previous := before.Deviations[i]
+		switch {
+		case math.IsNaN(sigma):
+		case math.IsNaN(previous):
+			evaluation.PrecisionGain++
+		case previous > 0:
+			evaluation.PrecisionGain += math.Max(1-sigma/previous, 0)
+		}
+		if before.Classification[i] == ClassUnobservable && after.Classification[i] != ClassUnobservable {
+			evaluation.NewlyObservable = append(evaluation.NewlyObservable, i)
+		}
+		if before.Classification[i] != ClassRedundant && after.Classification[i] == ClassRedundant {
+			evaluation.NewlyRedundant = append(evaluation.NewlyRedundant, i)
+		}
+	}
+	evaluation.GainPerCost = evaluation.PrecisionGain / c.Cost
+	return evaluation, installed, after, nil
+}
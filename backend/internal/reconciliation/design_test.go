@@ -0,0 +1,90 @@
+package reconciliation
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestDesignSensors(t *testing.T) {
+	// x1, x2 e x3 não medidas: só a soma x1 + x2 é determinada pelos balanços.
+	measurements := []float64{100, math.NaN(), math.NaN(), math.NaN(), 101}
+	tolerances := []float64{1, 0, 0, 0, 1}
+	absolute := WithUncertaintyMode(UncertaintyAbsolute)
+	unmeasured := WithUnmeasured([]int{1, 2, 3})
+	candidates := []Candidate{
+		{Name: "FT-1", Variable: 1, Deviation: 0.5, Cost: 5},
+		{Name: "FT-2", Variable: 2, Deviation: 0.5, Cost: 10},
+		{Name: "FT-3", Variable: 3, Deviation: 0.5, Cost: 10},
+		{Name: "FT-0B", Variable: 0, Deviation: 1, Cost: 1},
+	}
+
+	result, err := DesignSensors(measurements, tolerances, networkConstraints(), candidates, 16, absolute, unmeasured)
+	if err != nil {
+		t.Fatalf("A função DesignSensors retornou um erro inesperado: %v", err)
+	}
+
+	t.Run("Avaliação dos Candidatos", func(t *testing.T) {
+		for _, i := range []int{1, 2, 3} {
+			if result.Baseline.Classification[i] != ClassUnobservable {
+				t.Fatalf("A variável %d deveria ser não observável na rede atual: %v", i, result.Baseline.Classification)
+			}
+		}
+		for k := 0; k < 3; k++ {
+			evaluation := result.Candidates[k]
+			if !slices.Equal(evaluation.NewlyObservable, []int{1, 2, 3}) || evaluation.PrecisionGain < 3 {
+				t.Errorf("O candidato %d deveria tornar x1, x2 e x3 observáveis: %+v", k, evaluation)
+			}
+		}
+		// Um segundo medidor em x0 melhora a sua precisão, mas não a observabilidade.
+		duplicate := result.Candidates[3]
+		if len(duplicate.NewlyObservable) != 0 || duplicate.PrecisionGain <= 0 || duplicate.Deviations[0] >= result.Baseline.Deviations[0] {
+			t.Errorf("O medidor duplicado deveria apenas melhorar x0: %+v", duplicate)
+		}
+	})
+
+	t.Run("Seleção Gulosa", func(t *testing.T) {
+		if len(result.Selected) == 0 || result.Selected[0].Candidate != 0 {
+			t.Fatalf("O candidato mais barato que torna as variáveis observáveis deveria ser o primeiro: %+v", result.Selected)
+		}
+		cost := 0.0
+		for _, s := range result.Selected {
+			cost += candidates[s.Candidate].Cost
+		}
+		if cost != result.TotalCost || cost > 16 {
+			t.Errorf("Custo total incorreto: %v (soma %v)", result.TotalCost, cost)
+		}
+		for i, class := range result.Final.Classification {
+			if class == ClassUnobservable {
+				t.Errorf("A variável %d deveria ser observável na rede final", i)
+			}
+		}
+		for i, sigma := range result.Final.Deviations {
+			if before := result.Baseline.Deviations[i]; !math.IsNaN(before) && sigma > before+1e-12 {
+				t.Errorf("A precisão da variável %d não deveria piorar: %v -> %v", i, before, sigma)
+			}
+		}
+	})
+
+	t.Run("Sem Orçamento", func(t *testing.T) {
+		result, err := DesignSensors(measurements, tolerances, networkConstraints(), candidates, 0, absolute, unmeasured)
+		if err != nil {
+			t.Fatalf("A função DesignSensors retornou um erro inesperado: %v", err)
+		}
+		if len(result.Selected) != 0 || result.TotalCost != 0 || len(result.Candidates) != len(candidates) {
+			t.Errorf("Sem orçamento, os candidatos deveriam apenas ser avaliados: %+v", result)
+		}
+	})
+
+	t.Run("Entradas Inválidas", func(t *testing.T) {
+		if _, err := DesignSensors(measurements, tolerances, networkConstraints(), []Candidate{{Variable: 5, Deviation: 1, Cost: 1}}, 1, absolute, unmeasured); err == nil {
+			t.Error("Um candidato fora do intervalo deveria gerar um erro")
+		}
+		if _, err := DesignSensors(measurements, tolerances, networkConstraints(), []Candidate{{Variable: 1, Deviation: 0, Cost: 1}}, 1, absolute, unmeasured); err == nil {
+			t.Error("Um candidato sem desvio padrão deveria gerar um erro")
+		}
+		if _, err := DesignSensors(measurements, tolerances, networkConstraints(), candidates, -1, absolute, unmeasured); err == nil {
+			t.Error("Um orçamento negativo deveria gerar um erro")
+		}
+	})
+}
@@ -0,0 +1,347 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// Tank é um tanque (ou qualquer inventário) cujo nível é uma variável de estado. A variação do
+// inventário é a diferença entre as correntes de entrada e de saída: dH/dt = Σ entradas - Σ saídas.
+type Tank struct {
+	// Holdup é o índice da variável do inventário (H).
+	Holdup int `json:"holdup"`
+	// Inlets e Outlets são os índices das correntes que entram e saem do tanque.
+	Inlets  []int `json:"inlets"`
+	Outlets []int `json:"outlets"`
+}
+
+// Sample é um vetor de medições num instante.
+type Sample struct {
+	// Time é o instante da amostra, em qualquer unidade consistente com ProcessNoise.
+	Time float64 `json:"time"`
+	// Measurements são os valores medidos, um por variável. Um elemento NaN (null em JSON) é
+	// uma medição ausente naquele instante.
+	Measurements Values `json:"measurements"`
+}
+
+// DynamicModel descreve o processo da reconciliação dinâmica.
+type DynamicModel struct {
+	// Constraints são as restrições algébricas (B*x = c) válidas a cada instante, como os balanços
+	// dos nós sem acúmulo. Pode ser nula quando só há tanques. O lado direito vem de WithRHS.
+	Constraints *mat.Dense
+	// Tanks são os tanques cujos inventários evoluem com as correntes.
+	Tanks []Tank
+	// ProcessNoise é o desvio padrão da variação aleatória de cada variável por unidade de tempo
+	// (passeio aleatório). A variância do passo é ProcessNoise_i^2 * Δt. Deve ser positivo.
+	ProcessNoise []float64
+}
+
+// DynamicEstimate é a estimativa num instante da trajetória.
+type DynamicEstimate struct {
+	// Time é o instante da amostra.
+	Time float64 `json:"time"`
+	// Estimates são as estimativas filtradas, que obedecem às restrições algébricas.
+	Estimates Values `json:"estimates"`
+	// Deviations são os desvios padrão das estimativas.
+	Deviations Values `json:"deviations"`
+	// Lower e Upper são as faixas de incerteza, estimativa ∓ z * desvio, no nível de confiança.
+	Lower Values `json:"lower"`
+	Upper Values `json:"upper"`
+	// Innovations são as diferenças entre as medições e as previsões do modelo (m - x⁻).
+	// Medições ausentes e a primeira amostra não têm inovação (NaN, null em JSON).
+	Innovations Values `json:"innovations"`
+}
+
+// DynamicResult é o resultado da reconciliação dinâmica.
+type DynamicResult struct {
+	// Confidence é o nível de confiança das faixas de incerteza.
+	Confidence float64 `json:"confidence"`
+	// Trajectory são as estimativas, uma por amostra, na ordem do tempo.
+	Trajectory []DynamicEstimate `json:"trajectory"`
+}
+
+// ReconcileDynamic reconcilia uma série temporal de medições com um filtro de Kalman com
+// restrições de igualdade.
+//
+// A primeira amostra é reconciliada em regime permanente (Reconcile), o que fornece o estado
+// inicial e a sua covariância; todas as variáveis precisam ser observáveis nela. A cada nova
+// amostra, com Δt desde a anterior:
+//
+//  1. Previsão: x⁻ = F*x e P⁻ = F*P*F^T + Q. As correntes seguem um passeio aleatório e os
+//     inventários integram as correntes, H⁻ = H + Δt * (Σ entradas - Σ saídas). Q é diagonal,
+//     com Q_ii = ProcessNoise_i^2 * Δt.
+//  2. Atualização: as medições presentes corrigem a previsão pelo ganho de Kalman,
+//     K = P⁻*H^T*(H*P⁻*H^T + R)^-1, com R a covariância das medições (tolerâncias e opções de
+//     incerteza, como em Reconcile).
+//  3. Projeção: a estimativa é projetada nas restrições algébricas com a sua covariância, o que
+//     é exatamente a reconciliação de x⁺ com V = P⁺ (ver solveKKT).
+//
+// Cada estimativa usa as amostras até o seu instante (filtragem), e a covariância propagada
+// fornece as faixas de incerteza da trajetória.
+func ReconcileDynamic(samples []Sample, tolerances []float64, model DynamicModel, opts ...Option) (*DynamicResult, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("%w: a série temporal não pode estar vazia", ErrInvalidInput)
+	}
+	n := len(tolerances)
+	if err := validateDynamic(samples, n, model, o); err != nil {
+		return nil, err
+	}
+
+	// Estado inicial: reconciliação em regime permanente da primeira amostra.
+	first := samples[0]
+	x, p, err := initialState(first.Measurements, tolerances, model.Constraints, o, opts)
+	if err != nil {
+		return nil, err
+	}
+	z := distuv.UnitNormal.Quantile(1 - (1-o.confidence)/2)
+	result := &DynamicResult{Confidence: o.confidence}
+	innovations := make(Values, n)
+	for i := range innovations {
+		innovations[i] = math.NaN()
+	}
+	result.Trajectory = append(result.Trajectory, newEstimate(first.Time, x, p, innovations, z))
+
+	// F = I + Δt*G, onde G leva as correntes aos inventários dos tanques.
+	flows := mat.NewDense(n, n, nil)
+	for _, tank := range model.Tanks {
+		for _, j := range tank.Inlets {
+			flows.Set(tank.Holdup, j, flows.At(tank.Holdup, j)+1)
+		}
+		for _, j := range tank.Outlets {
+			flows.Set(tank.Holdup, j, flows.At(tank.Holdup, j)-1)
+		}
+	}
+
+	for s := 1; s < len(samples); s++ {
+		sample := samples[s]
+		dt := sample.Time - samples[s-1].Time
+
+		// 1. Previsão.
+		transition := mat.NewDense(n, n, nil)
+		transition.Scale(dt, flows)
+		for i := 0; i < n; i++ {
+			transition.Set(i, i, transition.At(i, i)+1)
+		}
+		var predicted mat.VecDense
+		predicted.MulVec(transition, mat.NewVecDense(n, x))
+		var fp, predictedCov mat.Dense
+		fp.Mul(transition, p)
+		predictedCov.Mul(&fp, transition.T())
+		for i := 0; i < n; i++ {
+			predictedCov.Set(i, i, predictedCov.At(i, i)+model.ProcessNoise[i]*model.ProcessNoise[i]*dt)
+		}
+
+		// 2. Atualização com as medições presentes.
+		var measured []int
+		deviations := make(Values, n)
+		for i, m := range sample.Measurements {
+			if math.IsNaN(m) || o.unmeasured[i] {
+				continue
+			}
+			measured = append(measured, i)
+			if o.covariance == nil {
+				if deviations[i], err = deviation(i, m, tolerances[i], o.uncertainty(i)); err != nil {
+					return nil, fmt.Errorf("amostra %d: %w", s, err)
+				}
+			}
+		}
+		updated := predicted.RawVector().Data
+		updatedCov := &predictedCov
+		for i := range innovations {
+			innovations[i] = math.NaN()
+		}
+		if len(measured) > 0 {
+			_, noise, err := weightMatrix(o, deviations, measured)
+			if err != nil {
+				return nil, fmt.Errorf("amostra %d: %w", s, err)
+			}
+			k := len(measured)
+			innovation := mat.NewVecDense(k, nil)
+			crossCov := mat.NewDense(n, k, nil)
+			innovationCov := mat.NewSymDense(k, nil)
+			for a, i := range measured {
+				innovation.SetVec(a, sample.Measurements[i]-updated[i])
+				innovations[i] = innovation.AtVec(a)
+				for row := 0; row < n; row++ {
+					crossCov.Set(row, a, predictedCov.At(row, i))
+				}
+				for b, j := range measured[a:] {
+					innovationCov.SetSym(a, a+b, predictedCov.At(i, j)+noise.At(a, a+b))
+				}
+			}
+			var chol mat.Cholesky
+			if ok := chol.Factorize(innovationCov); !ok {
+				return nil, fmt.Errorf("amostra %d: a covariância das inovações não é definida positiva", s)
+			}
+			// K^T = S^-1 * (P⁻*H^T)^T, x⁺ = x⁻ + K*ν e P⁺ = P⁻ - K*(P⁻*H^T)^T.
+			var gainT mat.Dense
+			if err := chol.SolveTo(&gainT, crossCov.T()); err != nil {
+				return nil, fmt.Errorf("amostra %d: %w", s, err)
+			}
+			var correction mat.VecDense
+			correction.MulVec(gainT.T(), innovation)
+			updated = make([]float64, n)
+			for i := range updated {
+				updated[i] = predicted.AtVec(i) + correction.AtVec(i)
+			}
+			var reduction mat.Dense
+			reduction.Mul(crossCov, &gainT)
+			updatedCov = mat.NewDense(n, n, nil)
+			updatedCov.Sub(&predictedCov, &reduction)
+		}
+
+		// 3. Projeção nas restrições algébricas.
+		if x, p, err = project(updated, symmetric(updatedCov), model.Constraints, o.rhs); err != nil {
+			return nil, fmt.Errorf("amostra %d: %w", s, err)
+		}
+		result.Trajectory = append(result.Trajectory, newEstimate(sample.Time, x, p, innovations, z))
+	}
+	return result, nil
+}
+
+// validateDynamic verifica as dimensões da série temporal e do modelo dinâmico.
+func validateDynamic(samples []Sample, n int, model DynamicModel, o options) error {
+	for s, sample := range samples {
+		if len(sample.Measurements) != n {
+			return fmt.Errorf("%w: amostra %d (%d) e tolerâncias (%d)", ErrDimensionMismatch, s, len(sample.Measurements), n)
+		}
+		if s > 0 && sample.Time <= samples[s-1].Time {
+			return fmt.Errorf("%w: os instantes das amostras devem ser crescentes: amostra %d (%v) após %v", ErrInvalidInput, s, sample.Time, samples[s-1].Time)
+		}
+	}
+	if len(model.ProcessNoise) != n {
+		return fmt.Errorf("%w: ruído de processo (%d) e tolerâncias (%d)", ErrDimensionMismatch, len(model.ProcessNoise), n)
+	}
+	for i, q := range model.ProcessNoise {
+		if q <= 0 {
+			return fmt.Errorf("%w: o ruído de processo da variável %d deve ser positivo: %v", ErrInvalidInput, i, q)
+		}
+	}
+	inRange := func(i int) bool { return i >= 0 && i < n }
+	for _, tank := range model.Tanks {
+		if !inRange(tank.Holdup) {
+			return fmt.Errorf("%w: índice de inventário fora do intervalo: %d", ErrInvalidInput, tank.Holdup)
+		}
+		for _, j := range append(append([]int(nil), tank.Inlets...), tank.Outlets...) {
+			if !inRange(j) {
+				return fmt.Errorf("%w: índice de corrente do tanque %d fora do intervalo: %d", ErrInvalidInput, tank.Holdup, j)
+			}
+		}
+	}
+	if model.Constraints != nil {
+		rows, cols := model.Constraints.Dims()
+		if cols != n {
+			return fmt.Errorf("%w: colunas das restrições (%d) e tolerâncias (%d)", ErrDimensionMismatch, cols, n)
+		}
+		if o.rhs != nil && len(o.rhs) != rows {
+			return fmt.Errorf("%w: linhas das restrições (%d) e lado direito (%d)", ErrDimensionMismatch, rows, len(o.rhs))
+		}
+	}
+	if o.uncertainties != nil && len(o.uncertainties) != n {
+		return fmt.Errorf("%w: tolerâncias (%d) e incertezas (%d)", ErrDimensionMismatch, n, len(o.uncertainties))
+	}
+	return validateCovariance(o, n)
+}
+
+// initialState reconcilia a primeira amostra em regime permanente e retorna o estado inicial
+// e a sua covariância. Sem restrições algébricas, o estado inicial são as próprias medições,
+// com a covariância das medições.
+func initialState(measurements Values, tolerances []float64, constraints *mat.Dense, o options, opts []Option) ([]float64, *mat.SymDense, error) {
+	n := len(measurements)
+	var missing []int
+	for i, m := range measurements {
+		if math.IsNaN(m) || o.unmeasured[i] {
+			missing = append(missing, i)
+		}
+	}
+	if constraints == nil {
+		if len(missing) > 0 {
+			return nil, nil, fmt.Errorf("%w: a variável %d não é medida na primeira amostra e não há restrições para estimá-la", ErrInvalidInput, missing[0])
+		}
+		deviations := make(Values, n)
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+			if o.covariance != nil {
+				continue
+			}
+			var err error
+			if deviations[i], err = deviation(i, measurements[i], tolerances[i], o.uncertainty(i)); err != nil {
+				return nil, nil, fmt.Errorf("amostra 0: %w", err)
+			}
+		}
+		_, cov, err := weightMatrix(o, deviations, all)
+		if err != nil {
+			return nil, nil, fmt.Errorf("amostra 0: %w", err)
+		}
+		return append([]float64(nil), measurements...), symmetric(cov), nil
+	}
+	initialOpts := append(append([]Option(nil), opts...), WithUnmeasured(missing), WithReconciledCovariance(true))
+	result, err := Reconcile(measurements, tolerances, constraints, initialOpts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("falha na reconciliação da primeira amostra: %w", err)
+	}
+	cov := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		if math.IsNaN(result.Reconciled[i]) {
+			return nil, nil, fmt.Errorf("a variável %d não é observável na primeira amostra", i)
+		}
+		for j := i; j < n; j++ {
+			cov.SetSym(i, j, result.Covariance[i][j])
+		}
+	}
+	return result.Reconciled, cov, nil
+}
+
+// project projeta a estimativa x, com covariância p, nas restrições B*x = c. Sem restrições,
+// retorna a própria estimativa.
+func project(x []float64, p *mat.SymDense, constraints *mat.Dense, rhs []float64) ([]float64, *mat.SymDense, error) {
+	if constraints == nil {
+		return x, p, nil
+	}
+	sol, err := solveKKT(constraints, nil, p, x, rhs, true, SolverDense)
+	if err != nil {
+		return nil, nil, err
+	}
+	n := len(x)
+	return sol.x, symmetric(mat.NewDense(n, n, sol.covariance)), nil
+}
+
+// symmetric retorna a parte simétrica (A + A^T)/2 de uma matriz quadrada, que elimina a
+// assimetria acumulada por arredondamento na propagação da covariância.
+func symmetric(a mat.Matrix) *mat.SymDense {
+	n, _ := a.Dims()
+	out := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			out.SetSym(i, j, (a.At(i, j)+a.At(j, i))/2)
+		}
+	}
+	return out
+}
+
+// newEstimate monta a estimativa de um instante, com as faixas de incerteza ∓ z*σ.
+func newEstimate(t float64, x []float64, p *mat.SymDense, innovations Values, z float64) DynamicEstimate {
+	n := len(x)
+	e := DynamicEstimate{
+		Time:        t,
+		Estimates:   append(Values(nil), x...),
+		Deviations:  make(Values, n),
+		Lower:       make(Values, n),
+		Upper:       make(Values, n),
+		Innovations: append(Values(nil), innovations...),
+	}
+	for i := range x {
+		e.Deviations[i] = math.Sqrt(math.Max(p.At(i, i), 0))
+		e.Lower[i] = x[i] - z*e.Deviations[i]
+		e.Upper[i] = x[i] + z*e.Deviations[i]
+	}
+	return e
+}
@@ -0,0 +1,144 @@
+package reconciliation
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// tankSeries gera uma série de um tanque alimentado por um divisor: as variáveis são a
+// alimentação (F0), a entrada do tanque (F1 = F0), a saída do tanque (F2) e o inventário (H),
+// que sobe 2 unidades por instante. As medições têm ruído normal com desvio de 1% do valor.
+func tankSeries(numSamples int, seed int64) ([]Sample, [][]float64, []float64, DynamicModel) {
+	rng := rand.New(rand.NewSource(seed))
+	tolerances := []float64{0.02, 0.02, 0.02, 0.02}
+	var samples []Sample
+	var truth [][]float64
+	for s := 0; s < numSamples; s++ {
+		state := []float64{10, 10, 8, 50 + 2*float64(s)}
+		measurements := make(Values, len(state))
+		for i, x := range state {
+			measurements[i] = x * (1 + 0.01*rng.NormFloat64())
+		}
+		samples = append(samples, Sample{Time: float64(s), Measurements: measurements})
+		truth = append(truth, state)
+	}
+	model := DynamicModel{
+		Constraints:  mat.NewDense(1, 4, []float64{1, -1, 0, 0}),
+		Tanks:        []Tank{{Holdup: 3, Inlets: []int{1}, Outlets: []int{2}}},
+		ProcessNoise: []float64{0.01, 0.01, 0.01, 0.01},
+	}
+	return samples, truth, tolerances, model
+}
+
+func TestReconcileDynamic(t *testing.T) {
+	samples, truth, tolerances, model := tankSeries(40, 1)
+	result, err := ReconcileDynamic(samples, tolerances, model)
+	if err != nil {
+		t.Fatalf("A função ReconcileDynamic retornou um erro inesperado: %v", err)
+	}
+
+	t.Run("Restrições e Faixas", func(t *testing.T) {
+		if len(result.Trajectory) != len(samples) || result.Confidence != DefaultConfidence {
+			t.Fatalf("Trajetória incorreta: %d estimativas, confiança %v", len(result.Trajectory), result.Confidence)
+		}
+		for s, e := range result.Trajectory {
+			if e.Time != samples[s].Time {
+				t.Errorf("Instante %d incorreto: %v", s, e.Time)
+			}
+			if r := e.Estimates[0] - e.Estimates[1]; math.Abs(r) > 1e-9 {
+				t.Errorf("A estimativa %d não obedece à restrição: resíduo %v", s, r)
+			}
+			for i, x := range e.Estimates {
+				if e.Lower[i] >= x || e.Upper[i] <= x {
+					t.Errorf("A faixa de incerteza da variável %d no instante %d não contém a estimativa", i, s)
+				}
+			}
+		}
+		for i, v := range result.Trajectory[0].Innovations {
+			if !math.IsNaN(v) {
+				t.Errorf("A primeira amostra não deveria ter inovação: variável %d = %v", i, v)
+			}
+		}
+	})
+
+	t.Run("Filtragem", func(t *testing.T) {
+		// Depois do transitório, as estimativas ficam mais perto dos valores verdadeiros que as
+		// medições, e o desvio das estimativas fica abaixo do das medições.
+		var measuredError, filteredError float64
+		for s := 10; s < len(samples); s++ {
+			for i := range truth[s] {
+				measuredError += math.Pow(samples[s].Measurements[i]-truth[s][i], 2)
+				filteredError += math.Pow(result.Trajectory[s].Estimates[i]-truth[s][i], 2)
+			}
+		}
+		if filteredError > measuredError/2 {
+			t.Errorf("A filtragem deveria reduzir o erro: filtrado %v, medido %v", filteredError, measuredError)
+		}
+		first, last := result.Trajectory[0], result.Trajectory[len(samples)-1]
+		for i := range last.Deviations {
+			if last.Deviations[i] >= first.Deviations[i] {
+				t.Errorf("O desvio da variável %d deveria diminuir com a filtragem: %v -> %v", i, first.Deviations[i], last.Deviations[i])
+			}
+		}
+	})
+
+	t.Run("Medições Ausentes", func(t *testing.T) {
+		missing := append([]Sample(nil), samples...)
+		missing[5] = Sample{Time: 5, Measurements: Values{math.NaN(), math.NaN(), math.NaN(), math.NaN()}}
+		result, err := ReconcileDynamic(missing, tolerances, model)
+		if err != nil {
+			t.Fatalf("A função ReconcileDynamic retornou um erro inesperado: %v", err)
+		}
+		estimate := result.Trajectory[5]
+		// Sem medições, o inventário avança pelo modelo e a incerteza cresce.
+		previous := result.Trajectory[4]
+		want := previous.Estimates[3] + previous.Estimates[1] - previous.Estimates[2]
+		if math.Abs(estimate.Estimates[3]-want) > 1e-9 {
+			t.Errorf("O inventário previsto deveria ser %v, obtido %v", want, estimate.Estimates[3])
+		}
+		if estimate.Deviations[3] <= previous.Deviations[3] || !math.IsNaN(estimate.Innovations[3]) {
+			t.Errorf("Estimativa sem medições incorreta: %+v", estimate)
+		}
+	})
+
+	t.Run("Sem Restrições", func(t *testing.T) {
+		free := model
+		free.Constraints = nil
+		result, err := ReconcileDynamic(samples, tolerances, free)
+		if err != nil {
+			t.Fatalf("A função ReconcileDynamic retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Trajectory[0].Estimates, samples[0].Measurements, 0) {
+			t.Errorf("Sem restrições, o estado inicial deveria ser a primeira medição: %v", result.Trajectory[0].Estimates)
+		}
+	})
+
+	t.Run("Entradas Inválidas", func(t *testing.T) {
+		reversed := append([]Sample(nil), samples...)
+		reversed[3].Time = 1
+		if _, err := ReconcileDynamic(reversed, tolerances, model); err == nil {
+			t.Error("Instantes não crescentes deveriam gerar um erro")
+		}
+		if _, err := ReconcileDynamic(nil, tolerances, model); err == nil {
+			t.Error("Uma série vazia deveria gerar um erro")
+		}
+		noisy := model
+		noisy.ProcessNoise = []float64{0.01, 0, 0.01, 0.01}
+		if _, err := ReconcileDynamic(samples, tolerances, noisy); err == nil {
+			t.Error("Um ruído de processo nulo deveria gerar um erro")
+		}
+		tanks := model
+		tanks.Tanks = []Tank{{Holdup: 4}}
+		if _, err := ReconcileDynamic(samples, tolerances, tanks); err == nil {
+			t.Error("Um índice de inventário fora do intervalo deveria gerar um erro")
+		}
+		unobservable := append([]Sample(nil), samples...)
+		unobservable[0] = Sample{Time: 0, Measurements: Values{10, 10, 8, math.NaN()}}
+		if _, err := ReconcileDynamic(unobservable, tolerances, model); err == nil {
+			t.Error("Um inventário não medido na primeira amostra deveria gerar um erro")
+		}
+	})
+}
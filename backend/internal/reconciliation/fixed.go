@@ -0,0 +1,68 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// fixedResidualTolerance é a tolerância relativa do resíduo de uma restrição que só envolve
+// variáveis fixas, abaixo da qual a restrição é considerada satisfeita.
+const fixedResidualTolerance = 1e-9
+
+// fixVariables leva as variáveis fixas ao lado direito das restrições: B*x = c passa a
+// B_L*x_L = c - B_F*m_F, onde F são as variáveis fixas e L as demais. Retorna as restrições com
+// as colunas das fixas zeradas e o novo lado direito, com o mesmo número de linhas, de modo
+// que os resíduos, os multiplicadores e os testes continuam indexados pelas restrições
+// originais; como x_F = m_F, os resíduos B*x - c também não mudam.
+//
+// Uma restrição que só envolve variáveis fixas não restringe as demais: ela é descartada,
+// junto às linhas de o.dropped, se as medições fixas a satisfazem, e é um erro do contrário.
+// As variáveis fixas também devem respeitar os seus limites (WithBounds).
+func fixVariables(measurements []float64, constraints *mat.Dense, fixed map[int]bool, o options) (*mat.Dense, []float64, map[int]bool, error) {
+	for i := range fixed {
+		m := measurements[i]
+		if math.IsNaN(m) || math.IsInf(m, 0) {
+			return nil, nil, nil, fmt.Errorf("%w: a variável fixa %d precisa de um valor medido, obtido %v", ErrInvalidInput, i, m)
+		}
+		if (o.lower != nil && m < o.lower[i]) || (o.upper != nil && m > o.upper[i]) {
+			return nil, nil, nil, fmt.Errorf("%w: o valor da variável fixa %d está fora dos seus limites: %v", ErrInvalidInput, i, m)
+		}
+	}
+
+	numConstraints, numVariables := constraints.Dims()
+	free := mat.NewDense(numConstraints, numVariables, nil)
+	free.Copy(constraints)
+	rhs := make([]float64, numConstraints)
+	copy(rhs, o.rhs)
+	dropped := make(map[int]bool, len(o.dropped))
+	for row := range o.dropped {
+		dropped[row] = true
+	}
+	for k := 0; k < numConstraints; k++ {
+		row := free.RawRowView(k)
+		scale := math.Abs(rhs[k])
+		remaining := false
+		for j, b := range row {
+			if b == 0 {
+				continue
+			}
+			if !fixed[j] {
+				remaining = true
+				continue
+			}
+			rhs[k] -= b * measurements[j]
+			scale += math.Abs(b * measurements[j])
+			row[j] = 0
+		}
+		if remaining || dropped[k] {
+			continue
+		}
+		if math.Abs(rhs[k]) > fixedResidualTolerance*math.Max(scale, 1) {
+			return nil, nil, nil, fmt.Errorf("%w: a restrição %d só envolve variáveis fixas e os seus valores não a satisfazem: resíduo %v", ErrInvalidInput, k, -rhs[k])
+		}
+		dropped[k] = true
+	}
+	return free, rhs, dropped, nil
+}
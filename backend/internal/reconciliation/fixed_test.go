@@ -0,0 +1,97 @@
+package reconciliation
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestFixedVariables(t *testing.T) {
+	t.Run("Tolerância Zero", func(t *testing.T) {
+		// Exemplo 2 com a entrada x0 certificada: as saídas são ajustadas para fechar com ela.
+		constraints := mat.NewDense(1, 3, []float64{1, -1, -1})
+		result, err := Reconcile([]float64{161, 79, 80}, []float64{0, 0.01, 0.01}, constraints)
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		if result.Reconciled[0] != 161 || result.Adjustments[0] != 0 || result.Deviations[0] != 0 || result.ReconciledDeviations[0] != 0 {
+			t.Errorf("A variável fixa não deveria ser ajustada: %v", result.Reconciled)
+		}
+		if result.Classification[0] != ClassFixed {
+			t.Errorf("Classificação incorreta da variável fixa: %v", result.Classification[0])
+		}
+		for j := 0; j < 3; j++ {
+			if result.Covariance[0][j] != 0 || result.Covariance[j][0] != 0 {
+				t.Fatalf("A covariância da variável fixa deveria ser zero: %v", result.Covariance)
+			}
+		}
+
+		// Equivale a reconciliar as demais com o termo fixo no lado direito: -x1 - x2 = -161.
+		moved, err := Reconcile([]float64{79, 80}, []float64{0.01, 0.01}, mat.NewDense(1, 2, []float64{-1, -1}), WithRHS([]float64{-161}))
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled[1:], moved.Reconciled, 1e-9) || math.Abs(result.Objective-moved.Objective) > 1e-9 {
+			t.Errorf("Resultado incorreto.\nEsperado: %v\nObtido:   %v", moved.Reconciled, result.Reconciled[1:])
+		}
+		if result.GlobalTest == nil || result.GlobalTest.DegreesOfFreedom != 1 {
+			t.Errorf("O teste global deveria ter 1 grau de liberdade: %+v", result.GlobalTest)
+		}
+		if math.Abs(result.ResidualsAfter[0]) > 1e-9 {
+			t.Errorf("O balanço deveria fechar com a variável fixa: %v", result.ResidualsAfter)
+		}
+	})
+
+	t.Run("Opção WithFixed com Não Medidas", func(t *testing.T) {
+		measurements := []float64{100, 60, math.NaN(), 40, 101}
+		tolerances := []float64{1, 1, 1, 1, 1}
+		result, err := Reconcile(measurements, tolerances, networkConstraints(),
+			WithUncertaintyMode(UncertaintyAbsolute), WithFixed([]int{0}), WithUnmeasured([]int{2}))
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		x := result.Reconciled
+		if x[0] != 100 || math.Abs(x[4]-100) > 1e-9 || math.Abs(x[1]+x[2]-100) > 1e-9 {
+			t.Errorf("Os balanços deveriam fechar com x0 = 100: %v", x)
+		}
+		if result.Classification[2] != ClassObservable {
+			t.Errorf("A variável não medida deveria ser observável: %v", result.Classification)
+		}
+	})
+
+	t.Run("Restrição Só com Variáveis Fixas", func(t *testing.T) {
+		constraints := mat.NewDense(2, 3, []float64{
+			1, -1, 0,
+			0, 1, -1,
+		})
+		result, err := Reconcile([]float64{10, 10, 10.5}, []float64{0, 0, 0.01}, constraints)
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		if math.Abs(result.Reconciled[2]-10) > 1e-9 || result.Lambda[0] != 0 {
+			t.Errorf("A restrição entre as fixas deveria ser descartada: %v, λ = %v", result.Reconciled, result.Lambda)
+		}
+		if _, err := Reconcile([]float64{10, 11, 10.5}, []float64{0, 0, 0.01}, constraints); err == nil {
+			t.Error("Esperado um erro para valores fixos que não satisfazem a restrição")
+		}
+	})
+
+	t.Run("Entradas Inválidas", func(t *testing.T) {
+		constraints := mat.NewDense(1, 3, []float64{1, -1, -1})
+		measurements := []float64{161, 79, 80}
+		tolerances := []float64{0.05, 0.01, 0.01}
+		if _, err := Reconcile(measurements, tolerances, constraints, WithFixed([]int{0}), WithUnmeasured([]int{0})); err == nil {
+			t.Error("Esperado um erro para uma variável fixa e não medida")
+		}
+		if _, err := Reconcile(measurements, tolerances, constraints, WithFixed([]int{3})); err == nil {
+			t.Error("Esperado um erro para um índice fora do intervalo")
+		}
+		if _, err := Reconcile(measurements, tolerances, constraints, WithFixed([]int{0}), WithBounds(nil, []float64{160, 100, 100})); err == nil {
+			t.Error("Esperado um erro para uma variável fixa fora dos limites")
+		}
+		if _, err := Reconcile(measurements, []float64{0, 0, 0}, constraints); err == nil {
+			t.Error("Esperado um erro sem variáveis a reconciliar")
+		}
+	})
+}
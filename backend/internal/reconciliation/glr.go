@@ -0,0 +1,261 @@
+package reconciliation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// HypothesisKind é o tipo de uma hipótese de erro grosseiro do teste GLR.
+type HypothesisKind string
+
+const (
+	// HypothesisBias é um viés numa medição: m_j = x_j + b.
+	HypothesisBias HypothesisKind = "bias"
+	// HypothesisLeak é um vazamento num nó: a restrição k passa a ser (B*x - c)_k = b, ou seja,
+	// b é o fluxo que deixa o nó sem ser medido quando a linha soma as entradas menos as saídas.
+	HypothesisLeak HypothesisKind = "leak"
+)
+
+// signatureTolerance é a norma abaixo da qual a assinatura de uma hipótese é considerada nula
+// (ex: o viés de uma medição não redundante), e a hipótese não pode ser detectada.
+const signatureTolerance = 1e-10
+
+// collinearityTolerance é a distância máxima de |cos| até 1 para que duas assinaturas sejam
+// consideradas colineares.
+const collinearityTolerance = 1e-9
+
+// Hypothesis é uma hipótese de erro grosseiro avaliada pelo teste GLR.
+type Hypothesis struct {
+	// Kind é o tipo da hipótese: viés de medição ou vazamento.
+	Kind HypothesisKind `json:"kind"`
+	// Index é o índice da medição (viés) ou da restrição (vazamento).
+	Index int `json:"index"`
+	// Statistic é a razão de verossimilhança generalizada, (f^T*V_r^-1*r)^2 / (f^T*V_r^-1*f).
+	Statistic float64 `json:"statistic"`
+	// Magnitude é a estimativa de máxima verossimilhança do tamanho do erro, f^T*V_r^-1*r / (f^T*V_r^-1*f).
+	Magnitude float64 `json:"magnitude"`
+	// StdError é o desvio padrão da estimativa da magnitude, 1/sqrt(f^T*V_r^-1*f).
+	StdError float64 `json:"stdError"`
+}
+
+// GLRResult é o resultado do teste da razão de verossimilhança generalizada (GLR).
+type GLRResult struct {
+	// Confidence é o nível de confiança global usado no teste.
+	Confidence float64 `json:"confidence"`
+	// CriticalValue é o quantil da qui-quadrado com 1 grau de liberdade, já com a correção de
+	// Šidák para o número de hipóteses.
+	CriticalValue float64 `json:"criticalValue"`
+	// Hypotheses são as hipóteses detectáveis, em ordem decrescente da estatística.
+	Hypotheses []Hypothesis `json:"hypotheses"`
+	// Detected indica se a maior estatística excede o valor crítico.
+	Detected bool `json:"detected"`
+	// MostLikely é a hipótese com a maior estatística.
+	MostLikely *Hypothesis `json:"mostLikely"`
+	// Equivalent são as outras hipóteses cuja assinatura nos resíduos é colinear com a de
+	// MostLikely (|cos| = 1 na métrica V_r^-1), que os dados não conseguem distinguir dela (ex:
+	// o viés de uma corrente que só passa por um nó e um vazamento nesse nó). Hipóteses com a
+	// mesma estatística mas assinaturas diferentes não são equivalentes.
+	Equivalent []Hypothesis `json:"equivalent"`
+	// Compensated é a reconciliação com o erro de MostLikely compensado, presente apenas
+	// quando um erro é detectado: a magnitude é subtraída da medição (viés) ou somada ao lado
+	// direito da restrição (vazamento).
+	Compensated *Result `json:"compensated,omitempty"`
+}
+
+// GLRTest aplica o teste da razão de verossimilhança generalizada aos resíduos das restrições
+// avaliados nas medições, r = P*(B_M*m - c), com a projeção P das variáveis não medidas.
+//
+// Sem erros grosseiros, r ~ N(0, V_r), com V_r = (P*B_M)*V*(P*B_M)^T. Um erro de magnitude b
+// com assinatura f desloca a média para b*f. A assinatura do viés da medição j é a coluna j
+// de P*B_M; a do vazamento no nó k é a coluna k de P. Para cada hipótese, a magnitude de
+// máxima verossimilhança e a estatística GLR, que segue uma qui-quadrado com 1 grau de
+// liberdade sem o erro, têm forma fechada. As hipóteses de assinatura nula (uma medição não
+// redundante, um nó com uma corrente não medida) não são detectáveis e ficam de fora, assim
+// como os vazamentos nas linhas descartadas por Reconcile (WithDropRedundantConstraints).
+//
+// A hipótese mais provável é a de maior estatística; quando ela excede o valor crítico, a
+// reconciliação é refeita com o erro compensado. Os parâmetros são os mesmos de Reconcile.
+func GLRTest(measurements, tolerances []float64, constraints *mat.Dense, opts ...Option) (*GLRResult, error) {
+	result, err := Reconcile(measurements, tolerances, constraints, opts...)
+	if err != nil {
+		return nil, err
+	}
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveMissing(measurements, &o); err != nil {
+		return nil, err
+	}
+	if err := applyScreening(&o, len(measurements)); err != nil {
+		return nil, err
+	}
+	deviations := result.Deviations
+	if result.Robust != nil {
+		deviations = result.Robust.Deviations
+	}
+
+	// As variáveis fixas não têm erro: não entram na covariância nem nas hipóteses de viés.
+	fixed := make(map[int]bool)
+	for i, class := range result.Classification {
+		if class == ClassFixed {
+			fixed[i] = true
+		}
+	}
+	// As restrições são as mesmas resolvidas por Reconcile: sem as linhas dependentes
+	// descartadas (WithDropRedundantConstraints) nem as que só envolvem variáveis fixas, que
+	// tornariam V_r singular. kept guarda o índice original de cada linha mantida.
+	o.dropped = make(map[int]bool)
+	if result.RankDiagnosis != nil && result.RankDiagnosis.Dropped {
+		for _, row := range result.RankDiagnosis.DependentRows {
+			o.dropped[row.Row] = true
+		}
+	}
+	if len(fixed) > 0 {
+		if _, _, o.dropped, err = fixVariables(measurements, constraints, fixed, o); err != nil {
+			return nil, err
+		}
+	}
+	numConstraints, _ := constraints.Dims()
+	var kept []int
+	for k := 0; k < numConstraints; k++ {
+		if !o.dropped[k] {
+			kept = append(kept, k)
+		}
+	}
+	keptConstraints, _ := withoutRows(constraints, nil, o.dropped)
+
+	pr := newProjection(keptConstraints, o.unmeasured, fixed)
+	if pr.reduced == nil {
+		return nil, fmt.Errorf("%w: o teste GLR precisa de restrições redundantes", ErrInvalidInput)
+	}
+	_, covariance, err := weightMatrix(o, deviations, pr.measured)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resíduos reduzidos r = P*(B_M*m + B_F*m_F - c), sem as variáveis não medidas.
+	raw := make([]float64, len(kept))
+	for k, row := range kept {
+		for j, b := range constraints.RawRowView(row) {
+			if b != 0 && !o.unmeasured[j] {
+				raw[k] += b * measurements[j]
+			}
+		}
+		if o.rhs != nil {
+			raw[k] -= o.rhs[row]
+		}
+	}
+	r := mat.NewVecDense(len(pr.project(raw)), pr.project(raw))
+
+	// V_r = (P*B_M)*V*(P*B_M)^T, fatorada por Cholesky.
+	numReduced, _ := pr.reduced.Dims()
+	var vb mat.Dense
+	vb.Mul(covariance, pr.reduced.T())
+	var vr mat.Dense
+	vr.Mul(pr.reduced, &vb)
+	var chol mat.Cholesky
+	if ok := chol.Factorize(symmetric(&vr)); !ok {
+		return nil, errors.New("a covariância dos resíduos é singular: há restrições dependentes")
+	}
+	var vrInvR mat.VecDense
+	if err := chol.SolveVecTo(&vrInvR, r); err != nil {
+		return nil, err
+	}
+
+	// Cada hipótese guarda a sua assinatura f e V_r^-1*f, para a comparação das assinaturas.
+	type candidate struct {
+		Hypothesis
+		signature, vrInvF *mat.VecDense
+	}
+	var candidates []candidate
+	evaluate := func(kind HypothesisKind, index int, signature *mat.VecDense) error {
+		if mat.Norm(signature, 2) <= signatureTolerance {
+			return nil
+		}
+		var vrInvF mat.VecDense
+		if err := chol.SolveVecTo(&vrInvF, signature); err != nil {
+			return err
+		}
+		information := mat.Dot(signature, &vrInvF)
+		projected := mat.Dot(signature, &vrInvR)
+		candidates = append(candidates, candidate{
+			Hypothesis: Hypothesis{
+				Kind:      kind,
+				Index:     index,
+				Statistic: projected * projected / information,
+				Magnitude: projected / information,
+				StdError:  1 / math.Sqrt(information),
+			},
+			signature: signature,
+			vrInvF:    &vrInvF,
+		})
+		return nil
+	}
+	for a, j := range pr.measured {
+		if err := evaluate(HypothesisBias, j, mat.VecDenseCopyOf(pr.reduced.ColView(a))); err != nil {
+			return nil, err
+		}
+	}
+	for k, row := range kept {
+		unit := make([]float64, len(kept))
+		unit[k] = 1
+		if err := evaluate(HypothesisLeak, row, mat.NewVecDense(numReduced, pr.project(unit))); err != nil {
+			return nil, err
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("nenhuma hipótese de erro grosseiro é detectável")
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].Statistic > candidates[b].Statistic
+	})
+	hypotheses := make([]Hypothesis, len(candidates))
+	for h, c := range candidates {
+		hypotheses[h] = c.Hypothesis
+	}
+
+	beta := 1 - math.Pow(o.confidence, 1/float64(len(hypotheses)))
+	glr := &GLRResult{
+		Confidence:    o.confidence,
+		CriticalValue: distuv.ChiSquared{K: 1}.Quantile(1 - beta),
+		Hypotheses:    hypotheses,
+		MostLikely:    &hypotheses[0],
+		Equivalent:    []Hypothesis{},
+	}
+	glr.Detected = glr.MostLikely.Statistic > glr.CriticalValue
+	// Duas assinaturas são colineares quando cos = f_a^T*V_r^-1*f_b / sqrt(I_a*I_b) tem módulo 1,
+	// com I = f^T*V_r^-1*f a informação de cada hipótese.
+	best := candidates[0]
+	bestInformation := mat.Dot(best.signature, best.vrInvF)
+	for _, c := range candidates[1:] {
+		cos := mat.Dot(c.signature, best.vrInvF) / math.Sqrt(bestInformation*mat.Dot(c.signature, c.vrInvF))
+		if 1-math.Abs(cos) <= collinearityTolerance {
+			glr.Equivalent = append(glr.Equivalent, c.Hypothesis)
+		}
+	}
+	if !glr.Detected {
+		return glr, nil
+	}
+
+	compensated := append([]float64(nil), measurements...)
+	compensatedOpts := append([]Option(nil), opts...)
+	switch glr.MostLikely.Kind {
+	case HypothesisBias:
+		compensated[glr.MostLikely.Index] -= glr.MostLikely.Magnitude
+	case HypothesisLeak:
+		rhs := make([]float64, numConstraints)
+		copy(rhs, o.rhs)
+		rhs[glr.MostLikely.Index] += glr.MostLikely.Magnitude
+		compensatedOpts = append(compensatedOpts, WithRHS(rhs))
+	}
+	if glr.Compensated, err = Reconcile(compensated, tolerances, constraints, compensatedOpts...); err != nil {
+		return nil, fmt.Errorf("falha na reconciliação compensada: %w", err)
+	}
+	return glr, nil
+}
@@ -0,0 +1,178 @@
+package reconciliation
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestGLRTest(t *testing.T) {
+	// Rede balanceada: x0 = 100, x1 = 60, x2 = x3 = 40, x4 = 100.
+	tolerances := []float64{1, 1, 1, 1, 1}
+	absolute := WithUncertaintyMode(UncertaintyAbsolute)
+
+	t.Run("Viés de Medição", func(t *testing.T) {
+		measurements := []float64{100, 65, 40, 40, 100}
+		result, err := GLRTest(measurements, tolerances, networkConstraints(), absolute)
+		if err != nil {
+			t.Fatalf("GLRTest retornou um erro inesperado: %v", err)
+		}
+		if !result.Detected {
+			t.Fatalf("O viés deveria ser detectado: %+v", result.MostLikely)
+		}
+		if result.MostLikely.Kind != HypothesisBias || result.MostLikely.Index != 1 {
+			t.Fatalf("A hipótese mais provável deveria ser o viés em x1, obtido %+v", result.MostLikely)
+		}
+		if math.Abs(result.MostLikely.Magnitude-5) > 1e-9 {
+			t.Errorf("Magnitude incorreta.\nEsperado: 5\nObtido:   %v", result.MostLikely.Magnitude)
+		}
+		if result.MostLikely.StdError <= 0 {
+			t.Errorf("O erro padrão da magnitude deveria ser positivo, obtido %v", result.MostLikely.StdError)
+		}
+		expected := []float64{100, 60, 40, 40, 100}
+		for i, x := range result.Compensated.Reconciled {
+			if math.Abs(x-expected[i]) > 1e-6 {
+				t.Errorf("Valor compensado incorreto para x%d.\nEsperado: %v\nObtido:   %v", i, expected[i], x)
+			}
+		}
+		for k := 1; k < len(result.Hypotheses); k++ {
+			if result.Hypotheses[k].Statistic > result.Hypotheses[k-1].Statistic {
+				t.Fatal("As hipóteses deveriam estar em ordem decrescente da estatística")
+			}
+		}
+	})
+
+	t.Run("Vazamento num Nó", func(t *testing.T) {
+		// 4 unidades deixam o nó 1 entre x2 e x3.
+		measurements := []float64{100, 60, 40, 36, 96}
+		result, err := GLRTest(measurements, tolerances, networkConstraints(), absolute)
+		if err != nil {
+			t.Fatalf("GLRTest retornou um erro inesperado: %v", err)
+		}
+		if !result.Detected || result.MostLikely.Kind != HypothesisLeak || result.MostLikely.Index != 1 {
+			t.Fatalf("A hipótese mais provável deveria ser o vazamento no nó 1, obtido %+v", result.MostLikely)
+		}
+		if math.Abs(result.MostLikely.Magnitude-4) > 1e-9 {
+			t.Errorf("Magnitude incorreta.\nEsperado: 4\nObtido:   %v", result.MostLikely.Magnitude)
+		}
+		// Com o vazamento compensado, as medições já fecham os balanços.
+		for i, x := range result.Compensated.Reconciled {
+			if math.Abs(x-measurements[i]) > 1e-6 {
+				t.Errorf("Valor compensado incorreto para x%d.\nEsperado: %v\nObtido:   %v", i, measurements[i], x)
+			}
+		}
+	})
+
+	t.Run("Hipóteses Equivalentes", func(t *testing.T) {
+		// x0 só aparece no nó 0: o seu viés e um vazamento no nó 0 têm a mesma assinatura.
+		measurements := []float64{108, 60, 40, 40, 100}
+		result, err := GLRTest(measurements, tolerances, networkConstraints(), absolute)
+		if err != nil {
+			t.Fatalf("GLRTest retornou um erro inesperado: %v", err)
+		}
+		if len(result.Equivalent) != 1 || result.Equivalent[0].Index != 0 || result.Equivalent[0].Kind == result.MostLikely.Kind {
+			t.Fatalf("O viés em x0 e o vazamento no nó 0 deveriam ser equivalentes: %+v, %+v", result.MostLikely, result.Equivalent)
+		}
+		// O viés é positivo; o vazamento equivalente é uma entrada, de magnitude negativa.
+		for _, h := range append(result.Equivalent, *result.MostLikely) {
+			if math.Abs(math.Abs(h.Magnitude)-8) > 1e-9 {
+				t.Errorf("Magnitude incorreta para %+v", h)
+			}
+		}
+	})
+
+	t.Run("Estatísticas Iguais com Assinaturas Diferentes", func(t *testing.T) {
+		// Dois divisores independentes com o mesmo desbalanço: todas as hipóteses têm a mesma
+		// estatística, mas só as do mesmo nó têm assinaturas colineares.
+		splitters := mat.NewDense(2, 6, []float64{1, -1, -1, 0, 0, 0, 0, 0, 0, 1, -1, -1})
+		result, err := GLRTest([]float64{100, 50, 40, 100, 50, 40}, []float64{1, 1, 1, 1, 1, 1}, splitters, absolute)
+		if err != nil {
+			t.Fatalf("GLRTest retornou um erro inesperado: %v", err)
+		}
+		node := func(h Hypothesis) int {
+			if h.Kind == HypothesisLeak {
+				return h.Index
+			}
+			return h.Index / 3
+		}
+		for _, h := range result.Hypotheses {
+			if math.Abs(h.Statistic-result.MostLikely.Statistic) > 1e-9 {
+				t.Fatalf("As estatísticas deveriam ser iguais: %+v", result.Hypotheses)
+			}
+		}
+		if len(result.Equivalent) != 3 {
+			t.Fatalf("Apenas as hipóteses do mesmo nó deveriam ser equivalentes: %+v, %+v", result.MostLikely, result.Equivalent)
+		}
+		for _, h := range result.Equivalent {
+			if node(h) != node(*result.MostLikely) {
+				t.Errorf("A hipótese %+v não é equivalente a %+v", h, result.MostLikely)
+			}
+		}
+	})
+
+	t.Run("Sem Erro Grosseiro", func(t *testing.T) {
+		measurements := []float64{100.5, 60, 40.3, 40, 99.8}
+		result, err := GLRTest(measurements, tolerances, networkConstraints(), absolute)
+		if err != nil {
+			t.Fatalf("GLRTest retornou um erro inesperado: %v", err)
+		}
+		if result.Detected || result.Compensated != nil {
+			t.Errorf("Nenhum erro deveria ser detectado: %+v", result.MostLikely)
+		}
+		if result.CriticalValue <= 3.84 {
+			t.Errorf("O valor crítico deveria incluir a correção de Šidák, obtido %v", result.CriticalValue)
+		}
+	})
+
+	t.Run("Variáveis Não Medidas", func(t *testing.T) {
+		// Com x2 não medida, os nós 0 e 1 se combinam no balanço x0 = x1 + x3, e x2 não tem
+		// hipótese de viés.
+		measurements := []float64{100, 65, math.NaN(), 40, 100}
+		result, err := GLRTest(measurements, tolerances, networkConstraints(), absolute, WithUnmeasured([]int{2}))
+		if err != nil {
+			t.Fatalf("GLRTest retornou um erro inesperado: %v", err)
+		}
+		for _, h := range result.Hypotheses {
+			if h.Kind == HypothesisBias && h.Index == 2 {
+				t.Errorf("Uma variável não medida não pode ter viés: %+v", h)
+			}
+		}
+		if !result.Detected || math.Abs(math.Abs(result.MostLikely.Magnitude)-5) > 1e-9 {
+			t.Errorf("O erro de 5 unidades deveria ser detectado, obtido %+v", result.MostLikely)
+		}
+	})
+
+	t.Run("Restrições Dependentes", func(t *testing.T) {
+		// O balanço da planta (linha 2) é a soma dos balanços das unidades A e B.
+		constraints := mat.NewDense(3, 4, []float64{
+			1, -1, -1, 0,
+			0, 0, 1, -1,
+			1, -1, 0, -1,
+		})
+		measurements := []float64{100, 65, 40, 40}
+		result, err := GLRTest(measurements, tolerances[:4], constraints, absolute, WithDropRedundantConstraints())
+		if err != nil {
+			t.Fatalf("GLRTest retornou um erro inesperado: %v", err)
+		}
+		if !result.Detected || math.Abs(math.Abs(result.MostLikely.Magnitude)-5) > 1e-9 {
+			t.Errorf("O erro de 5 unidades deveria ser detectado, obtido %+v", result.MostLikely)
+		}
+		for _, h := range result.Hypotheses {
+			if h.Kind == HypothesisLeak && h.Index == 2 {
+				t.Errorf("A linha descartada não deveria ter hipótese de vazamento: %+v", h)
+			}
+		}
+		if result.Compensated == nil || result.Compensated.RankDiagnosis == nil {
+			t.Fatalf("A reconciliação compensada também deveria descartar a linha dependente: %+v", result.Compensated)
+		}
+	})
+
+	t.Run("Sem Redundância", func(t *testing.T) {
+		// Com x1, x2 e x4 não medidas, os três balanços apenas as determinam.
+		measurements := []float64{100, math.NaN(), math.NaN(), 40, math.NaN()}
+		if _, err := GLRTest(measurements, tolerances, networkConstraints(), absolute, WithUnmeasured([]int{1, 2, 4})); err == nil {
+			t.Error("Esperado um erro sem restrições redundantes")
+		}
+	})
+}
@@ -0,0 +1,249 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// GlobalTestResult é o resultado do teste global (qui-quadrado) para detecção de erros grosseiros.
+//
+// Na ausência de erros grosseiros, a função objetivo (x - m)^T * W * (x - m) segue uma
+// distribuição qui-quadrado com tantos graus de liberdade quanto o número de restrições
+// independentes. Um valor acima do valor crítico indica que as medições contêm ao menos
+// um erro grosseiro (ex: medidor com falha ou vazamento).
+type GlobalTestResult struct {
+	// Statistic é o valor da estatística do teste (a função objetivo).
+	Statistic float64 `json:"statistic"`
+	// DegreesOfFreedom é o número de graus de liberdade da distribuição qui-quadrado.
+	DegreesOfFreedom int `json:"degreesOfFreedom"`
+	// Confidence é o nível de confiança usado no teste.
+	Confidence float64 `json:"confidence"`
+	// CriticalValue é o quantil da distribuição qui-quadrado no nível de confiança.
+	CriticalValue float64 `json:"criticalValue"`
+	// PValue é a probabilidade de observar uma estatística ao menos tão alta sem erros grosseiros.
+	PValue float64 `json:"pValue"`
+	// Passed indica se as medições passaram no teste, ou seja, se nenhum erro grosseiro foi detectado.
+	Passed bool `json:"passed"`
+}
+
+// GlobalTest compara a estatística (normalmente a função objetivo da reconciliação) com o
+// valor crítico da distribuição qui-quadrado com degreesOfFreedom graus de liberdade.
+func GlobalTest(statistic float64, degreesOfFreedom int, confidence float64) (*GlobalTestResult, error) {
+	if degreesOfFreedom <= 0 {
+		return nil, fmt.Errorf("%w: o número de graus de liberdade deve ser positivo, obtido %d", ErrInvalidInput, degreesOfFreedom)
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return nil, fmt.Errorf("%w: o nível de confiança deve estar entre 0 e 1, obtido %v", ErrInvalidInput, confidence)
+	}
+
+	chi2 := distuv.ChiSquared{K: float64(degreesOfFreedom)}
+	criticalValue := chi2.Quantile(confidence)
+	return &GlobalTestResult{
+		Statistic:        statistic,
+		DegreesOfFreedom: degreesOfFreedom,
+		Confidence:       confidence,
+		CriticalValue:    criticalValue,
+		PValue:           chi2.Survival(statistic),
+		Passed:           statistic <= criticalValue,
+	}, nil
+}
+
+// MeasurementTestResult é o resultado do teste das medidas.
+//
+// Na ausência de erros grosseiros, cada ajuste padronizado segue uma distribuição normal
+// padrão. Uma medição cujo ajuste padronizado excede o valor crítico é considerada suspeita.
+type MeasurementTestResult struct {
+	// Confidence é o nível de confiança global usado no teste.
+	Confidence float64 `json:"confidence"`
+	// CriticalValue é o valor crítico da distribuição normal padrão, já com a correção de Šidák.
+	CriticalValue float64 `json:"criticalValue"`
+	// Tested é o número de medições testadas, usado na correção de Šidák.
+	Tested int `json:"tested"`
+	// Flags indica, para cada medição, se o módulo do seu ajuste padronizado excede o valor crítico.
+	Flags []bool `json:"flags"`
+}
+
+// MeasurementTest compara o módulo de cada ajuste padronizado com o valor crítico bilateral
+// da distribuição normal padrão.
+//
+// tested indica quais ajustes são testados; nulo testa todos. As medições não redundantes, as
+// fixas e as variáveis não medidas têm ajuste padronizado sempre zero e ficam de fora, sem
+// tornar o teste das demais mais rigoroso. Como as n medições testadas são avaliadas
+// simultaneamente, o nível de significância de cada teste individual é corrigido pela fórmula
+// de Šidák, β = 1 - confidence^(1/n), para que a probabilidade de um falso alarme em qualquer
+// medição seja 1 - confidence. Sem medições testadas, o valor crítico é o de um único teste.
+func MeasurementTest(standardized []float64, tested []bool, confidence float64) (*MeasurementTestResult, error) {
+	if len(standardized) == 0 {
+		return nil, fmt.Errorf("%w: o slice de ajustes padronizados não pode estar vazio", ErrInvalidInput)
+	}
+	if tested != nil && len(tested) != len(standardized) {
+		return nil, fmt.Errorf("%w: ajustes padronizados (%d) e medições testadas (%d)", ErrDimensionMismatch, len(standardized), len(tested))
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return nil, fmt.Errorf("%w: o nível de confiança deve estar entre 0 e 1, obtido %v", ErrInvalidInput, confidence)
+	}
+
+	numTested := len(standardized)
+	if tested != nil {
+		numTested = 0
+		for _, t := range tested {
+			if t {
+				numTested++
+			}
+		}
+	}
+	beta := 1 - math.Pow(confidence, 1/float64(max(numTested, 1)))
+	criticalValue := distuv.UnitNormal.Quantile(1 - beta/2)
+	flags := make([]bool, len(standardized))
+	for i, z := range standardized {
+		flags[i] = (tested == nil || tested[i]) && math.Abs(z) > criticalValue
+	}
+	return &MeasurementTestResult{
+		Confidence:    confidence,
+		CriticalValue: criticalValue,
+		Tested:        numTested,
+		Flags:         flags,
+	}, nil
+}
+
+// Suspect descreve uma medição identificada como portadora de erro grosseiro.
+type Suspect struct {
+	// Index é o índice da medição (tag) suspeita.
+	Index int `json:"index"`
+	// StandardizedAdjustment é o ajuste padronizado da medição no momento da sua eliminação.
+	StandardizedAdjustment float64 `json:"standardizedAdjustment"`
+	// Bias é a magnitude estimada do erro grosseiro: a medição menos a estimativa obtida
+	// pelas restrições quando a medição é tratada como não medida.
+	Bias float64 `json:"bias"`
+}
+
+// EliminationResult é o resultado da eliminação serial de medições suspeitas.
+type EliminationResult struct {
+	// Suspects são as medições eliminadas, na ordem em que foram eliminadas.
+	Suspects []Suspect `json:"suspects"`
+	// Result é a reconciliação final, com as medições suspeitas tratadas como não medidas.
+	Result *Result `json:"result"`
+}
+
+// SerialElimination identifica as medições com erros grosseiros por eliminação serial.
+//
+// A cada iteração, se o teste global falhar, a medição com o maior ajuste padronizado acima
+// do valor crítico do teste das medidas é eliminada (passa a ser tratada como não medida) e
+// o problema é resolvido novamente. O processo termina quando o teste global passa, quando
+// nenhuma medição excede o valor crítico ou quando eliminar outra medição acabaria com a
+// redundância ou tornaria alguma variável indeterminada.
+//
+// Os parâmetros são os mesmos de Reconcile.
+func SerialElimination(measurements, tolerances []float64, constraints *mat.Dense, opts ...Option) (*EliminationResult, error) {
+	result, err := Reconcile(measurements, tolerances, constraints, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var eliminated []int
+	suspects := []Suspect{}
+	for result.GlobalTest != nil && !result.GlobalTest.Passed {
+		// Ordena os candidatos pelo módulo do ajuste padronizado, do maior para o menor.
+		var candidates []int
+		for i, flagged := range result.MeasurementTest.Flags {
+			if flagged {
+				candidates = append(candidates, i)
+			}
+		}
+		sort.Slice(candidates, func(a, b int) bool {
+			return math.Abs(result.StandardizedAdjustments[candidates[a]]) > math.Abs(result.StandardizedAdjustments[candidates[b]])
+		})
+
+		// Elimina o primeiro candidato cuja remoção mantém o problema solúvel e redundante.
+		var next *Result
+		for _, candidate := range candidates {
+			trial := append(append([]int(nil), eliminated...), candidate)
+			trialOpts := append(append([]Option(nil), opts...), WithUnmeasured(trial))
+			r, err := Reconcile(measurements, tolerances, constraints, trialOpts...)
+			if err != nil || r.GlobalTest == nil || r.Classification[candidate] != ClassObservable {
+				continue
+			}
+			suspects = append(suspects, Suspect{
+				Index:                  candidate,
+				StandardizedAdjustment: result.StandardizedAdjustments[candidate],
+				Bias:                   measurements[candidate] - r.Reconciled[candidate],
+			})
+			eliminated, next = trial, r
+			break
+		}
+		if next == nil {
+			break
+		}
+		result = next
+	}
+
+	return &EliminationResult{Suspects: suspects, Result: result}, nil
+}
+
+// NodalTestResult é o resultado do teste nodal (teste das restrições).
+//
+// Na ausência de erros grosseiros, o resíduo r_k = (B*m - c)_k de cada restrição segue uma
+// distribuição normal com média zero e variância (B*V*B^T)_kk. Uma restrição cujo resíduo
+// padronizado excede o valor crítico indica um desbalanço significativo na unidade (nó)
+// que ela representa.
+type NodalTestResult struct {
+	// Confidence é o nível de confiança global usado no teste.
+	Confidence float64 `json:"confidence"`
+	// CriticalValue é o valor crítico da distribuição normal padrão, já com a correção de Šidák.
+	CriticalValue float64 `json:"criticalValue"`
+	// Tested é o número de restrições testadas (com variância positiva), usado na correção de
+	// Šidák.
+	Tested int `json:"tested"`
+	// Residuals são os resíduos das restrições avaliados nas medições.
+	Residuals Values `json:"residuals"`
+	// ZScores são os resíduos padronizados, r_k / sqrt((B*V*B^T)_kk).
+	ZScores []float64 `json:"zScores"`
+	// Flags indica, para cada restrição, se o seu desbalanço é estatisticamente significativo.
+	Flags []bool `json:"flags"`
+}
+
+// NodalTest padroniza o resíduo de cada restrição pela sua variância e o compara com o valor
+// crítico bilateral da distribuição normal padrão, com a correção de Šidák para o número de
+// restrições testadas. Restrições com variância nula (ex: que envolvem variáveis não medidas)
+// não são testadas nem contadas na correção e recebem z-score zero.
+func NodalTest(residuals Values, variances []float64, confidence float64) (*NodalTestResult, error) {
+	if len(residuals) == 0 {
+		return nil, fmt.Errorf("%w: o slice de resíduos não pode estar vazio", ErrInvalidInput)
+	}
+	if len(variances) != len(residuals) {
+		return nil, fmt.Errorf("%w: resíduos (%d) e variâncias (%d)", ErrDimensionMismatch, len(residuals), len(variances))
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return nil, fmt.Errorf("%w: o nível de confiança deve estar entre 0 e 1, obtido %v", ErrInvalidInput, confidence)
+	}
+
+	numTested := 0
+	for _, v := range variances {
+		if v > 0 {
+			numTested++
+		}
+	}
+	beta := 1 - math.Pow(confidence, 1/float64(max(numTested, 1)))
+	criticalValue := distuv.UnitNormal.Quantile(1 - beta/2)
+	zScores := make([]float64, len(residuals))
+	flags := make([]bool, len(residuals))
+	for k, r := range residuals {
+		if variances[k] <= 0 {
+			continue
+		}
+		zScores[k] = r / math.Sqrt(variances[k])
+		flags[k] = math.Abs(zScores[k]) > criticalValue
+	}
+	return &NodalTestResult{
+		Confidence:    confidence,
+		CriticalValue: criticalValue,
+		Tested:        numTested,
+		Residuals:     residuals,
+		ZScores:       zScores,
+		Flags:         flags,
+	}, nil
+}
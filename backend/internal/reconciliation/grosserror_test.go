@@ -0,0 +1,247 @@
+package reconciliation
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestGlobalTest(t *testing.T) {
+	t.Run("Valor Crítico Tabelado", func(t *testing.T) {
+		// Quantis tabelados da distribuição qui-quadrado.
+		cases := []struct {
+			dof        int
+			confidence float64
+			expected   float64
+		}{
+			{1, 0.95, 3.8415},
+			{2, 0.95, 5.9915},
+			{3, 0.99, 11.3449},
+		}
+		for _, c := range cases {
+			result, err := GlobalTest(0, c.dof, c.confidence)
+			if err != nil {
+				t.Fatalf("GlobalTest retornou um erro inesperado: %v", err)
+			}
+			if math.Abs(result.CriticalValue-c.expected) > 1e-4 {
+				t.Errorf("Valor crítico incorreto para %d graus de liberdade a %v.\nEsperado: %v\nObtido:   %v", c.dof, c.confidence, c.expected, result.CriticalValue)
+			}
+		}
+	})
+
+	t.Run("Exemplo 2 Sem Erro Grosseiro", func(t *testing.T) {
+		result, err := Reconcile([]float64{161, 79, 80}, []float64{0.05, 0.01, 0.01}, mat.NewDense(1, 3, []float64{1, -1, -1}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !result.GlobalTest.Passed {
+			t.Errorf("O teste global deveria passar: %+v", result.GlobalTest)
+		}
+		if result.GlobalTest.DegreesOfFreedom != 1 || result.GlobalTest.Confidence != DefaultConfidence {
+			t.Errorf("Parâmetros do teste global incorretos: %+v", result.GlobalTest)
+		}
+		if result.GlobalTest.PValue <= 0.05 || result.GlobalTest.PValue > 1 {
+			t.Errorf("p-valor incorreto: %v", result.GlobalTest.PValue)
+		}
+	})
+
+	t.Run("Erro Grosseiro Detectado", func(t *testing.T) {
+		// A corrente de entrada está 20% acima da soma das saídas, muito além das tolerâncias.
+		result, err := Reconcile([]float64{200, 79, 80}, []float64{0.01, 0.01, 0.01}, mat.NewDense(1, 3, []float64{1, -1, -1}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if result.GlobalTest.Passed {
+			t.Errorf("O teste global deveria falhar: %+v", result.GlobalTest)
+		}
+		if result.GlobalTest.PValue >= 0.05 {
+			t.Errorf("O p-valor deveria ser menor que 0.05: %v", result.GlobalTest.PValue)
+		}
+	})
+
+	t.Run("Nível de Confiança Inválido", func(t *testing.T) {
+		_, err := Reconcile([]float64{161, 79, 80}, []float64{0.05, 0.01, 0.01}, mat.NewDense(1, 3, []float64{1, -1, -1}), WithConfidence(1.5))
+		if err == nil {
+			t.Error("Esperava-se um erro para um nível de confiança inválido, mas nenhum foi retornado")
+		}
+	})
+}
+
+// networkConstraints descreve uma rede com um divisor, um trocador e um misturador:
+// x1 = x2 + x3, x3 = x4 e x2 + x4 = x5. Os valores verdadeiros são (100, 60, 40, 40, 100).
+func networkConstraints() *mat.Dense {
+	return mat.NewDense(3, 5, []float64{
+		1, -1, -1, 0, 0,
+		0, 0, 1, -1, 0,
+		0, 1, 0, 1, -1,
+	})
+}
+
+func TestMeasurementTest(t *testing.T) {
+	t.Run("Valor Crítico com Correção de Šidák", func(t *testing.T) {
+		// Com uma única medição, o valor crítico é o quantil bilateral usual de 95%.
+		result, err := MeasurementTest([]float64{2}, nil, 0.95)
+		if err != nil {
+			t.Fatalf("MeasurementTest retornou um erro inesperado: %v", err)
+		}
+		if math.Abs(result.CriticalValue-1.96) > 1e-3 {
+			t.Errorf("Valor crítico incorreto.\nEsperado: 1.96\nObtido:   %v", result.CriticalValue)
+		}
+		if !result.Flags[0] {
+			t.Error("A medição deveria ser marcada como suspeita")
+		}
+
+		// Com mais medições, o valor crítico aumenta.
+		result, err = MeasurementTest([]float64{2, 0, 0, 0, 0}, nil, 0.95)
+		if err != nil {
+			t.Fatalf("MeasurementTest retornou um erro inesperado: %v", err)
+		}
+		if result.CriticalValue <= 1.96 || result.Flags[0] {
+			t.Errorf("A correção de Šidák não foi aplicada: %+v", result)
+		}
+
+		// As medições não testadas não entram na correção nem são marcadas.
+		result, err = MeasurementTest([]float64{2, 0, 0, 0, 0}, []bool{true, false, false, false, false}, 0.95)
+		if err != nil {
+			t.Fatalf("MeasurementTest retornou um erro inesperado: %v", err)
+		}
+		if math.Abs(result.CriticalValue-1.96) > 1e-3 || result.Tested != 1 || !result.Flags[0] {
+			t.Errorf("Apenas a medição testada deveria contar na correção: %+v", result)
+		}
+		if _, err := MeasurementTest([]float64{2, 0}, []bool{true}, 0.95); err == nil {
+			t.Error("Esperado um erro para uma máscara incompatível")
+		}
+	})
+
+	t.Run("Medição com Erro Grosseiro", func(t *testing.T) {
+		// A medição x3 tem um erro grosseiro de +15.
+		measurements := []float64{100, 60, 55, 40, 100}
+		tolerances := []float64{0.01, 0.01, 0.01, 0.01, 0.01}
+		result, err := Reconcile(measurements, tolerances, networkConstraints())
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !result.MeasurementTest.Flags[2] {
+			t.Errorf("A medição 2 deveria ser marcada como suspeita: %v", result.StandardizedAdjustments)
+		}
+	})
+
+	t.Run("Apenas Medições Redundantes", func(t *testing.T) {
+		// x1 não é medida e x3 é fixa: a correção de Šidák conta só as medições redundantes.
+		measurements := []float64{100, math.NaN(), 55, 40, 100}
+		tolerances := []float64{0.01, 0.01, 0.01, 0, 0.01}
+		result, err := Reconcile(measurements, tolerances, networkConstraints(), WithUnmeasured([]int{1}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		redundant := 0
+		for _, class := range result.Classification {
+			if class == ClassRedundant {
+				redundant++
+			}
+		}
+		expected, err := MeasurementTest(make([]float64, redundant), nil, 0.95)
+		if err != nil {
+			t.Fatalf("MeasurementTest retornou um erro inesperado: %v", err)
+		}
+		if redundant == 0 || redundant >= 5 || result.MeasurementTest.Tested != redundant || result.MeasurementTest.CriticalValue != expected.CriticalValue {
+			t.Errorf("Correção de Šidák incorreta: %d redundantes, %+v", redundant, result.MeasurementTest)
+		}
+	})
+}
+
+func TestSerialElimination(t *testing.T) {
+	t.Run("Um Erro Grosseiro", func(t *testing.T) {
+		measurements := []float64{100, 60, 55, 40, 100}
+		tolerances := []float64{0.01, 0.01, 0.01, 0.01, 0.01}
+		result, err := SerialElimination(measurements, tolerances, networkConstraints())
+		if err != nil {
+			t.Fatalf("SerialElimination retornou um erro inesperado: %v", err)
+		}
+		if len(result.Suspects) != 1 || result.Suspects[0].Index != 2 {
+			t.Fatalf("Esperava-se apenas a medição 2 como suspeita, obtido %+v", result.Suspects)
+		}
+		if math.Abs(result.Suspects[0].Bias-15) > 1 {
+			t.Errorf("Magnitude do erro incorreta.\nEsperado: ~15\nObtido:   %v", result.Suspects[0].Bias)
+		}
+		if !result.Result.GlobalTest.Passed {
+			t.Errorf("O teste global deveria passar após a eliminação: %+v", result.Result.GlobalTest)
+		}
+		if math.Abs(result.Result.Reconciled[2]-40) > 1 {
+			t.Errorf("A estimativa da medição eliminada deveria ser ~40, obtido %v", result.Result.Reconciled[2])
+		}
+	})
+
+	t.Run("Sem Erro Grosseiro", func(t *testing.T) {
+		measurements := []float64{100.5, 59.8, 40.1, 39.9, 100.2}
+		tolerances := []float64{0.01, 0.01, 0.01, 0.01, 0.01}
+		result, err := SerialElimination(measurements, tolerances, networkConstraints())
+		if err != nil {
+			t.Fatalf("SerialElimination retornou um erro inesperado: %v", err)
+		}
+		if len(result.Suspects) != 0 {
+			t.Errorf("Nenhuma medição deveria ser suspeita, obtido %+v", result.Suspects)
+		}
+	})
+}
+
+func TestNodalTest(t *testing.T) {
+	t.Run("Exemplo 2 do Documento", func(t *testing.T) {
+		result, err := Reconcile([]float64{161, 79, 80}, []float64{0.05, 0.01, 0.01}, mat.NewDense(1, 3, []float64{1, -1, -1}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		// Com uma única restrição, o z-score nodal ao quadrado é igual à função objetivo.
+		z := result.NodalTest.ZScores[0]
+		if math.Abs(z*z-result.Objective) > 1e-9 {
+			t.Errorf("z-score nodal incorreto.\nEsperado: %v\nObtido:   %v", math.Sqrt(result.Objective), z)
+		}
+		if result.NodalTest.Flags[0] {
+			t.Error("A restrição não deveria ser marcada como desbalanceada")
+		}
+	})
+
+	t.Run("Nós Desbalanceados", func(t *testing.T) {
+		// O erro grosseiro em x3 desbalanceia apenas os nós em que x3 aparece (restrições 0 e 1).
+		measurements := []float64{100, 60, 55, 40, 100}
+		tolerances := []float64{0.01, 0.01, 0.01, 0.01, 0.01}
+		result, err := Reconcile(measurements, tolerances, networkConstraints())
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		expected := []bool{true, true, false}
+		for k, flag := range result.NodalTest.Flags {
+			if flag != expected[k] {
+				t.Errorf("Flag da restrição %d incorreta: esperado %v, obtido %v (z = %v)", k, expected[k], flag, result.NodalTest.ZScores[k])
+			}
+		}
+		if !equal(result.NodalTest.Residuals, []float64{-15, 15, 0}, 1e-9) {
+			t.Errorf("Resíduos nodais incorretos: %v", result.NodalTest.Residuals)
+		}
+	})
+
+	t.Run("Restrições Não Testadas", func(t *testing.T) {
+		// A restrição de variância nula não entra na correção de Šidák.
+		result, err := NodalTest([]float64{1, 2, 3}, []float64{1, 0, 4}, 0.95)
+		if err != nil {
+			t.Fatalf("A função NodalTest retornou um erro inesperado: %v", err)
+		}
+		expected, err := NodalTest([]float64{1, 3}, []float64{1, 4}, 0.95)
+		if err != nil {
+			t.Fatalf("A função NodalTest retornou um erro inesperado: %v", err)
+		}
+		if result.Tested != 2 || math.Abs(result.CriticalValue-expected.CriticalValue) > 1e-12 {
+			t.Errorf("Correção de Šidák incorreta: %d testadas, valor crítico %v, esperado %v", result.Tested, result.CriticalValue, expected.CriticalValue)
+		}
+		if result.ZScores[1] != 0 || result.Flags[1] {
+			t.Errorf("A restrição de variância nula não deveria ser testada: %v", result.ZScores)
+		}
+	})
+
+	t.Run("Dimensões Incompatíveis", func(t *testing.T) {
+		if _, err := NodalTest([]float64{1, 2}, []float64{1}, 0.95); err == nil {
+			t.Error("Esperava-se um erro de incompatibilidade de dimensão, mas nenhum foi retornado")
+		}
+	})
+}
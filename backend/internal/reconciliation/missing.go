@@ -0,0 +1,83 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MissingPolicy define o tratamento das medições ausentes ou inválidas (NaN ou ±Inf), comuns
+// nas lacunas das exportações de historiadores de processo.
+type MissingPolicy string
+
+const (
+	// MissingReject rejeita a reconciliação com um *MissingMeasurementError que lista os índices
+	// das medições inválidas. É o padrão.
+	MissingReject MissingPolicy = "reject"
+	// MissingUnmeasured trata as variáveis com medições inválidas como não medidas: elas são
+	// eliminadas das restrições e estimadas quando observáveis (ver WithUnmeasured).
+	MissingUnmeasured MissingPolicy = "unmeasured"
+)
+
+// MissingMeasurementError é o erro retornado pela política MissingReject quando há medições
+// ausentes ou inválidas em variáveis que não foram marcadas como não medidas.
+type MissingMeasurementError struct {
+	// Indices são os índices das medições inválidas, em ordem crescente.
+	Indices []int
+	// Values são os valores inválidos (NaN, +Inf ou -Inf), na ordem de Indices.
+	Values []float64
+}
+
+func (e *MissingMeasurementError) Error() string {
+	var b strings.Builder
+	b.WriteString("medições ausentes ou inválidas")
+	for k, i := range e.Indices {
+		sep := ", "
+		if k == 0 {
+			sep = ": "
+		}
+		fmt.Fprintf(&b, "%sa medição %d é %v", sep, i, e.Values[k])
+	}
+	b.WriteString("; marque-as como não medidas ou use a política MissingUnmeasured")
+	return b.String()
+}
+
+// validateMissingPolicy verifica se a política de medições ausentes é conhecida.
+func validateMissingPolicy(p MissingPolicy) error {
+	switch p {
+	case MissingReject, MissingUnmeasured:
+		return nil
+	}
+	return fmt.Errorf("%w: política de medições ausentes desconhecida: %q", ErrInvalidInput, p)
+}
+
+// resolveMissing aplica a política de medições ausentes: as medições NaN ou ±Inf das variáveis
+// que não foram marcadas como não medidas são rejeitadas ou passam a ser não medidas.
+func resolveMissing(measurements []float64, o *options) error {
+	var invalid *MissingMeasurementError
+	for i, m := range measurements {
+		if o.unmeasured[i] || (!math.IsNaN(m) && !math.IsInf(m, 0)) {
+			continue
+		}
+		if invalid == nil {
+			invalid = &MissingMeasurementError{}
+		}
+		invalid.Indices = append(invalid.Indices, i)
+		invalid.Values = append(invalid.Values, m)
+	}
+	if invalid == nil {
+		return nil
+	}
+	if o.missing == MissingReject {
+		return invalid
+	}
+	unmeasured := make(map[int]bool, len(o.unmeasured)+len(invalid.Indices))
+	for i := range o.unmeasured {
+		unmeasured[i] = true
+	}
+	for _, i := range invalid.Indices {
+		unmeasured[i] = true
+	}
+	o.unmeasured = unmeasured
+	return nil
+}
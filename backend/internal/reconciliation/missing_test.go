@@ -0,0 +1,66 @@
+package reconciliation
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestMissingMeasurements(t *testing.T) {
+	tolerances := []float64{1, 1, 1, 1, 1}
+	absolute := WithUncertaintyMode(UncertaintyAbsolute)
+
+	t.Run("Rejeição com os Índices", func(t *testing.T) {
+		measurements := []float64{100, math.NaN(), 40, math.Inf(1), 100}
+		_, err := Reconcile(measurements, tolerances, networkConstraints(), absolute)
+		var missing *MissingMeasurementError
+		if !errors.As(err, &missing) {
+			t.Fatalf("Esperado um *MissingMeasurementError, obtido %v", err)
+		}
+		if !slices.Equal(missing.Indices, []int{1, 3}) || !math.IsNaN(missing.Values[0]) || !math.IsInf(missing.Values[1], 1) {
+			t.Errorf("Índices ou valores incorretos: %+v", missing)
+		}
+
+		// Uma variável marcada como não medida pode ter medição NaN.
+		if _, err := Reconcile(measurements, tolerances, networkConstraints(), absolute, WithUnmeasured([]int{1, 3})); err != nil {
+			t.Errorf("Reconcile retornou um erro inesperado: %v", err)
+		}
+	})
+
+	t.Run("Tratadas como Não Medidas", func(t *testing.T) {
+		measurements := []float64{100, math.NaN(), 40, 40, math.Inf(-1)}
+		result, err := Reconcile(measurements, tolerances, networkConstraints(), absolute, WithMissing(MissingUnmeasured))
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		expected, err := Reconcile(measurements, tolerances, networkConstraints(), absolute, WithUnmeasured([]int{1, 4}))
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, expected.Reconciled, 1e-12) {
+			t.Errorf("Resultado incorreto.\nEsperado: %v\nObtido:   %v", expected.Reconciled, result.Reconciled)
+		}
+		if result.Classification[1] != ClassObservable || result.Classification[4] != ClassObservable {
+			t.Errorf("As variáveis ausentes deveriam ser estimadas: %v", result.Classification)
+		}
+	})
+
+	t.Run("Reconciliação Não Linear", func(t *testing.T) {
+		constraints := []Constraint{LinearPolynomial([]float64{1, -1, -1}, 0).Constraint()}
+		var missing *MissingMeasurementError
+		if _, err := ReconcileNonlinear([]float64{161, math.NaN(), 80}, []float64{0.05, 0.01, 0.01}, constraints); !errors.As(err, &missing) {
+			t.Errorf("Esperado um *MissingMeasurementError, obtido %v", err)
+		}
+	})
+
+	t.Run("Entradas Inválidas", func(t *testing.T) {
+		measurements := []float64{100, 60, 40, 40, 100}
+		if _, err := Reconcile(measurements, tolerances, networkConstraints(), WithMissing("ignore")); err == nil {
+			t.Error("Esperado um erro para uma política desconhecida")
+		}
+		if _, err := Reconcile(measurements, []float64{1, math.NaN(), 1, 1, 1}, networkConstraints(), absolute); err == nil {
+			t.Error("Esperado um erro para uma tolerância NaN")
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package reconciliation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// MonteCarloPercentiles são os níveis (entre 0 e 1) dos percentis informados pelo Monte Carlo.
+var MonteCarloPercentiles = []float64{0.025, 0.05, 0.25, 0.5, 0.75, 0.95, 0.975}
+
+// Percentile é um percentil da distribuição empírica de uma variável.
+type Percentile struct {
+	// Level é o nível do percentil, entre 0 e 1 (ex: 0.975).
+	Level float64 `json:"level"`
+	// Value é o valor abaixo do qual fica a fração Level das amostras.
+	Value float64 `json:"value"`
+}
+
+// Histogram é o histograma das amostras de uma variável, com classes de mesma largura.
+type Histogram struct {
+	// Edges são os limites das classes, um a mais que o número de classes.
+	Edges []float64 `json:"edges"`
+	// Counts é o número de amostras em cada classe. A última classe inclui o limite superior.
+	Counts []int `json:"counts"`
+}
+
+// Distribution é a distribuição empírica de uma variável reconciliada.
+type Distribution struct {
+	// Mean e StdDev são a média e o desvio padrão amostrais.
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+	// Percentiles são os percentis nos níveis de MonteCarloPercentiles.
+	Percentiles []Percentile `json:"percentiles"`
+	// Histogram é o histograma das amostras.
+	Histogram Histogram `json:"histogram"`
+}
+
+// MonteCarloResult é o resultado da propagação de incerteza por Monte Carlo.
+type MonteCarloResult struct {
+	// Samples é o número de conjuntos de medições sorteados.
+	Samples int `json:"samples"`
+	// Failed é o número de amostras em que a reconciliação falhou (ex: limites inviáveis ou
+	// falta de convergência); elas ficam fora das distribuições.
+	Failed int `json:"failed"`
+	// Seed é a semente usada, que reproduz o resultado.
+	Seed uint64 `json:"seed"`
+	// Variables são as distribuições de cada variável reconciliada. Variáveis sem estimativa
+	// em alguma amostra (ex: não observáveis) não têm distribuição (nil, null em JSON).
+	Variables []*Distribution `json:"variables"`
+}
+
+// MonteCarlo propaga a incerteza das medições para os valores reconciliados por Monte Carlo,
+// resolvendo Reconcile (com os mesmos limites e opções) para cada conjunto sorteado. É útil
+// com limites ativos ou estimadores robustos, quando a covariância analítica deixa de valer.
+func MonteCarlo(measurements, tolerances []float64, constraints *mat.Dense, opts ...Option) (*MonteCarloResult, error) {
+	solveOpts := append(append([]Option(nil), opts...), WithReconciledCovariance(false))
+	return monteCarlo(measurements, tolerances, opts, func(m []float64) (Values, error) {
+		result, err := Reconcile(m, tolerances, constraints, solveOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return result.Reconciled, nil
+	})
+}
+
+// MonteCarloNonlinear é o equivalente de MonteCarlo para a reconciliação não linear
+// (ReconcileNonlinear). Amostras que não convergem contam como falhas.
+func MonteCarloNonlinear(measurements, tolerances []float64, constraints []Constraint, opts ...Option) (*MonteCarloResult, error) {
+	solveOpts := append(append([]Option(nil), opts...), WithReconciledCovariance(false))
+	return monteCarlo(measurements, tolerances, opts, func(m []float64) (Values, error) {
+		result, err := ReconcileNonlinear(m, tolerances, constraints, solveOpts...)
+		if err != nil {
+			return nil, err
+		}
+		if !result.Convergence.Converged {
+			return nil, errors.New("a reconciliação não linear não convergiu")
+		}
+		return result.Reconciled, nil
+	})
+}
+
+// monteCarlo sorteia os conjuntos de medições e os resolve com um pool de workers.
+//
+// Cada conjunto é m* = m + L*z, onde z é um vetor normal padrão e L o fator de Cholesky da
+// covariância das medições V (as tolerâncias e opções de incerteza, como em Reconcile); as
+// variáveis não medidas e as fixas não são perturbadas. A amostra k usa um gerador próprio, iniciado
+// com (semente, k), de modo que o resultado não depende do número de workers nem da ordem
+// em que as amostras terminam.
+func monteCarlo(measurements, tolerances []float64, opts []Option, solve func([]float64) (Values, error)) (*MonteCarloResult, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	n := len(measurements)
+	if n == 0 {
+		return nil, fmt.Errorf("%w: o slice de medições não pode estar vazio", ErrInvalidInput)
+	}
+	if len(tolerances) != n {
+		return nil, fmt.Errorf("%w: medições (%d) e tolerâncias (%d)", ErrDimensionMismatch, n, len(tolerances))
+	}
+	if err := validateCovariance(o, n); err != nil {
+		return nil, err
+	}
+	if err := resolveMissing(measurements, &o); err != nil {
+		return nil, err
+	}
+
+	// A solução das medições nominais valida o problema antes de sortear as amostras.
+	if _, err := solve(measurements); err != nil {
+		return nil, err
+	}
+
+	var measured []int
+	deviations := make(Values, n)
+	for i, m := range measurements {
+		if math.IsNaN(m) || o.unmeasured[i] || o.isFixed(i, tolerances[i]) {
+			continue
+		}
+		measured = append(measured, i)
+		if o.covariance == nil {
+			if deviations[i], err = deviation(i, m, tolerances[i], o.uncertainty(i)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(measured) == 0 {
+		return nil, fmt.Errorf("%w: o Monte Carlo precisa de ao menos uma variável medida", ErrInvalidInput)
+	}
+	_, covariance, err := weightMatrix(o, deviations, measured)
+	if err != nil {
+		return nil, err
+	}
+	var factor mat.TriDense
+	var chol mat.Cholesky
+	if ok := chol.Factorize(covariance); !ok {
+		return nil, fmt.Errorf("%w: a matriz de covariância das medições não é definida positiva", ErrInvalidInput)
+	}
+	chol.LTo(&factor)
+
+	workers := o.workers
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	samples := make([]Values, o.samples)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			z := mat.NewVecDense(len(measured), nil)
+			var shift mat.VecDense
+			for k := range jobs {
+				rng := rand.New(rand.NewPCG(o.seed, uint64(k)))
+				for a := range measured {
+					z.SetVec(a, rng.NormFloat64())
+				}
+				shift.MulVec(&factor, z)
+				perturbed := append([]float64(nil), measurements...)
+				for a, i := range measured {
+					perturbed[i] += shift.AtVec(a)
+				}
+				if x, err := solve(perturbed); err == nil {
+					samples[k] = x
+				}
+			}
+		}()
+	}
+	for k := range samples {
+		jobs <- k
+	}
+	close(jobs)
+	wg.Wait()
+
+	result := &MonteCarloResult{Samples: o.samples, Seed: o.seed, Variables: make([]*Distribution, n)}
+	var solved []Values
+	for _, x := range samples {
+		if x == nil {
+			result.Failed++
+			continue
+		}
+		solved = append(solved, x)
+	}
+	if len(solved) == 0 {
+		return nil, errors.New("a reconciliação falhou em todas as amostras do Monte Carlo")
+	}
+	values := make([]float64, len(solved))
+	for i := range result.Variables {
+		for k, x := range solved {
+			values[k] = x[i]
+		}
+		result.Variables[i] = distribution(values, o.histogramBins)
+	}
+	return result, nil
+}
+
+// distribution resume as amostras de uma variável. Qualquer amostra NaN torna a distribuição
+// indefinida (nil).
+func distribution(values []float64, bins int) *Distribution {
+	d := &Distribution{Percentiles: make([]Percentile, len(MonteCarloPercentiles))}
+	sorted := append([]float64(nil), values...)
+	for _, v := range sorted {
+		if math.IsNaN(v) {
+			return nil
+		}
+	}
+	sort.Float64s(sorted)
+	d.Mean, d.StdDev = stat.MeanStdDev(sorted, nil)
+	if len(sorted) == 1 {
+		d.StdDev = 0
+	}
+	for p, level := range MonteCarloPercentiles {
+		d.Percentiles[p] = Percentile{Level: level, Value: stat.Quantile(level, stat.LinInterp, sorted, nil)}
+	}
+
+	low, high := sorted[0], sorted[len(sorted)-1]
+	if high == low {
+		// Todas as amostras iguais (ex: uma variável fixada por um limite ativo): uma só classe.
+		d.Histogram = Histogram{Edges: []float64{low, high}, Counts: []int{len(sorted)}}
+		return d
+	}
+	width := (high - low) / float64(bins)
+	d.Histogram = Histogram{Edges: make([]float64, bins+1), Counts: make([]int, bins)}
+	for b := range d.Histogram.Edges {
+		d.Histogram.Edges[b] = low + float64(b)*width
+	}
+	d.Histogram.Edges[bins] = high
+	for _, v := range sorted {
+		b := min(int((v-low)/width), bins-1)
+		d.Histogram.Counts[b]++
+	}
+	return d
+}
@@ -0,0 +1,116 @@
+package reconciliation
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMonteCarlo(t *testing.T) {
+	measurements := []float64{100, 60, 42, 40, 101}
+	tolerances := []float64{1, 1.2, 0.4, 1.2, 1}
+	absolute := WithUncertaintyMode(UncertaintyAbsolute)
+
+	t.Run("Linear Igual ao Analítico", func(t *testing.T) {
+		analytic, err := Reconcile(measurements, tolerances, networkConstraints(), absolute)
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		result, err := MonteCarlo(measurements, tolerances, networkConstraints(), absolute, WithSamples(4000))
+		if err != nil {
+			t.Fatalf("A função MonteCarlo retornou um erro inesperado: %v", err)
+		}
+		if result.Samples != 4000 || result.Failed != 0 || result.Seed != DefaultMonteCarloSeed {
+			t.Errorf("Contagem de amostras incorreta: %+v", result)
+		}
+		for i, d := range result.Variables {
+			sigma := analytic.ReconciledDeviations[i]
+			if math.Abs(d.Mean-analytic.Reconciled[i]) > 4*sigma/math.Sqrt(4000) {
+				t.Errorf("Média da variável %d: esperado %v, obtido %v", i, analytic.Reconciled[i], d.Mean)
+			}
+			if math.Abs(d.StdDev-sigma) > 0.05*sigma {
+				t.Errorf("Desvio da variável %d: esperado %v, obtido %v", i, sigma, d.StdDev)
+			}
+			if d.Percentiles[3].Level != 0.5 || d.Percentiles[0].Value >= d.Percentiles[3].Value || d.Percentiles[3].Value >= d.Percentiles[6].Value {
+				t.Errorf("Percentis da variável %d fora de ordem: %v", i, d.Percentiles)
+			}
+			count := 0
+			for _, c := range d.Histogram.Counts {
+				count += c
+			}
+			if count != 4000 || len(d.Histogram.Edges) != DefaultHistogramBins+1 {
+				t.Errorf("Histograma da variável %d incorreto: %+v", i, d.Histogram)
+			}
+		}
+	})
+
+	t.Run("Reprodutível", func(t *testing.T) {
+		serial, err := MonteCarlo(measurements, tolerances, networkConstraints(), absolute, WithSamples(200), WithSeed(7), WithWorkers(1))
+		if err != nil {
+			t.Fatalf("A função MonteCarlo retornou um erro inesperado: %v", err)
+		}
+		parallel, err := MonteCarlo(measurements, tolerances, networkConstraints(), absolute, WithSamples(200), WithSeed(7), WithWorkers(8))
+		if err != nil {
+			t.Fatalf("A função MonteCarlo retornou um erro inesperado: %v", err)
+		}
+		if !reflect.DeepEqual(serial, parallel) {
+			t.Error("A mesma semente deveria reproduzir o resultado com qualquer número de workers")
+		}
+		other, _ := MonteCarlo(measurements, tolerances, networkConstraints(), absolute, WithSamples(200), WithSeed(8))
+		if reflect.DeepEqual(serial.Variables, other.Variables) {
+			t.Error("Sementes diferentes deveriam produzir amostras diferentes")
+		}
+	})
+
+	t.Run("Limites", func(t *testing.T) {
+		// Uma corrente pequena com limite inferior zero: a distribuição fica truncada em zero,
+		// o que a covariância analítica não representa.
+		small := []float64{100, 99.7, 0.3, 0.3, 100}
+		lower := []float64{0, 0, 0, 0, 0}
+		result, err := MonteCarlo(small, tolerances, networkConstraints(), absolute, WithBounds(lower, nil), WithSamples(500))
+		if err != nil {
+			t.Fatalf("A função MonteCarlo retornou um erro inesperado: %v", err)
+		}
+		d := result.Variables[2]
+		if d.Percentiles[0].Value < -1e-9 || d.Histogram.Edges[0] < -1e-9 {
+			t.Errorf("A corrente limitada não deveria ficar negativa: %+v", d.Percentiles)
+		}
+		if d.Percentiles[0].Value > 1e-9 {
+			t.Errorf("O limite deveria ficar ativo em parte das amostras: %+v", d.Percentiles)
+		}
+	})
+
+	t.Run("Não Linear", func(t *testing.T) {
+		measurements := []float64{101, 49, 148, 0.21, 0.58, 0.34}
+		tolerances := []float64{0.02, 0.02, 0.02, 0.02, 0.02, 0.02}
+		result, err := MonteCarloNonlinear(measurements, tolerances, mixerConstraints(), WithSamples(300))
+		if err != nil {
+			t.Fatalf("A função MonteCarloNonlinear retornou um erro inesperado: %v", err)
+		}
+		nominal, _ := ReconcileNonlinear(measurements, tolerances, mixerConstraints())
+		for i, d := range result.Variables {
+			if d == nil || math.Abs(d.Percentiles[3].Value-nominal.Reconciled[i]) > 3*nominal.ReconciledDeviations[i] {
+				t.Errorf("Mediana da variável %d longe da solução nominal: %+v", i, d)
+			}
+		}
+	})
+
+	t.Run("Não Observável", func(t *testing.T) {
+		// x1, x2 e x3 não medidas: só a soma x1 + x2 é determinada, e as três ficam sem distribuição.
+		result, err := MonteCarlo([]float64{100, math.NaN(), math.NaN(), math.NaN(), 101}, tolerances, networkConstraints(), absolute, WithUnmeasured([]int{1, 2, 3}), WithSamples(50))
+		if err != nil {
+			t.Fatalf("A função MonteCarlo retornou um erro inesperado: %v", err)
+		}
+		if result.Variables[0] == nil || result.Variables[1] != nil || result.Variables[2] != nil || result.Variables[3] != nil {
+			t.Errorf("Apenas as variáveis não observáveis deveriam ficar sem distribuição: %+v", result.Variables)
+		}
+	})
+
+	t.Run("Entradas Inválidas", func(t *testing.T) {
+		for _, opt := range []Option{WithSamples(0), WithWorkers(-1), WithHistogramBins(0)} {
+			if _, err := MonteCarlo(measurements, tolerances, networkConstraints(), opt); err == nil {
+				t.Error("Uma configuração inválida do Monte Carlo deveria gerar um erro")
+			}
+		}
+	})
+}
@@ -0,0 +1,252 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Constraint é uma restrição escalar não linear g(x) = 0, com o seu gradiente.
+type Constraint struct {
+	// Value avalia g(x).
+	Value func(x []float64) float64
+	// Gradient escreve em grad o gradiente de g avaliado em x (uma linha da jacobiana).
+	Gradient func(grad, x []float64)
+}
+
+// Term é um monômio coeficiente * Π x_v de uma restrição polinomial. Um índice repetido
+// em Variables eleva a variável à potência correspondente.
+type Term struct {
+	// Coefficient é o coeficiente do monômio.
+	Coefficient float64 `json:"coefficient"`
+	// Variables são os índices das variáveis multiplicadas no monômio. Vazio representa uma constante.
+	Variables []int `json:"variables"`
+}
+
+// Polynomial é uma restrição polinomial Σ termos + Constant = 0. Descreve, sem código, os
+// balanços lineares (um termo por variável), os balanços de componente (vazão × concentração)
+// e os balanços de energia (vazão × temperatura × cp).
+type Polynomial struct {
+	// Terms são os monômios da restrição.
+	Terms []Term `json:"terms"`
+	// Constant é o termo constante da restrição.
+	Constant float64 `json:"constant,omitempty"`
+}
+
+// LinearPolynomial converte uma linha b de uma restrição linear b*x = c em um Polynomial.
+func LinearPolynomial(row []float64, rhs float64) Polynomial {
+	p := Polynomial{Constant: -rhs}
+	for j, b := range row {
+		if b != 0 {
+			p.Terms = append(p.Terms, Term{Coefficient: b, Variables: []int{j}})
+		}
+	}
+	return p
+}
+
+// Validate verifica se os índices das variáveis estão no intervalo [0, numVariables).
+func (p Polynomial) Validate(numVariables int) error {
+	for _, t := range p.Terms {
+		for _, v := range t.Variables {
+			if v < 0 || v >= numVariables {
+				return fmt.Errorf("%w: índice de variável fora do intervalo no termo polinomial: %d", ErrInvalidInput, v)
+			}
+		}
+	}
+	return nil
+}
+
+// Constraint retorna a restrição g(x) = 0 do polinômio, com o gradiente analítico.
+func (p Polynomial) Constraint() Constraint {
+	return Constraint{
+		Value: func(x []float64) float64 {
+			value := p.Constant
+			for _, t := range p.Terms {
+				product := t.Coefficient
+				for _, v := range t.Variables {
+					product *= x[v]
+				}
+				value += product
+			}
+			return value
+		},
+		Gradient: func(grad, x []float64) {
+			for j := range grad {
+				grad[j] = 0
+			}
+			// A derivada de um monômio em relação a uma ocorrência de x_v é o produto das demais.
+			for _, t := range p.Terms {
+				for k, v := range t.Variables {
+					product := t.Coefficient
+					for l, u := range t.Variables {
+						if l != k {
+							product *= x[u]
+						}
+					}
+					grad[v] += product
+				}
+			}
+		},
+	}
+}
+
+// Convergence descreve a convergência de um método iterativo.
+type Convergence struct {
+	// Iterations é o número de iterações realizadas.
+	Iterations int `json:"iterations"`
+	// Converged indica se a tolerância de convergência foi atingida.
+	Converged bool `json:"converged"`
+	// ConstraintNorm é o maior resíduo absoluto das restrições na solução, max |g(x)|.
+	ConstraintNorm float64 `json:"constraintNorm"`
+	// StepNorm é o maior passo relativo da última iteração, max |Δx_i| / max(1, |x_i|).
+	StepNorm float64 `json:"stepNorm"`
+}
+
+// NonlinearResult é o resultado da reconciliação não linear.
+type NonlinearResult struct {
+	// Result é a reconciliação linearizada na solução. As estatísticas (covariância, testes)
+	// valem para o problema linearizado; os resíduos antes e depois são os das restrições
+	// não lineares, g(m) e g(x), e o teste nodal é aplicado a g(m).
+	*Result
+	// Convergence descreve a convergência das linearizações sucessivas.
+	Convergence Convergence `json:"convergence"`
+}
+
+// ReconcileNonlinear ajusta os valores medidos para que obedeçam a restrições não lineares
+// g(x) = 0, como balanços de componente (bilineares) e de energia, por linearizações
+// sucessivas (SQP com passos de Gauss-Newton).
+//
+// A cada iteração, as restrições são linearizadas no ponto atual x_k,
+//
+//	g(x) ≈ g(x_k) + J(x_k) * (x - x_k) = 0  =>  J(x_k) * x = J(x_k) * x_k - g(x_k),
+//
+// e o problema linear resultante é resolvido por Reconcile, com B = J(x_k) e c = J(x_k)*x_k - g(x_k).
+// Como a função objetivo é quadrática, cada solução é o passo de Gauss-Newton do problema
+// original. O processo termina quando o maior passo relativo e o maior resíduo das
+// restrições ficam abaixo da tolerância (WithConvergenceTolerance), ou após o número
+// máximo de iterações (WithMaxIterations); neste caso Convergence.Converged é falso.
+//
+// As demais opções de Reconcile (incertezas, covariâncias, variáveis não medidas, limites)
+// são aplicadas a cada problema linearizado. O lado direito (WithRHS) não se aplica: os
+// termos fixos fazem parte de g. O ponto inicial são as medições, ou WithStartingPoint.
+func ReconcileNonlinear(measurements, tolerances []float64, constraints []Constraint, opts ...Option) (*NonlinearResult, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	numVariables := len(measurements)
+	numConstraints := len(constraints)
+	if numVariables == 0 {
+		return nil, fmt.Errorf("%w: o slice de medições não pode estar vazio", ErrInvalidInput)
+	}
+	if numConstraints == 0 {
+		return nil, fmt.Errorf("%w: a reconciliação não linear precisa de ao menos uma restrição", ErrInvalidInput)
+	}
+	if o.rhs != nil {
+		return nil, fmt.Errorf("%w: a reconciliação não linear não aceita lado direito, inclua os termos fixos nas restrições", ErrInvalidInput)
+	}
+	if o.start != nil && len(o.start) != numVariables {
+		return nil, fmt.Errorf("%w: medições (%d) e ponto inicial (%d)", ErrDimensionMismatch, numVariables, len(o.start))
+	}
+	if err := resolveMissing(measurements, &o); err != nil {
+		return nil, err
+	}
+
+	// As medições ausentes já resolvidas passam a cada problema linearizado como não medidas.
+	unmeasured := make([]int, 0, len(o.unmeasured))
+	for i := range o.unmeasured {
+		unmeasured = append(unmeasured, i)
+	}
+
+	// Ponto inicial: o informado, ou a própria medição.
+	x := make([]float64, numVariables)
+	for i := range x {
+		switch {
+		case o.start != nil && !math.IsNaN(o.start[i]):
+			x[i] = o.start[i]
+		case !math.IsNaN(measurements[i]) && !math.IsInf(measurements[i], 0):
+			x[i] = measurements[i]
+		default:
+			return nil, fmt.Errorf("%w: a variável %d não tem medição nem ponto inicial", ErrInvalidInput, i)
+		}
+	}
+
+	g := make([]float64, numConstraints)
+	evaluate := func(x []float64) float64 {
+		norm := 0.0
+		for k, c := range constraints {
+			g[k] = c.Value(x)
+			norm = math.Max(norm, math.Abs(g[k]))
+		}
+		return norm
+	}
+
+	jacobian := mat.NewDense(numConstraints, numVariables, nil)
+	var result *Result
+	convergence := Convergence{}
+	for convergence.Iterations < o.maxIterations {
+		convergence.Iterations++
+
+		// Lineariza as restrições no ponto atual: B = J(x_k) e c = J(x_k)*x_k - g(x_k).
+		evaluate(x)
+		for k, c := range constraints {
+			c.Gradient(jacobian.RawRowView(k), x)
+		}
+		var jx mat.VecDense
+		jx.MulVec(jacobian, mat.NewVecDense(numVariables, x))
+		rhs := make([]float64, numConstraints)
+		for k := range rhs {
+			rhs[k] = jx.AtVec(k) - g[k]
+		}
+
+		linearOpts := append(append([]Option(nil), opts...), WithRHS(rhs), WithUnmeasured(unmeasured))
+		if result, err = Reconcile(measurements, tolerances, jacobian, linearOpts...); err != nil {
+			return nil, fmt.Errorf("falha na iteração %d da reconciliação não linear: %w", convergence.Iterations, err)
+		}
+
+		convergence.StepNorm = 0
+		for i, xi := range result.Reconciled {
+			if math.IsNaN(xi) {
+				return nil, fmt.Errorf("a variável %d não é observável na iteração %d", i, convergence.Iterations)
+			}
+			convergence.StepNorm = math.Max(convergence.StepNorm, math.Abs(xi-x[i])/math.Max(1, math.Abs(x[i])))
+		}
+		copy(x, result.Reconciled)
+
+		convergence.ConstraintNorm = evaluate(x)
+		if convergence.StepNorm < o.convergenceTolerance && convergence.ConstraintNorm < o.convergenceTolerance {
+			convergence.Converged = true
+			break
+		}
+	}
+
+	// Substitui os resíduos linearizados pelos resíduos das restrições não lineares.
+	values := make([]float64, numVariables)
+	for i := range values {
+		values[i] = measurements[i]
+		if o.unmeasured[i] {
+			values[i] = math.NaN()
+		}
+	}
+	result.ResidualsBefore = make(Values, numConstraints)
+	result.ResidualsAfter = make(Values, numConstraints)
+	for k, c := range constraints {
+		result.ResidualsBefore[k] = c.Value(values)
+		result.ResidualsAfter[k] = c.Value(x)
+	}
+
+	// Refaz o teste nodal sobre os resíduos não lineares g(m), com as variâncias da última
+	// linearização. As restrições sem resíduo (que envolvem variáveis não medidas) não são testadas.
+	variances := append([]float64(nil), result.residualVariances...)
+	for k, r := range result.ResidualsBefore {
+		if math.IsNaN(r) {
+			variances[k] = 0
+		}
+	}
+	if result.NodalTest, err = NodalTest(result.ResidualsBefore, variances, o.confidence); err != nil {
+		return nil, err
+	}
+
+	return &NonlinearResult{Result: result, Convergence: convergence}, nil
+}
@@ -0,0 +1,150 @@
+package reconciliation
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// mixerConstraints descreve um misturador com balanço de massa F1 + F2 = F3 e balanço de
+// componente F1*c1 + F2*c2 = F3*c3, com variáveis [F1, F2, F3, c1, c2, c3].
+func mixerConstraints() []Constraint {
+	return []Constraint{
+		LinearPolynomial([]float64{1, 1, -1, 0, 0, 0}, 0).Constraint(),
+		Polynomial{Terms: []Term{
+			{Coefficient: 1, Variables: []int{0, 3}},
+			{Coefficient: 1, Variables: []int{1, 4}},
+			{Coefficient: -1, Variables: []int{2, 5}},
+		}}.Constraint(),
+	}
+}
+
+func TestReconcileNonlinear(t *testing.T) {
+	t.Run("Misturador Bilinear", func(t *testing.T) {
+		measurements := []float64{101, 49, 148, 0.21, 0.58, 0.34}
+		tolerances := []float64{0.02, 0.02, 0.02, 0.02, 0.02, 0.02}
+		result, err := ReconcileNonlinear(measurements, tolerances, mixerConstraints())
+		if err != nil {
+			t.Fatalf("A função ReconcileNonlinear retornou um erro inesperado: %v", err)
+		}
+		if !result.Convergence.Converged || result.Convergence.Iterations < 2 {
+			t.Errorf("A reconciliação deveria convergir em mais de uma iteração: %+v", result.Convergence)
+		}
+		for k, r := range result.ResidualsAfter {
+			if math.Abs(r) > 1e-8 {
+				t.Errorf("Resíduo da restrição %d após a reconciliação: %v", k, r)
+			}
+		}
+		if math.Abs(result.ResidualsBefore[1]-(101*0.21+49*0.58-148*0.34)) > 1e-9 {
+			t.Errorf("Resíduo inicial do balanço de componente incorreto: %v", result.ResidualsBefore[1])
+		}
+		// O teste nodal é aplicado aos resíduos não lineares informados.
+		for k, r := range result.NodalTest.Residuals {
+			if r != result.ResidualsBefore[k] {
+				t.Errorf("O teste nodal deveria usar os resíduos g(m): %v, %v", result.NodalTest.Residuals, result.ResidualsBefore)
+			}
+		}
+
+		// Na solução, o gradiente da função objetivo deve ser combinação das linhas da jacobiana.
+		x := result.Reconciled
+		jacobian := mat.NewDense(2, 6, []float64{
+			1, 1, -1, 0, 0, 0,
+			x[3], x[4], -x[5], x[0], x[1], -x[2],
+		})
+		var jtl mat.VecDense
+		jtl.MulVec(jacobian.T(), mat.NewVecDense(2, result.Lambda))
+		for i := range x {
+			sigma := tolerances[i] * measurements[i]
+			stationarity := (x[i]-measurements[i])/(sigma*sigma) + jtl.AtVec(i)
+			if math.Abs(stationarity) > 1e-5 {
+				t.Errorf("Condição de otimalidade violada na variável %d: %v", i, stationarity)
+			}
+		}
+	})
+
+	t.Run("Restrições Lineares", func(t *testing.T) {
+		measurements := []float64{161, 79, 80}
+		tolerances := []float64{0.05, 0.01, 0.01}
+		constraints := mat.NewDense(1, 3, []float64{1, -1, -1})
+		expected, err := Reconcile(measurements, tolerances, constraints)
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		result, err := ReconcileNonlinear(measurements, tolerances, []Constraint{LinearPolynomial([]float64{1, -1, -1}, 0).Constraint()})
+		if err != nil {
+			t.Fatalf("A função ReconcileNonlinear retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, expected.Reconciled, 1e-9) || !result.Convergence.Converged {
+			t.Errorf("Restrições lineares deveriam reproduzir Reconcile.\nEsperado: %v\nObtido:   %v", expected.Reconciled, result.Reconciled)
+		}
+	})
+
+	t.Run("Variável Não Medida", func(t *testing.T) {
+		// c3 não é medida e é estimada pelo balanço de componente.
+		measurements := []float64{101, 49, 148, 0.21, 0.58, math.NaN()}
+		tolerances := []float64{0.02, 0.02, 0.02, 0.02, 0.02, 0}
+		_, err := ReconcileNonlinear(measurements, tolerances, mixerConstraints(), WithUnmeasured([]int{5}))
+		if err == nil {
+			t.Fatal("Uma variável não medida sem ponto inicial deveria gerar um erro")
+		}
+		result, err := ReconcileNonlinear(measurements, tolerances, mixerConstraints(), WithUnmeasured([]int{5}),
+			WithStartingPoint([]float64{math.NaN(), math.NaN(), math.NaN(), math.NaN(), math.NaN(), 0.3}))
+		if err != nil {
+			t.Fatalf("A função ReconcileNonlinear retornou um erro inesperado: %v", err)
+		}
+		x := result.Reconciled
+		if !result.Convergence.Converged || math.Abs(x[0]*x[3]+x[1]*x[4]-x[2]*x[5]) > 1e-8 {
+			t.Errorf("c3 deveria fechar o balanço de componente: %v", x)
+		}
+	})
+
+	t.Run("Medição Ausente", func(t *testing.T) {
+		measurements := []float64{101, 49, 148, 0.21, 0.58, math.NaN()}
+		tolerances := []float64{0.02, 0.02, 0.02, 0.02, 0.02, 0.02}
+		start := WithStartingPoint([]float64{math.NaN(), math.NaN(), math.NaN(), math.NaN(), math.NaN(), 0.3})
+		if _, err := ReconcileNonlinear(measurements, tolerances, mixerConstraints(), start); err == nil {
+			t.Fatal("Uma medição ausente deveria ser rejeitada por padrão")
+		}
+		result, err := ReconcileNonlinear(measurements, tolerances, mixerConstraints(), start, WithMissing(MissingUnmeasured))
+		if err != nil {
+			t.Fatalf("A função ReconcileNonlinear retornou um erro inesperado: %v", err)
+		}
+		expected, err := ReconcileNonlinear(measurements, tolerances, mixerConstraints(), start, WithUnmeasured([]int{5}))
+		if err != nil {
+			t.Fatalf("A função ReconcileNonlinear retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, expected.Reconciled, 1e-12) || result.Classification[5] != ClassObservable {
+			t.Errorf("A medição ausente deveria ser tratada como não medida.\nEsperado: %v\nObtido:   %v", expected.Reconciled, result.Reconciled)
+		}
+	})
+
+	t.Run("Erro Tipado", func(t *testing.T) {
+		// O balanço de massa repetido torna as restrições linearizadas dependentes.
+		constraints := append(mixerConstraints(), LinearPolynomial([]float64{2, 2, -2, 0, 0, 0}, 0).Constraint())
+		_, err := ReconcileNonlinear([]float64{101, 49, 148, 0.21, 0.58, 0.34}, []float64{0.02, 0.02, 0.02, 0.02, 0.02, 0.02}, constraints)
+		var rankErr *RankDeficiencyError
+		if !errors.As(err, &rankErr) {
+			t.Errorf("Esperava-se um *RankDeficiencyError, obtido: %v", err)
+		}
+	})
+
+	t.Run("Sem Convergência", func(t *testing.T) {
+		result, err := ReconcileNonlinear([]float64{101, 49, 148, 0.21, 0.58, 0.34}, []float64{0.02, 0.02, 0.02, 0.02, 0.02, 0.02},
+			mixerConstraints(), WithMaxIterations(1))
+		if err != nil {
+			t.Fatalf("A função ReconcileNonlinear retornou um erro inesperado: %v", err)
+		}
+		if result.Convergence.Converged || result.Convergence.Iterations != 1 {
+			t.Errorf("Uma iteração não deveria bastar para convergir: %+v", result.Convergence)
+		}
+	})
+
+	t.Run("Índice Inválido", func(t *testing.T) {
+		p := Polynomial{Terms: []Term{{Coefficient: 1, Variables: []int{0, 7}}}}
+		if err := p.Validate(6); err == nil {
+			t.Error("Um índice fora do intervalo deveria gerar um erro")
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package reconciliation
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// VariableClass é a classificação de uma variável quanto à redundância e à observabilidade.
+type VariableClass string
+
+const (
+	// ClassRedundant é uma variável medida que também pode ser calculada pelas restrições a
+	// partir das demais medições; a sua medição é ajustada pela reconciliação.
+	ClassRedundant VariableClass = "redundant"
+	// ClassNonRedundant é uma variável medida (apenas medida) que não aparece nas restrições
+	// reduzidas; a sua medição não é ajustada e o seu erro não pode ser detectado.
+	ClassNonRedundant VariableClass = "nonRedundant"
+	// ClassObservable é uma variável não medida que pode ser estimada de forma única pelas
+	// restrições a partir das variáveis medidas.
+	ClassObservable VariableClass = "observable"
+	// ClassUnobservable é uma variável não medida que não pode ser determinada pelas restrições.
+	ClassUnobservable VariableClass = "unobservable"
+	// ClassFixed é uma variável de valor exato (variância zero ou WithFixed), que entra nas
+	// restrições como um termo conhecido e não é ajustada.
+	ClassFixed VariableClass = "fixed"
+)
+
+// rankTolerance é a tolerância relativa ao maior valor singular abaixo da qual um valor
+// singular é considerado nulo na determinação do posto de uma matriz.
+const rankTolerance = 1e-10
+
+// projection descreve a eliminação das variáveis não medidas das restrições.
+//
+// As restrições B*x = c são separadas em B_M*x_M + B_U*x_U = c. A decomposição em valores
+// singulares de B_U = U*Σ*V^T revela o seu posto r e fornece uma base ortonormal U_2 para o
+// complemento ortogonal da imagem de B_U. A matriz de projeção P = U_2^T satisfaz P*B_U = 0,
+// de forma que as restrições reduzidas P*B_M*x_M = P*c envolvem apenas as variáveis medidas.
+// Depois da reconciliação das medidas, as não medidas são estimadas por mínimos quadrados,
+// x_U = B_U^+ * (c - B_M*x_M), e apenas as que não pertencem ao núcleo de B_U são observáveis.
+type projection struct {
+	// measured e unmeasured são os índices das variáveis medidas e não medidas, em ordem. As
+	// variáveis fixas não pertencem a nenhum dos dois: as suas colunas já foram levadas ao lado
+	// direito (ver fixVariables).
+	measured, unmeasured []int
+	// p é a matriz de projeção P; nula quando não há variáveis não medidas (P = I).
+	p *mat.Dense
+	// reduced é a matriz das restrições reduzidas P*B_M; nula quando não sobra nenhuma restrição.
+	reduced *mat.Dense
+	// bMeasured e bUnmeasured são as colunas de B das variáveis medidas e não medidas.
+	bMeasured, bUnmeasured *mat.Dense
+	// pinv é a pseudoinversa B_U^+; nula quando não há variáveis não medidas.
+	pinv *mat.Dense
+	// observable indica, para cada variável não medida, se ela é observável.
+	observable []bool
+}
+
+// newProjection separa as variáveis não medidas e constrói a projeção das restrições. As
+// variáveis fixas ficam de fora.
+func newProjection(constraints *mat.Dense, unmeasured, fixed map[int]bool) *projection {
+	numConstraints, numVariables := constraints.Dims()
+	pr := &projection{}
+	for j := 0; j < numVariables; j++ {
+		if fixed[j] {
+			continue
+		}
+		if unmeasured[j] {
+			pr.unmeasured = append(pr.unmeasured, j)
+		} else {
+			pr.measured = append(pr.measured, j)
+		}
+	}
+	pr.bMeasured = columns(constraints, pr.measured)
+	if len(pr.unmeasured) == 0 {
+		pr.reduced = pr.bMeasured
+		return pr
+	}
+	pr.bUnmeasured = columns(constraints, pr.unmeasured)
+
+	var svd mat.SVD
+	svd.Factorize(pr.bUnmeasured, mat.SVDFull)
+	var u, v mat.Dense
+	svd.UTo(&u)
+	svd.VTo(&v)
+	singular := svd.Values(nil)
+	rank := numericalRank(singular)
+
+	// B_U^+ = V_r * Σ_r^-1 * U_r^T.
+	numUnmeasured := len(pr.unmeasured)
+	pr.pinv = mat.NewDense(numUnmeasured, numConstraints, nil)
+	for k := 0; k < rank; k++ {
+		for i := 0; i < numUnmeasured; i++ {
+			for j := 0; j < numConstraints; j++ {
+				pr.pinv.Set(i, j, pr.pinv.At(i, j)+v.At(i, k)*u.At(j, k)/singular[k])
+			}
+		}
+	}
+
+	// Uma variável não medida é observável se a sua linha na base do núcleo de B_U é nula.
+	pr.observable = make([]bool, numUnmeasured)
+	for i := 0; i < numUnmeasured; i++ {
+		norm := 0.0
+		for k := rank; k < numUnmeasured; k++ {
+			norm += v.At(i, k) * v.At(i, k)
+		}
+		pr.observable[i] = math.Sqrt(norm) < 1e-8
+	}
+
+	// P = U_2^T, as colunas de U associadas aos valores singulares nulos.
+	if rows := numConstraints - rank; rows > 0 {
+		pr.p = mat.NewDense(rows, numConstraints, nil)
+		pr.p.Copy(u.Slice(0, numConstraints, rank, numConstraints).T())
+		if len(pr.measured) > 0 {
+			pr.reduced = mat.NewDense(rows, len(pr.measured), nil)
+			pr.reduced.Mul(pr.p, pr.bMeasured)
+		}
+	}
+	return pr
+}
+
+// project aplica a projeção P a um vetor com um elemento por restrição.
+// Um vetor nulo (ex: lado direito omitido) resulta em um vetor nulo.
+func (pr *projection) project(v []float64) []float64 {
+	if len(pr.unmeasured) == 0 {
+		return v
+	}
+	if v == nil || pr.p == nil {
+		return nil
+	}
+	rows, _ := pr.p.Dims()
+	var out mat.VecDense
+	out.MulVec(pr.p, mat.NewVecDense(len(v), v))
+	projected := make([]float64, rows)
+	for i := range projected {
+		projected[i] = out.AtVec(i)
+	}
+	return projected
+}
+
+// rows retorna o número de restrições reduzidas.
+func (pr *projection) rows() int {
+	if pr.reduced == nil {
+		return 0
+	}
+	rows, _ := pr.reduced.Dims()
+	return rows
+}
+
+// numericalRank conta os valores singulares (em ordem decrescente) acima da tolerância.
+func numericalRank(singular []float64) int {
+	if len(singular) == 0 || singular[0] == 0 {
+		return 0
+	}
+	rank := 0
+	for _, s := range singular {
+		if s > rankTolerance*singular[0] {
+			rank++
+		}
+	}
+	return rank
+}
+
+// columns retorna uma matriz com as colunas indicadas de a, em ordem crescente, ou nula se não
+// houver colunas. Se as colunas forem todas as de a, retorna a própria a, sem cópia; o
+// resultado não deve ser alterado.
+func columns(a *mat.Dense, indices []int) *mat.Dense {
+	rows, cols := a.Dims()
+	if len(indices) == 0 || rows == 0 {
+		return nil
+	}
+	if len(indices) == cols && indices[0] == 0 && indices[cols-1] == cols-1 {
+		return a
+	}
+	out := mat.NewDense(rows, len(indices), nil)
+	for i := 0; i < rows; i++ {
+		src, dst := a.RawRowView(i), out.RawRowView(i)
+		for k, j := range indices {
+			dst[k] = src[j]
+		}
+	}
+	return out
+}
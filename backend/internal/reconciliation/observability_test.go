@@ -0,0 +1,139 @@
+package reconciliation
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestUnmeasuredVariables(t *testing.T) {
+	tolerances := []float64{0.01, 0.01, 0.01, 0.01, 0.01}
+
+	t.Run("Variável Não Medida Observável", func(t *testing.T) {
+		// x3 não é medida, mas é determinada pela restrição x3 = x4.
+		measurements := []float64{100.5, 59.8, math.NaN(), 39.9, 100.2}
+		result, err := Reconcile(measurements, tolerances, networkConstraints(), WithUnmeasured([]int{2}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if math.Abs(result.Reconciled[2]-result.Reconciled[3]) > 1e-9 {
+			t.Errorf("A estimativa de x3 deveria ser igual a x4: %v", result.Reconciled)
+		}
+		if !equal(result.ResidualsAfter, []float64{0, 0, 0}, 1e-9) {
+			t.Errorf("As restrições não foram satisfeitas: %v", result.ResidualsAfter)
+		}
+		expected := []VariableClass{ClassRedundant, ClassRedundant, ClassObservable, ClassRedundant, ClassRedundant}
+		for i, class := range result.Classification {
+			if class != expected[i] {
+				t.Errorf("Classificação da variável %d incorreta: esperado %s, obtido %s", i, expected[i], class)
+			}
+		}
+		// Duas restrições independentes sobram após eliminar x3.
+		if result.GlobalTest == nil || result.GlobalTest.DegreesOfFreedom != 2 {
+			t.Errorf("Graus de liberdade incorretos: %+v", result.GlobalTest)
+		}
+		if !math.IsNaN(result.Adjustments[2]) || result.ReconciledDeviations[2] <= 0 {
+			t.Errorf("Estatísticas da variável não medida incorretas: ajuste %v, desvio %v", result.Adjustments[2], result.ReconciledDeviations[2])
+		}
+	})
+
+	t.Run("Medição Não Redundante", func(t *testing.T) {
+		// Sem x2 e x3, as restrições reduzidas se resumem a x1 = x5, e x4 deixa de ser redundante.
+		measurements := []float64{100.5, 0, 0, 39.9, 100.2}
+		result, err := Reconcile(measurements, tolerances, networkConstraints(), WithUnmeasured([]int{1, 2}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		expected := []VariableClass{ClassRedundant, ClassObservable, ClassObservable, ClassNonRedundant, ClassRedundant}
+		for i, class := range result.Classification {
+			if class != expected[i] {
+				t.Errorf("Classificação da variável %d incorreta: esperado %s, obtido %s", i, expected[i], class)
+			}
+		}
+		if result.Reconciled[3] != 39.9 || result.StandardizedAdjustments[3] != 0 {
+			t.Errorf("A medição não redundante não deveria ser ajustada: %v", result.Reconciled[3])
+		}
+		if math.Abs(result.Reconciled[0]-result.Reconciled[4]) > 1e-9 {
+			t.Errorf("x1 e x5 deveriam ser iguais: %v", result.Reconciled)
+		}
+		if math.Abs(result.Reconciled[1]-(result.Reconciled[0]-39.9)) > 1e-9 {
+			t.Errorf("A estimativa de x2 deveria ser x1 - x4: %v", result.Reconciled)
+		}
+		if result.GlobalTest == nil || result.GlobalTest.DegreesOfFreedom != 1 {
+			t.Errorf("Graus de liberdade incorretos: %+v", result.GlobalTest)
+		}
+	})
+
+	t.Run("Variáveis Não Observáveis", func(t *testing.T) {
+		// Com x1 = x2 + x3 e x2 + x3 = x4, apenas a soma de x2 e x3 é conhecida.
+		constraints := mat.NewDense(2, 4, []float64{
+			1, -1, -1, 0,
+			0, 1, 1, -1,
+		})
+		measurements := []float64{100.5, math.NaN(), math.NaN(), 99.8}
+		result, err := Reconcile(measurements, []float64{0.01, 0.01, 0.01, 0.01}, constraints, WithUnmeasured([]int{1, 2}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		expected := []VariableClass{ClassRedundant, ClassUnobservable, ClassUnobservable, ClassRedundant}
+		for i, class := range result.Classification {
+			if class != expected[i] {
+				t.Errorf("Classificação da variável %d incorreta: esperado %s, obtido %s", i, expected[i], class)
+			}
+		}
+		if !math.IsNaN(result.Reconciled[1]) || !math.IsNaN(result.Reconciled[2]) {
+			t.Errorf("Variáveis não observáveis não deveriam ter estimativa: %v", result.Reconciled)
+		}
+		if math.Abs(result.Reconciled[0]-result.Reconciled[3]) > 1e-9 {
+			t.Errorf("x1 e x4 deveriam ser iguais: %v", result.Reconciled)
+		}
+
+		// O resultado continua serializável, com null no lugar das estimativas ausentes.
+		data, err := json.Marshal(result)
+		if err != nil {
+			t.Fatalf("Falha ao serializar o resultado: %v", err)
+		}
+		var decoded struct {
+			Reconciled []*float64 `json:"reconciled"`
+		}
+		json.Unmarshal(data, &decoded)
+		if decoded.Reconciled[1] != nil || decoded.Reconciled[0] == nil {
+			t.Errorf("Serialização incorreta das estimativas: %s", data)
+		}
+	})
+
+	t.Run("Sem Variáveis Medidas", func(t *testing.T) {
+		constraints := mat.NewDense(1, 3, []float64{1, -1, -1})
+		_, err := Reconcile([]float64{0, 0, 0}, []float64{0.01, 0.01, 0.01}, constraints, WithUnmeasured([]int{0, 1, 2}))
+		if err == nil {
+			t.Error("Esperava-se um erro sem variáveis medidas, mas nenhum foi retornado")
+		}
+	})
+
+	t.Run("Índice Fora do Intervalo", func(t *testing.T) {
+		constraints := mat.NewDense(1, 3, []float64{1, -1, -1})
+		_, err := Reconcile([]float64{161, 79, 80}, []float64{0.05, 0.01, 0.01}, constraints, WithUnmeasured([]int{3}))
+		if err == nil {
+			t.Error("Esperava-se um erro para um índice fora do intervalo, mas nenhum foi retornado")
+		}
+	})
+}
+
+func TestValuesJSON(t *testing.T) {
+	var v Values
+	if err := json.Unmarshal([]byte(`[1.5, null, -2]`), &v); err != nil {
+		t.Fatalf("Falha ao ler o vetor: %v", err)
+	}
+	if len(v) != 3 || v[0] != 1.5 || !math.IsNaN(v[1]) || v[2] != -2 {
+		t.Errorf("Vetor lido incorretamente: %v", v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Falha ao serializar o vetor: %v", err)
+	}
+	if string(data) != `[1.5,null,-2]` {
+		t.Errorf("Vetor serializado incorretamente: %s", data)
+	}
+}
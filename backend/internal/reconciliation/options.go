@@ -0,0 +1,413 @@
+package reconciliation
+
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DefaultConfidence é o nível de confiança padrão dos testes estatísticos (95%).
+const DefaultConfidence = 0.95
+
+// DefaultConvergenceTolerance é a tolerância de convergência padrão dos métodos iterativos.
+const DefaultConvergenceTolerance = 1e-8
+
+// DefaultMaxIterations é o número máximo padrão de iterações dos métodos iterativos.
+const DefaultMaxIterations = 50
+
+// DefaultMonteCarloSamples é o número padrão de conjuntos de medições sorteados pelo Monte Carlo.
+const DefaultMonteCarloSamples = 1000
+
+// DefaultMonteCarloSeed é a semente padrão do Monte Carlo; a mesma semente reproduz o resultado.
+const DefaultMonteCarloSeed = 1
+
+// DefaultHistogramBins é o número padrão de classes dos histogramas do Monte Carlo.
+const DefaultHistogramBins = 20
+
+// Option configura um parâmetro opcional da reconciliação.
+// As opções são aplicadas na ordem em que são passadas para Reconcile.
+type Option func(*options)
+
+// options agrupa os parâmetros opcionais da reconciliação com os seus valores efetivos.
+type options struct {
+	confidence float64
+	// rhs é o lado direito c das restrições B*x = c. Nulo equivale a c = 0.
+	rhs []float64
+	// uncertaintyMode é o modo de incerteza global, usado quando a medição não define o seu.
+	uncertaintyMode UncertaintyMode
+	// coverageFactor é o fator de abrangência global, usado quando a medição não define o seu.
+	coverageFactor float64
+	// uncertainties são as incertezas por medição. Nulo usa o modo global para todas.
+	uncertainties []Uncertainty
+	// covariance é a matriz de covariância completa das medições, se informada.
+	covariance *mat.Dense
+	// covariances são as covariâncias esparsas entre pares de medições, se informadas.
+	covariances []CovarianceEntry
+	// unmeasured marca os índices das variáveis não medidas.
+	unmeasured map[int]bool
+	// fixed marca os índices das variáveis fixas, além das de tolerância zero.
+	fixed map[int]bool
+	// missing é a política das medições ausentes ou inválidas (NaN ou ±Inf).
+	missing MissingPolicy
+	// lower e upper são os limites inferiores e superiores das variáveis, se informados.
+	lower, upper []float64
+	// active são os limites ativos na iteração atual do método de conjunto ativo.
+	active []activeBound
+	// convergenceTolerance e maxIterations controlam os métodos iterativos (ex: ReconcileNonlinear).
+	convergenceTolerance float64
+	maxIterations        int
+	// start é o ponto inicial dos métodos iterativos, se informado.
+	start []float64
+	// solver é o método de solução do sistema de Lagrange.
+	solver Solver
+	// omitCovariance dispensa o cálculo da matriz Cov(x) completa, mantendo a sua diagonal.
+	omitCovariance bool
+	// covarianceChosen indica que omitCovariance foi definido por WithReconciledCovariance.
+	covarianceChosen bool
+	// dropRedundant descarta as linhas de restrição linearmente dependentes.
+	dropRedundant bool
+	// dropped marca as linhas de restrição descartadas por serem dependentes.
+	dropped map[int]bool
+	// estimator e tuningConstant definem o estimador robusto; tuningConstant zero usa o padrão.
+	estimator      Estimator
+	tuningConstant float64
+	// robustWeights são os pesos da iteração atual do estimador robusto, um por variável.
+	robustWeights []float64
+	// screening é o resultado da triagem das medições, se informado.
+	screening *ScreeningResult
+	// inflation são os fatores que multiplicam o desvio padrão das medições rebaixadas pela
+	// triagem, um por variável. Nulo equivale a 1 para todas.
+	inflation []float64
+	// samples, seed, workers e histogramBins configuram o Monte Carlo; workers zero usa
+	// GOMAXPROCS.
+	samples       int
+	seed          uint64
+	workers       int
+	histogramBins int
+	// batch compartilha as projeções e as fatorações entre os conjuntos de ReconcileBatch.
+	batch *batchCache
+}
+
+// newOptions aplica as opções informadas sobre os valores padrão e valida o resultado.
+func newOptions(opts []Option) (options, error) {
+	o := options{
+		confidence:           DefaultConfidence,
+		uncertaintyMode:      UncertaintyRelative,
+		coverageFactor:       CoverageOneSigma,
+		convergenceTolerance: DefaultConvergenceTolerance,
+		maxIterations:        DefaultMaxIterations,
+		solver:               SolverAuto,
+		estimator:            EstimatorLeastSquares,
+		missing:              MissingReject,
+		samples:              DefaultMonteCarloSamples,
+		seed:                 DefaultMonteCarloSeed,
+		histogramBins:        DefaultHistogramBins,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.confidence <= 0 || o.confidence >= 1 {
+		return o, fmt.Errorf("%w: o nível de confiança deve estar entre 0 e 1, obtido %v", ErrInvalidInput, o.confidence)
+	}
+	if o.coverageFactor <= 0 {
+		return o, fmt.Errorf("%w: o fator de abrangência deve ser positivo, obtido %v", ErrInvalidInput, o.coverageFactor)
+	}
+	if o.convergenceTolerance <= 0 {
+		return o, fmt.Errorf("%w: a tolerância de convergência deve ser positiva, obtido %v", ErrInvalidInput, o.convergenceTolerance)
+	}
+	if o.maxIterations <= 0 {
+		return o, fmt.Errorf("%w: o número máximo de iterações deve ser positivo, obtido %d", ErrInvalidInput, o.maxIterations)
+	}
+	if err := validateSolver(o.solver); err != nil {
+		return o, err
+	}
+	if err := validateEstimator(o.estimator); err != nil {
+		return o, err
+	}
+	if err := validateMissingPolicy(o.missing); err != nil {
+		return o, err
+	}
+	if o.tuningConstant < 0 {
+		return o, fmt.Errorf("%w: a constante de ajuste deve ser positiva, obtido %v", ErrInvalidInput, o.tuningConstant)
+	}
+	if o.tuningConstant == 0 {
+		o.tuningConstant = defaultTuningConstants[o.estimator]
+	}
+	if o.samples <= 0 {
+		return o, fmt.Errorf("%w: o número de amostras do Monte Carlo deve ser positivo, obtido %d", ErrInvalidInput, o.samples)
+	}
+	if o.workers < 0 {
+		return o, fmt.Errorf("%w: o número de workers não pode ser negativo, obtido %d", ErrInvalidInput, o.workers)
+	}
+	if o.histogramBins <= 0 {
+		return o, fmt.Errorf("%w: o número de classes do histograma deve ser positivo, obtido %d", ErrInvalidInput, o.histogramBins)
+	}
+	return o, nil
+}
+
+// WithConfidence define o nível de confiança (entre 0 e 1) usado nos testes estatísticos.
+func WithConfidence(confidence float64) Option {
+	return func(o *options) {
+		o.confidence = confidence
+	}
+}
+
+// WithRHS define o lado direito c das restrições, que passam a ser B*x = c.
+// O vetor deve ter um elemento por linha da matriz de restrições e representa termos fixos
+// conhecidos, como uma exportação contratual ou uma variação de inventário conhecida.
+func WithRHS(rhs []float64) Option {
+	return func(o *options) {
+		o.rhs = rhs
+	}
+}
+
+// WithUncertaintyMode define o modo de incerteza global, aplicado às medições que não
+// definem o seu próprio modo em WithUncertainties. O padrão é UncertaintyRelative.
+func WithUncertaintyMode(mode UncertaintyMode) Option {
+	return func(o *options) {
+		o.uncertaintyMode = mode
+	}
+}
+
+// WithCoverageFactor define o fator de abrangência (k) global das tolerâncias, aplicado às
+// medições que não definem o seu próprio fator em WithUncertainties. As tolerâncias são
+// divididas por k antes de construir W; o padrão, CoverageOneSigma, trata a tolerância como σ.
+func WithCoverageFactor(k float64) Option {
+	return func(o *options) {
+		o.coverageFactor = k
+	}
+}
+
+// WithUncertainties define a incerteza de cada medição, com um elemento por medição.
+// Elementos com o modo vazio ou o fator de abrangência zero herdam os valores globais,
+// mas mantêm a faixa do instrumento.
+func WithUncertainties(uncertainties []Uncertainty) Option {
+	return func(o *options) {
+		o.uncertainties = uncertainties
+	}
+}
+
+// uncertainty retorna a incerteza efetiva da medição i, aplicando o modo global quando necessário.
+func (o options) uncertainty(i int) Uncertainty {
+	var u Uncertainty
+	if o.uncertainties != nil {
+		u = o.uncertainties[i]
+	}
+	if u.Mode == "" {
+		u.Mode = o.uncertaintyMode
+	}
+	if u.CoverageFactor == 0 {
+		u.CoverageFactor = o.coverageFactor
+	}
+	return u
+}
+
+// WithCovariance define a matriz de covariância completa (n x n) dos erros das medições,
+// que deve ser simétrica e definida positiva. A sua inversa é usada como matriz de pesos,
+// e os desvios padrão passam a ser a raiz da sua diagonal: as tolerâncias são ignoradas.
+func WithCovariance(covariance *mat.Dense) Option {
+	return func(o *options) {
+		o.covariance = covariance
+	}
+}
+
+// WithCovariances define covariâncias esparsas entre pares de medições. Cada elemento
+// define V_ij = V_ji, e os elementos omitidos são zero; a diagonal continua vindo das
+// tolerâncias ou da matriz de WithCovariance.
+func WithCovariances(entries []CovarianceEntry) Option {
+	return func(o *options) {
+		o.covariances = entries
+	}
+}
+
+// WithBounds define limites inferiores e superiores para os valores reconciliados, como
+// vazões não negativas ou níveis entre zero e a altura do tanque. Cada slice, quando não
+// nulo, deve ter um elemento por variável; NaN ou ±Inf indicam uma variável sem aquele limite.
+// Com limites, o problema é resolvido como um problema quadrático por conjunto ativo, e os
+// limites ativos na solução são informados em Result.ActiveBounds.
+func WithBounds(lower, upper []float64) Option {
+	return func(o *options) {
+		o.lower, o.upper = lower, upper
+	}
+}
+
+// WithUnmeasured marca as variáveis dos índices informados como não medidas. As suas
+// medições e tolerâncias são ignoradas, elas são eliminadas das restrições por projeção e,
+// quando observáveis, estimadas a partir das variáveis medidas. Pode ser combinada várias
+// vezes; os índices são acumulados.
+func WithUnmeasured(indices []int) Option {
+	return func(o *options) {
+		if o.unmeasured == nil {
+			o.unmeasured = make(map[int]bool, len(indices))
+		}
+		for _, i := range indices {
+			o.unmeasured[i] = true
+		}
+	}
+}
+
+// WithFixed marca as variáveis dos índices informados como fixas: as suas medições são
+// valores exatos (ex: uma leitura de transferência de custódia certificada), que não são
+// ajustados. Uma tolerância zero tem o mesmo efeito, mas com uma matriz de covariância
+// completa as tolerâncias são ignoradas e só esta opção fixa uma variável; as covariâncias
+// das variáveis fixas são ignoradas. Pode ser combinada várias vezes; os índices são acumulados.
+func WithFixed(indices []int) Option {
+	return func(o *options) {
+		if o.fixed == nil {
+			o.fixed = make(map[int]bool, len(indices))
+		}
+		for _, i := range indices {
+			o.fixed[i] = true
+		}
+	}
+}
+
+// WithMissing define o tratamento das medições ausentes ou inválidas (NaN ou ±Inf) das
+// variáveis que não foram marcadas por WithUnmeasured. O padrão é MissingReject.
+func WithMissing(p MissingPolicy) Option {
+	return func(o *options) {
+		o.missing = p
+	}
+}
+
+// WithScreening aplica o resultado da triagem das medições (ver Screen) à reconciliação: as
+// variáveis sinalizadas são excluídas (tratadas como não medidas) ou rebaixadas (desvio padrão
+// multiplicado por DownweightFactor), conforme a ação, e os sinais são informados em
+// Result.Screening. As variáveis fixas (WithFixed) sinalizadas são apenas informadas.
+func WithScreening(s *ScreeningResult) Option {
+	return func(o *options) {
+		o.screening = s
+	}
+}
+
+// omitsCovariance informa se a matriz Cov(x) completa é dispensada: por escolha explícita ou,
+// sem ela, quando o método esparso foi escolhido.
+func (o options) omitsCovariance() bool {
+	if o.covarianceChosen {
+		return o.omitCovariance
+	}
+	return o.solver == SolverSparse
+}
+
+// isFixed indica se a variável medida i, com a tolerância informada, é fixa: marcada por
+// WithFixed ou com tolerância zero, quando as tolerâncias valem.
+func (o options) isFixed(i int, tolerance float64) bool {
+	return o.fixed[i] || (o.covariance == nil && tolerance == 0)
+}
+
+// WithConvergenceTolerance define a tolerância de convergência dos métodos iterativos, como
+// a reconciliação não linear. O padrão é DefaultConvergenceTolerance.
+func WithConvergenceTolerance(tolerance float64) Option {
+	return func(o *options) {
+		o.convergenceTolerance = tolerance
+	}
+}
+
+// WithMaxIterations define o número máximo de iterações dos métodos iterativos. O padrão é
+// DefaultMaxIterations.
+func WithMaxIterations(n int) Option {
+	return func(o *options) {
+		o.maxIterations = n
+	}
+}
+
+// WithStartingPoint define o ponto inicial dos métodos iterativos, com um elemento por
+// variável. Elementos NaN usam a medição da variável; variáveis não medidas precisam de um
+// ponto inicial.
+func WithStartingPoint(x0 []float64) Option {
+	return func(o *options) {
+		o.start = x0
+	}
+}
+
+// WithSolver define o método de solução do sistema de Lagrange. O padrão é SolverAuto. Com
+// SolverSparse, a matriz de covariância completa deixa de ser calculada por padrão (veja
+// WithReconciledCovariance).
+func WithSolver(solver Solver) Option {
+	return func(o *options) {
+		o.solver = solver
+	}
+}
+
+// WithReconciledCovariance define se a matriz de covariância completa das estimativas
+// (Result.Covariance) é calculada. Em plantas grandes ela tem n^2 elementos; desativada, o
+// campo fica nulo e apenas a diagonal (Result.ReconciledDeviations) é calculada. O padrão
+// é ativada, exceto com WithSolver(SolverSparse): a matriz completa é densa e o seu custo
+// (O(n^2) de memória e uma resolução por variável) domina o do método esparso. Com
+// SolverAuto ela continua ativada mesmo quando o método esparso é escolhido. Variáveis não
+// medidas sempre exigem a matriz completa.
+func WithReconciledCovariance(enabled bool) Option {
+	return func(o *options) {
+		o.omitCovariance = !enabled
+		o.covarianceChosen = true
+	}
+}
+
+// WithDropRedundantConstraints descarta automaticamente as linhas de restrição que são
+// combinação linear das anteriores (ex: o balanço da planta inteira junto com os balanços de
+// cada unidade), desde que o lado direito seja consistente. O descarte é informado em
+// Result.RankDiagnosis. Sem esta opção, restrições dependentes geram um *RankDeficiencyError
+// que identifica as linhas e as variáveis envolvidas.
+func WithDropRedundantConstraints() Option {
+	return func(o *options) {
+		o.dropRedundant = true
+	}
+}
+
+// WithEstimator define o estimador da reconciliação. O padrão é EstimatorLeastSquares; os
+// estimadores robustos são resolvidos por mínimos quadrados iterativamente reponderados.
+func WithEstimator(estimator Estimator) Option {
+	return func(o *options) {
+		o.estimator = estimator
+	}
+}
+
+// WithTuningConstant define a constante de ajuste (c) do estimador robusto, em desvios
+// padrão. Valores menores rebaixam mais cedo as medições afastadas. Se omitida, usa a
+// constante de 95% de eficiência assintótica do estimador.
+func WithTuningConstant(c float64) Option {
+	return func(o *options) {
+		o.tuningConstant = c
+	}
+}
+
+// WithSamples define o número de conjuntos de medições sorteados pelo Monte Carlo. O padrão é
+// DefaultMonteCarloSamples.
+func WithSamples(n int) Option {
+	return func(o *options) {
+		o.samples = n
+	}
+}
+
+// WithSeed define a semente do gerador de números aleatórios do Monte Carlo. A mesma semente
+// reproduz o mesmo resultado, qualquer que seja o número de workers. O padrão é
+// DefaultMonteCarloSeed.
+func WithSeed(seed uint64) Option {
+	return func(o *options) {
+		o.seed = seed
+	}
+}
+
+// WithWorkers define o número de goroutines que resolvem em paralelo as amostras do Monte
+// Carlo e os conjuntos de medições de ReconcileBatch. O padrão, zero, usa GOMAXPROCS.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithHistogramBins define o número de classes dos histogramas do Monte Carlo. O padrão é
+// DefaultHistogramBins.
+func WithHistogramBins(n int) Option {
+	return func(o *options) {
+		o.histogramBins = n
+	}
+}
+
+// withBatchCache compartilha as projeções e as fatorações entre reconciliações com a mesma
+// matriz de restrições (ver ReconcileBatch).
+func withBatchCache(c *batchCache) Option {
+	return func(o *options) {
+		o.batch = c
+	}
+}
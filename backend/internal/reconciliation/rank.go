@@ -0,0 +1,281 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// dependencyTolerance é a fração da norma de uma linha de restrição abaixo da qual o que
+// sobra dela, depois de removida a sua componente no espaço das linhas anteriores, é
+// considerado nulo. Corresponde à raiz de 1/maxConditionNumber, pois o complemento de Schur
+// eleva ao quadrado o condicionamento das restrições.
+const dependencyTolerance = 1e-6
+
+// illConditionedThreshold é o número de condição a partir do qual a solução é sinalizada
+// como mal condicionada (Result.IllConditioned), ainda que possa ser calculada.
+const illConditionedThreshold = 1e8
+
+// RowTerm é um termo de uma combinação linear de linhas de restrição.
+type RowTerm struct {
+	// Row é o índice da linha de restrição.
+	Row int `json:"row"`
+	// Coefficient é o coeficiente da linha na combinação.
+	Coefficient float64 `json:"coefficient"`
+}
+
+// DependentRow descreve uma linha de restrição que é combinação linear de linhas anteriores,
+// como o balanço da planta inteira diante dos balanços de cada unidade.
+type DependentRow struct {
+	// Row é o índice da linha dependente.
+	Row int `json:"row"`
+	// Combination expressa a linha como combinação das linhas independentes anteriores,
+	// B_row = Σ coeficiente * B_linha. Vazia para uma linha nula.
+	Combination []RowTerm `json:"combination"`
+	// Variables são os índices das variáveis envolvidas na dependência, em ordem crescente.
+	Variables []int `json:"variables"`
+	// Consistent indica se o lado direito obedece à mesma combinação; do contrário, as
+	// restrições são contraditórias e não há solução.
+	Consistent bool `json:"consistent"`
+}
+
+// RankDiagnosis é o diagnóstico do posto da matriz de restrições.
+type RankDiagnosis struct {
+	// Rank é o posto numérico da matriz de restrições.
+	Rank int `json:"rank"`
+	// DependentRows são as linhas linearmente dependentes das anteriores.
+	DependentRows []DependentRow `json:"dependentRows"`
+	// Dropped indica se as linhas dependentes foram descartadas na solução (WithDropRedundantConstraints).
+	Dropped bool `json:"dropped"`
+}
+
+// consistent indica se todas as linhas dependentes têm lado direito consistente.
+func (d *RankDiagnosis) consistent() bool {
+	for _, row := range d.DependentRows {
+		if !row.Consistent {
+			return false
+		}
+	}
+	return true
+}
+
+// RankDeficiencyError é o erro retornado quando as restrições são linearmente dependentes e
+// não podem ser descartadas, seja porque o descarte não foi solicitado, seja porque o lado
+// direito das linhas dependentes é inconsistente.
+type RankDeficiencyError struct {
+	Diagnosis *RankDiagnosis
+}
+
+func (e *RankDeficiencyError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "as restrições são linearmente dependentes (posto %d)", e.Diagnosis.Rank)
+	for _, row := range e.Diagnosis.DependentRows {
+		rows := make([]int, len(row.Combination))
+		for k, term := range row.Combination {
+			rows[k] = term.Row
+		}
+		fmt.Fprintf(&b, "; a linha %d é combinação das linhas %v, com as variáveis %v", row.Row, rows, row.Variables)
+		if !row.Consistent {
+			b.WriteString(", e o seu lado direito é inconsistente")
+		}
+	}
+	return b.String()
+}
+
+// diagnoseRank identifica as linhas de B que são combinação linear das anteriores, por uma
+// fatoração QR das linhas na ordem em que aparecem (Gram-Schmidt modificado com
+// reortogonalização). Cada linha independente entra na base ortonormal; uma linha cuja
+// componente fora da base é desprezível (dependencyTolerance) é dependente, e a sua
+// combinação é obtida por mínimos quadrados sobre as linhas independentes.
+func diagnoseRank(constraints *mat.Dense, rhs []float64) *RankDiagnosis {
+	numConstraints, numVariables := constraints.Dims()
+	diagnosis := &RankDiagnosis{}
+	var basis [][]float64
+	var independent []int
+	var dependent []int
+	for k := 0; k < numConstraints; k++ {
+		row := append([]float64(nil), constraints.RawRowView(k)...)
+		norm := floats.Norm(row, 2)
+		for pass := 0; pass < 2; pass++ {
+			for _, q := range basis {
+				floats.AddScaled(row, -floats.Dot(q, row), q)
+			}
+		}
+		remainder := floats.Norm(row, 2)
+		if norm == 0 || remainder <= dependencyTolerance*norm {
+			dependent = append(dependent, k)
+			continue
+		}
+		floats.Scale(1/remainder, row)
+		basis = append(basis, row)
+		independent = append(independent, k)
+	}
+	diagnosis.Rank = len(independent)
+
+	// B_I^T * coeficientes = B_k^T, resolvido por QR sobre as linhas independentes.
+	var qr mat.QR
+	if len(independent) > 0 {
+		independentT := mat.NewDense(numVariables, len(independent), nil)
+		for l, j := range independent {
+			for i, v := range constraints.RawRowView(j) {
+				independentT.Set(i, l, v)
+			}
+		}
+		qr.Factorize(independentT)
+	}
+
+	for _, k := range dependent {
+		entry := DependentRow{Row: k, Combination: []RowTerm{}}
+		involved := make(map[int]bool)
+		markVariables := func(row int) {
+			for i, v := range constraints.RawRowView(row) {
+				if v != 0 {
+					involved[i] = true
+				}
+			}
+		}
+		markVariables(k)
+
+		combined := 0.0
+		if len(independent) > 0 {
+			var coefficients mat.Dense
+			if err := qr.SolveTo(&coefficients, false, mat.NewDense(numVariables, 1, append([]float64(nil), constraints.RawRowView(k)...))); err == nil {
+				largest := 0.0
+				for l := range independent {
+					largest = math.Max(largest, math.Abs(coefficients.At(l, 0)))
+				}
+				for l, j := range independent {
+					c := coefficients.At(l, 0)
+					if math.Abs(c) <= rankTolerance*largest {
+						continue
+					}
+					entry.Combination = append(entry.Combination, RowTerm{Row: j, Coefficient: c})
+					markVariables(j)
+					if rhs != nil {
+						combined += c * rhs[j]
+					}
+				}
+			}
+		}
+
+		value := 0.0
+		if rhs != nil {
+			value = rhs[k]
+		}
+		entry.Consistent = math.Abs(value-combined) <= 1e-8*(1+math.Abs(value)+math.Abs(combined))
+
+		for i := range involved {
+			entry.Variables = append(entry.Variables, i)
+		}
+		sort.Ints(entry.Variables)
+		diagnosis.DependentRows = append(diagnosis.DependentRows, entry)
+	}
+	return diagnosis
+}
+
+// diagnoseSolvedRank diagnostica o posto das restrições como elas são resolvidas: com as
+// variáveis fixas levadas ao lado direito e sem as linhas que só as envolvem (ver
+// fixVariables). Fixar uma variável pode tornar dependentes linhas que não o eram em B. As
+// variáveis não medidas não precisam ser eliminadas antes: se as restrições têm posto
+// completo, as restrições reduzidas pela projeção também têm. Os índices das linhas do
+// diagnóstico são os das restrições originais.
+func diagnoseSolvedRank(measurements, tolerances []float64, constraints *mat.Dense, o options) (*RankDiagnosis, error) {
+	fixed := make(map[int]bool)
+	for i := range measurements {
+		if !o.unmeasured[i] && o.isFixed(i, tolerances[i]) {
+			fixed[i] = true
+		}
+	}
+	if len(fixed) == 0 {
+		return diagnoseRank(constraints, o.rhs), nil
+	}
+	free, rhs, dropped, err := fixVariables(measurements, constraints, fixed, o)
+	if err != nil {
+		return nil, err
+	}
+	numConstraints, _ := constraints.Dims()
+	var kept []int
+	for k := 0; k < numConstraints; k++ {
+		if !dropped[k] {
+			kept = append(kept, k)
+		}
+	}
+	keptConstraints, keptRHS := withoutRows(free, rhs, dropped)
+	diagnosis := diagnoseRank(keptConstraints, keptRHS)
+	for d := range diagnosis.DependentRows {
+		row := &diagnosis.DependentRows[d]
+		row.Row = kept[row.Row]
+		for t := range row.Combination {
+			row.Combination[t].Row = kept[row.Combination[t].Row]
+		}
+	}
+	return diagnosis, nil
+}
+
+// withoutRows retorna as restrições e o lado direito sem as linhas descartadas. Sem linhas
+// descartadas, retorna as próprias restrições e o próprio lado direito.
+func withoutRows(constraints *mat.Dense, rhs []float64, dropped map[int]bool) (*mat.Dense, []float64) {
+	if len(dropped) == 0 {
+		return constraints, rhs
+	}
+	numConstraints, numVariables := constraints.Dims()
+	kept := mat.NewDense(numConstraints-len(dropped), numVariables, nil)
+	var keptRHS []float64
+	k := 0
+	for row := 0; row < numConstraints; row++ {
+		if dropped[row] {
+			continue
+		}
+		kept.SetRow(k, constraints.RawRowView(row))
+		if rhs != nil {
+			keptRHS = append(keptRHS, rhs[row])
+		}
+		k++
+	}
+	return kept, keptRHS
+}
+
+// estimateInverseNorm1 estima a norma 1 da inversa de uma matriz simétrica de ordem n, a
+// partir de uma função que resolve sistemas com ela, pelo método de Hager. Junto com a norma
+// 1 da própria matriz, fornece uma estimativa do número de condição sem formar a inversa.
+func estimateInverseNorm1(n int, solve func([]float64) []float64) float64 {
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = 1 / float64(n)
+	}
+	estimate := 0.0
+	for iteration := 0; iteration < 5; iteration++ {
+		y := solve(x)
+		estimate = floats.Norm(y, 1)
+		sign := make([]float64, n)
+		for i, v := range y {
+			sign[i] = 1
+			if v < 0 {
+				sign[i] = -1
+			}
+		}
+		z := solve(sign)
+		j := floats.MaxIdx(absolute(z))
+		if math.Abs(z[j]) <= floats.Dot(z, x) {
+			break
+		}
+		for i := range x {
+			x[i] = 0
+		}
+		x[j] = 1
+	}
+	return estimate
+}
+
+// absolute retorna um novo slice com os valores absolutos de v.
+func absolute(v []float64) []float64 {
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = math.Abs(x)
+	}
+	return out
+}
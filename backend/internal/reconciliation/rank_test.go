@@ -0,0 +1,127 @@
+package reconciliation
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestRankDeficiency(t *testing.T) {
+	// Balanços das unidades A (x1 = x2 + x3) e B (x3 = x4) e o balanço da planta inteira
+	// (x1 = x2 + x4), que é a soma dos dois primeiros.
+	constraints := mat.NewDense(3, 4, []float64{
+		1, -1, -1, 0,
+		0, 0, 1, -1,
+		1, -1, 0, -1,
+	})
+	measurements := []float64{100, 61, 41, 38}
+	tolerances := []float64{1, 1, 1, 1}
+
+	t.Run("Linhas Dependentes", func(t *testing.T) {
+		_, err := Reconcile(measurements, tolerances, constraints, WithUncertaintyMode(UncertaintyAbsolute))
+		var rankErr *RankDeficiencyError
+		if !errors.As(err, &rankErr) {
+			t.Fatalf("Esperava-se um *RankDeficiencyError, obtido: %v", err)
+		}
+		diagnosis := rankErr.Diagnosis
+		if diagnosis.Rank != 2 || len(diagnosis.DependentRows) != 1 {
+			t.Fatalf("Diagnóstico incorreto: %+v", diagnosis)
+		}
+		row := diagnosis.DependentRows[0]
+		if row.Row != 2 || len(row.Combination) != 2 || !row.Consistent {
+			t.Fatalf("Linha dependente incorreta: %+v", row)
+		}
+		for _, term := range row.Combination {
+			if math.Abs(term.Coefficient-1) > 1e-9 {
+				t.Errorf("O balanço da planta deveria ser a soma dos balanços das unidades: %+v", row.Combination)
+			}
+		}
+		if len(row.Variables) != 4 {
+			t.Errorf("Todas as variáveis participam da dependência: %v", row.Variables)
+		}
+	})
+
+	t.Run("Descarte Automático", func(t *testing.T) {
+		result, err := Reconcile(measurements, tolerances, constraints, WithUncertaintyMode(UncertaintyAbsolute), WithDropRedundantConstraints())
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		expected, err := Reconcile(measurements, tolerances, mat.DenseCopyOf(constraints.Slice(0, 2, 0, 4)), WithUncertaintyMode(UncertaintyAbsolute))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, expected.Reconciled, 1e-9) {
+			t.Errorf("O descarte deveria reproduzir as linhas independentes.\nEsperado: %v\nObtido:   %v", expected.Reconciled, result.Reconciled)
+		}
+		if len(result.Lambda) != 3 || result.Lambda[2] != 0 || len(result.ResidualsAfter) != 3 || math.Abs(result.ResidualsAfter[2]) > 1e-9 {
+			t.Errorf("A linha descartada deveria ter multiplicador nulo e resíduo nulo: λ %v, resíduos %v", result.Lambda, result.ResidualsAfter)
+		}
+		if result.RankDiagnosis == nil || !result.RankDiagnosis.Dropped || result.GlobalTest.DegreesOfFreedom != 2 {
+			t.Errorf("O descarte deveria ser informado e o teste global ter 2 graus de liberdade: %+v", result.RankDiagnosis)
+		}
+	})
+
+	t.Run("Dependência Criada por Variável Fixa", func(t *testing.T) {
+		// A linha 2 tem o desvio x3, fechado (zero exato): com x3 fixa, ela passa a ser a soma
+		// das linhas 0 e 1, embora B tenha posto completo.
+		bypass := mat.NewDense(3, 4, []float64{
+			1, -1, 0, 0,
+			0, 1, -1, 0,
+			1, 0, -1, 1,
+		})
+		values := []float64{100, 99, 101, 0}
+		relative := []float64{0.01, 0.01, 0.01, 0.01}
+		_, err := Reconcile(values, relative, bypass, WithFixed([]int{3}))
+		var rankErr *RankDeficiencyError
+		if !errors.As(err, &rankErr) {
+			t.Fatalf("Esperava-se um *RankDeficiencyError, obtido: %v", err)
+		}
+		row := rankErr.Diagnosis.DependentRows[0]
+		if len(rankErr.Diagnosis.DependentRows) != 1 || row.Row != 2 || len(row.Combination) != 2 || !row.Consistent {
+			t.Fatalf("Diagnóstico incorreto: %+v", rankErr.Diagnosis)
+		}
+
+		result, err := Reconcile(values, relative, bypass, WithFixed([]int{3}), WithDropRedundantConstraints())
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		expected, err := Reconcile(values, relative, mat.DenseCopyOf(bypass.Slice(0, 2, 0, 4)), WithFixed([]int{3}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, expected.Reconciled, 1e-9) || result.Lambda[2] != 0 {
+			t.Errorf("O descarte deveria reproduzir as linhas independentes.\nEsperado: %v\nObtido:   %v", expected.Reconciled, result.Reconciled)
+		}
+	})
+
+	t.Run("Lado Direito Inconsistente", func(t *testing.T) {
+		_, err := Reconcile(measurements, tolerances, constraints, WithUncertaintyMode(UncertaintyAbsolute),
+			WithRHS([]float64{0, 0, 5}), WithDropRedundantConstraints())
+		var rankErr *RankDeficiencyError
+		if !errors.As(err, &rankErr) || rankErr.Diagnosis.DependentRows[0].Consistent {
+			t.Fatalf("Restrições contraditórias não deveriam ser descartadas, obtido: %v", err)
+		}
+	})
+
+	t.Run("Número de Condição", func(t *testing.T) {
+		result, err := Reconcile(measurements, tolerances, mat.DenseCopyOf(constraints.Slice(0, 2, 0, 4)), WithUncertaintyMode(UncertaintyAbsolute))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if result.ConditionNumber < 1 || result.IllConditioned {
+			t.Errorf("Restrições bem condicionadas: %v", result.ConditionNumber)
+		}
+
+		// Duas restrições quase paralelas podem ser resolvidas, mas são sinalizadas.
+		nearlyParallel := mat.NewDense(2, 3, []float64{1, -1, 0, 1, -1, 1e-4})
+		result, err = Reconcile([]float64{10, 9, 1}, []float64{1, 1, 1}, nearlyParallel, WithUncertaintyMode(UncertaintyAbsolute))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !result.IllConditioned {
+			t.Errorf("Restrições quase paralelas deveriam ser sinalizadas: %v", result.ConditionNumber)
+		}
+	})
+}
@@ -6,9 +6,89 @@ package reconciliation
 import (
 	"errors"
 	"fmt"
+	"math"
+
 	"gonum.org/v1/gonum/mat"
 )
 
+// ErrDimensionMismatch é o erro base das entradas de dimensões incompatíveis entre si (ex:
+// medições e tolerâncias de tamanhos diferentes), identificável por errors.Is.
+var ErrDimensionMismatch = errors.New("incompatibilidade de dimensão")
+
+// ErrInvalidInput é o erro base das demais entradas inválidas (ex: opções desconhecidas,
+// limites invertidos ou covariância que não é definida positiva), identificável por
+// errors.Is. As falhas da solução não o envolvem.
+var ErrInvalidInput = errors.New("entrada inválida")
+
+// Result reúne a solução completa do problema de reconciliação, e não apenas o vetor
+// reconciliado. Os campos permitem avaliar quanto cada medidor foi corrigido e quanto
+// a precisão melhorou após a reconciliação.
+type Result struct {
+	// Reconciled é o vetor dos valores reconciliados (x). Variáveis não observáveis não têm
+	// estimativa (NaN, null em JSON).
+	Reconciled Values `json:"reconciled"`
+	// Adjustments são os ajustes aplicados a cada medição (a = x - m). Variáveis não medidas
+	// não têm ajuste (NaN, null em JSON).
+	Adjustments Values `json:"adjustments"`
+	// StandardizedAdjustments são os ajustes divididos pelo seu desvio padrão (a_i / sqrt(Cov(a)_ii)).
+	// Medições não redundantes, cujo ajuste tem variância nula, e variáveis não medidas recebem zero.
+	StandardizedAdjustments []float64 `json:"standardizedAdjustments"`
+	// Lambda é o vetor dos multiplicadores de Lagrange (λ), um por restrição.
+	Lambda []float64 `json:"lambda"`
+	// Objective é o valor da função objetivo ponderada, (x - m)^T * W * (x - m).
+	Objective float64 `json:"objective"`
+	// Deviations são os desvios padrão efetivos das medições (σ) usados para construir W,
+	// já convertidos pelo modo de incerteza e pelo fator de abrangência de cada medição.
+	// Variáveis não medidas não têm desvio padrão (NaN, null em JSON).
+	Deviations Values `json:"deviations"`
+	// ReconciledDeviations são os desvios padrão das estimativas reconciliadas (sqrt(Cov(x)_ii)).
+	ReconciledDeviations Values `json:"reconciledDeviations"`
+	// Covariance é a matriz de covariância das estimativas reconciliadas, Cov(x). As linhas e
+	// colunas das variáveis não observáveis são NaN (null em JSON). É nula quando dispensada
+	// por WithReconciledCovariance(false) ou, por padrão, com WithSolver(SolverSparse).
+	Covariance []Values `json:"covariance"`
+	// ResidualsBefore são os resíduos das restrições avaliados nas medições (B*m - c).
+	// Restrições que envolvem variáveis não medidas não têm resíduo (NaN, null em JSON).
+	ResidualsBefore Values `json:"residualsBefore"`
+	// ResidualsAfter são os resíduos das restrições avaliados nos valores reconciliados (B*x - c).
+	// Restrições que envolvem variáveis não observáveis não têm resíduo (NaN, null em JSON).
+	ResidualsAfter Values `json:"residualsAfter"`
+	// GlobalTest é o resultado do teste global qui-quadrado sobre a função objetivo.
+	// É nulo quando o problema não tem redundância (nenhum grau de liberdade).
+	GlobalTest *GlobalTestResult `json:"globalTest"`
+	// MeasurementTest é o resultado do teste das medidas sobre os ajustes padronizados.
+	MeasurementTest *MeasurementTestResult `json:"measurementTest"`
+	// NodalTest é o resultado do teste nodal sobre os resíduos das restrições.
+	NodalTest *NodalTestResult `json:"nodalTest"`
+	// Classification é a classificação de cada variável quanto à redundância (medidas) e à
+	// observabilidade (não medidas).
+	Classification []VariableClass `json:"classification"`
+	// ActiveBounds são os limites ativos na solução, presentes apenas quando há limites (WithBounds).
+	ActiveBounds []ActiveBound `json:"activeBounds,omitempty"`
+	// ConditionNumber é a estimativa do número de condição (norma 1) do sistema de Lagrange
+	// reduzido ao complemento de Schur B*V*B^T, cuja fatoração fornece a solução. É zero
+	// quando não há restrições reduzidas.
+	ConditionNumber float64 `json:"conditionNumber"`
+	// IllConditioned sinaliza um número de condição elevado: a solução foi calculada, mas
+	// pode ter perdido dígitos significativos. Acima de um limite ainda maior, as restrições
+	// são tratadas como dependentes.
+	IllConditioned bool `json:"illConditioned"`
+	// Robust descreve a solução por um estimador robusto, presente apenas quando solicitado (WithEstimator).
+	Robust *RobustResult `json:"robust,omitempty"`
+	// RankDiagnosis descreve as linhas de restrição dependentes que foram descartadas,
+	// presente apenas quando houve descarte (WithDropRedundantConstraints).
+	RankDiagnosis *RankDiagnosis `json:"rankDiagnosis,omitempty"`
+	// Screening é o resultado da triagem das medições, com os sinais de qualidade de cada
+	// variável, presente apenas quando informado (WithScreening).
+	Screening *ScreeningResult `json:"screening,omitempty"`
+
+	// boundMultipliers são os multiplicadores das linhas dos limites ativos, na ordem de o.active.
+	boundMultipliers []float64
+	// residualVariances são as variâncias dos resíduos das restrições, diag(B*V*B^T), usadas
+	// pelo teste nodal.
+	residualVariances []float64
+}
+
 // Reconcile ajusta os valores medidos para que obedeçam às equações de restrição,
 // utilizando o método dos multiplicadores de Lagrange para minimizar o erro quadrático ponderado.
 //
@@ -16,100 +96,503 @@ import (
 //
 // | W   B^T | | x |   | W*m |
 // |         | |   | = |     |
-// | B    0  | | λ |   |  0  |
+// | B    0  | | λ |   |  c  |
 //
 // Onde:
 // - x: vetor dos valores reconciliados (o que queremos encontrar).
 // - m: vetor dos valores medidos.
 // - W: matriz de pesos, diagonal, com W_ii = 1 / σ_i^2, onde σ_i é o desvio padrão da medição i.
-// - B: matriz de restrições, onde cada linha representa uma equação de restrição (B*x = 0).
+// - B: matriz de restrições, onde cada linha representa uma equação de restrição (B*x = c).
+// - c: vetor do lado direito das restrições, com os termos fixos conhecidos (zero por padrão, ver WithRHS).
 // - λ: vetor dos multiplicadores de Lagrange.
 //
 // Parâmetros:
-//   - measurements: Um slice de float64 representando os valores medidos (m).
-//   - tolerances: Um slice de float64 representando as tolerâncias percentuais (p), usadas para calcular os desvios padrão.
+//   - measurements: Um slice de float64 representando os valores medidos (m). Medições NaN ou
+//     ±Inf são rejeitadas, a menos que a variável seja não medida (ver WithMissing).
+//   - tolerances: Um slice de float64 representando as tolerâncias (p), usadas para calcular os desvios padrão.
+//     Por padrão são tolerâncias relativas; ver WithUncertaintyMode e WithUncertainties para os outros modos.
 //   - constraints: Uma matriz densa (*mat.Dense) representando as equações de restrição (B).
+//   - opts: Opções adicionais, como o nível de confiança dos testes estatísticos (WithConfidence).
 //
 // Retorna:
-//   - Um slice de float64 com os valores reconciliados (x).
+//   - Um *Result com os valores reconciliados (x), os multiplicadores (λ) e as estatísticas da solução.
 //   - Um erro se os cálculos falharem (ex: matriz singular, dimensões incompatíveis).
-func Reconcile(measurements, tolerances []float64, constraints *mat.Dense) ([]float64, error) {
+//
+// O sistema não é invertido: eliminando x, resta o complemento de Schur B*V*B^T, com V = W^-1,
+// que é fatorado por Cholesky (denso, ou esparso para restrições grandes e esparsas; ver
+// WithSolver). A mesma fatoração fornece a covariância das estimativas,
+// Cov(x) = V - V*B^T*(B*V*B^T)^-1*B*V, e a covariância dos ajustes é Cov(a) = V - Cov(x).
+//
+// Variáveis não medidas (WithUnmeasured) são eliminadas das restrições por uma matriz de
+// projeção P antes da solução acima, que passa a usar as restrições reduzidas A = P*B_M.
+// Em seguida, as não medidas observáveis são estimadas a partir dos valores reconciliados.
+//
+// Variáveis de tolerância zero ou marcadas por WithFixed são valores exatos: os seus termos
+// vão para o lado direito das restrições, as demais são reconciliadas em torno delas, e elas
+// são retornadas sem ajuste, com desvio padrão zero e a classificação ClassFixed.
+func Reconcile(measurements, tolerances []float64, constraints *mat.Dense, opts ...Option) (*Result, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	numMeasurements := len(measurements)
 	if numMeasurements == 0 {
-		return nil, errors.New("o slice de medições não pode estar vazio")
+		return nil, fmt.Errorf("%w: o slice de medições não pode estar vazio", ErrInvalidInput)
 	}
 	if len(tolerances) != numMeasurements {
-		return nil, fmt.Errorf("incompatibilidade de dimensão: medições (%d) e tolerâncias (%d)", numMeasurements, len(tolerances))
+		return nil, fmt.Errorf("%w: medições (%d) e tolerâncias (%d)", ErrDimensionMismatch, numMeasurements, len(tolerances))
 	}
 
 	numConstraints, cCols := constraints.Dims()
 	if cCols != numMeasurements {
-		return nil, fmt.Errorf("incompatibilidade de dimensão: colunas das restrições (%d) e medições (%d)", cCols, numMeasurements)
+		return nil, fmt.Errorf("%w: colunas das restrições (%d) e medições (%d)", ErrDimensionMismatch, cCols, numMeasurements)
+	}
+	if o.rhs != nil && len(o.rhs) != numConstraints {
+		return nil, fmt.Errorf("%w: linhas das restrições (%d) e lado direito (%d)", ErrDimensionMismatch, numConstraints, len(o.rhs))
+	}
+	if o.uncertainties != nil && len(o.uncertainties) != numMeasurements {
+		return nil, fmt.Errorf("%w: medições (%d) e incertezas (%d)", ErrDimensionMismatch, numMeasurements, len(o.uncertainties))
+	}
+	if err := validateCovariance(o, numMeasurements); err != nil {
+		return nil, err
+	}
+	for i := range o.unmeasured {
+		if i < 0 || i >= numMeasurements {
+			return nil, fmt.Errorf("%w: índice de variável não medida fora do intervalo: %d", ErrInvalidInput, i)
+		}
+	}
+	if err := resolveMissing(measurements, &o); err != nil {
+		return nil, err
+	}
+	if err := applyScreening(&o, numMeasurements); err != nil {
+		return nil, err
+	}
+	for i := range o.fixed {
+		if i < 0 || i >= numMeasurements {
+			return nil, fmt.Errorf("%w: índice de variável fixa fora do intervalo: %d", ErrInvalidInput, i)
+		}
+		if o.unmeasured[i] {
+			return nil, fmt.Errorf("%w: a variável %d não pode ser fixa e não medida ao mesmo tempo", ErrInvalidInput, i)
+		}
+	}
+	if err := validateBounds(o, numMeasurements); err != nil {
+		return nil, err
+	}
+
+	result, err := dispatch(measurements, tolerances, constraints, o)
+	if !errors.Is(err, errSingular) {
+		return result, err
 	}
 
-	// Calcula os desvios padrão absolutos (σ_i = m_i * p_i)
-	// Assume-se que a tolerância é o desvio padrão relativo.
-	absDeviations := make([]float64, numMeasurements)
+	// Restrições singulares: identifica as linhas dependentes e as variáveis envolvidas nas
+	// restrições resolvidas, já sem as variáveis fixas. Se não houver dependência exata, o
+	// problema é apenas mal condicionado.
+	diagnosis, diagnosisErr := diagnoseSolvedRank(measurements, tolerances, constraints, o)
+	if diagnosisErr != nil || len(diagnosis.DependentRows) == 0 {
+		return nil, err
+	}
+	if !o.dropRedundant || !diagnosis.consistent() {
+		return nil, &RankDeficiencyError{Diagnosis: diagnosis}
+	}
+	o.dropped = make(map[int]bool, len(diagnosis.DependentRows))
+	for _, row := range diagnosis.DependentRows {
+		o.dropped[row.Row] = true
+	}
+	if result, err = dispatch(measurements, tolerances, constraints, o); err != nil {
+		return nil, err
+	}
+	diagnosis.Dropped = true
+	result.RankDiagnosis = diagnosis
+	return result, nil
+}
+
+// dispatch resolve o problema com as opções já validadas, com ou sem limites, por mínimos
+// quadrados ou por um estimador robusto.
+func dispatch(measurements, tolerances []float64, constraints *mat.Dense, o options) (*Result, error) {
+	if o.estimator != EstimatorLeastSquares && o.robustWeights == nil {
+		return reconcileRobust(measurements, tolerances, constraints, o)
+	}
+	// Com limites, o problema passa a ser um problema quadrático com desigualdades.
+	if o.lower != nil || o.upper != nil {
+		return reconcileBounded(measurements, tolerances, constraints, o)
+	}
+	return reconcile(measurements, tolerances, constraints, o)
+}
+
+// reconcile resolve o problema de reconciliação com as opções já validadas. Os limites ativos
+// (o.active) são tratados como restrições de igualdade adicionais, x_i = limite, anexadas a B.
+func reconcile(measurements, tolerances []float64, constraints *mat.Dense, o options) (*Result, error) {
+	var err error
+	numMeasurements := len(measurements)
+	numConstraints, _ := constraints.Dims()
+
+	// Calcula os desvios padrão absolutos de acordo com o modo de incerteza de cada medição.
+	// No modo padrão (relativo), σ_i = |m_i| * p_i. Com uma matriz de covariância completa,
+	// σ_i = sqrt(V_ii) e as tolerâncias são ignoradas.
+	// Variáveis não medidas não têm medição nem desvio padrão (NaN); variáveis fixas têm desvio zero.
+	absDeviations := make(Values, numMeasurements)
+	values := make(Values, numMeasurements)
+	fixed := make(map[int]bool)
 	for i := 0; i < numMeasurements; i++ {
-		absDeviations[i] = measurements[i] * tolerances[i]
-		if absDeviations[i] == 0 {
-			// Evita divisão por zero ao construir a matriz de pesos.
-			return nil, fmt.Errorf("a tolerância absoluta para a medição %d é zero, causando divisão por zero", i)
+		if o.unmeasured[i] {
+			absDeviations[i], values[i] = math.NaN(), math.NaN()
+			continue
+		}
+		values[i] = measurements[i]
+		if o.isFixed(i, tolerances[i]) {
+			fixed[i] = true
+			continue
+		}
+		if o.covariance != nil {
+			absDeviations[i] = math.Sqrt(o.covariance.At(i, i))
+			continue
+		}
+		if absDeviations[i], err = deviation(i, measurements[i], tolerances[i], o.uncertainty(i)); err != nil {
+			return nil, err
+		}
+	}
+	// Os pesos robustos (ver reconcileRobust) inflam o desvio padrão das medições suspeitas.
+	for i := 0; o.robustWeights != nil && i < numMeasurements; i++ {
+		absDeviations[i] /= math.Sqrt(o.robustWeights[i])
+	}
+	// As medições rebaixadas pela triagem (WithScreening) têm o desvio padrão multiplicado.
+	for i := 0; o.inflation != nil && i < numMeasurements; i++ {
+		absDeviations[i] *= o.inflation[i]
+	}
+
+	// Leva as variáveis fixas ao lado direito das restrições (ver fixVariables).
+	if len(fixed) > 0 {
+		if constraints, o.rhs, o.dropped, err = fixVariables(measurements, constraints, fixed, o); err != nil {
+			return nil, err
 		}
 	}
 
-	// Constrói a matriz aumentada do sistema de Lagrange (Matriz 'Peso' no código original).
-	// Esta é uma matriz de bloco no formato:
-	// [ W   B^T ]
-	// [ B    0  ]
-	// O fator de 2 foi removido da formulação original para simplicidade, pois ele se cancela.
-	totalDim := numMeasurements + numConstraints
-	lagrangeMatrix := mat.NewDense(totalDim, totalDim, nil)
+	// As restrições por linhas servem aos resíduos, ao teste nodal e ao método esparso.
+	sparseConstraints := newSparseRows(constraints)
 
-	// Bloco superior esquerdo: Matriz de Pesos (W), com W_ii = 1 / σ_i^2
-	weightsData := make([]float64, numMeasurements)
-	for i := 0; i < numMeasurements; i++ {
-		weightsData[i] = 1 / (absDeviations[i] * absDeviations[i])
+	// Descarta as linhas dependentes (WithDropRedundantConstraints) e anexa os limites ativos
+	// às restrições. As restrições resolvidas são as linhas mantidas de B (e c) seguidas de
+	// uma linha por limite ativo.
+	keptConstraints, keptRHS := withoutRows(constraints, o.rhs, o.dropped)
+	numKept, _ := keptConstraints.Dims()
+	solveConstraints, solveRHS := withActiveBounds(keptConstraints, keptRHS, o.active)
+	numSolve, _ := solveConstraints.Dims()
+
+	// Elimina as variáveis não medidas das restrições por projeção (ver projection). Sem
+	// variáveis não medidas, as restrições reduzidas (A) são as próprias restrições (B). Sem
+	// limites ativos, a projeção só depende das restrições e pode ser compartilhada (ReconcileBatch).
+	var proj *projection
+	shared := o.batch != nil && len(o.active) == 0
+	pattern := ""
+	if shared {
+		pattern = patternKey(o.unmeasured, fixed, o.dropped)
+		proj = o.batch.projection(pattern, solveConstraints, o.unmeasured, fixed)
+	} else {
+		proj = newProjection(solveConstraints, o.unmeasured, fixed)
 	}
-	weightsMatrix := mat.NewDiagDense(numMeasurements, weightsData)
-	lagrangeMatrix.Slice(0, numMeasurements, 0, numMeasurements).(*mat.Dense).Copy(weightsMatrix)
+	numMeasured := len(proj.measured)
+	if numMeasured == 0 {
+		return nil, fmt.Errorf("%w: ao menos uma variável deve ser medida e não fixa", ErrInvalidInput)
+	}
+	numReduced := proj.rows()
 
-	// Bloco superior direito: Transposta da matriz de restrições (B^T)
-	lagrangeMatrix.Slice(0, numMeasurements, numMeasurements, totalDim).(*mat.Dense).Copy(constraints.T())
+	// Constrói a matriz de pesos (W) e a covariância (V) das variáveis medidas.
+	weights, measuredCov, err := weightMatrix(o, absDeviations, proj.measured)
+	if err != nil {
+		return nil, err
+	}
 
-	// Bloco inferior esquerdo: Matriz de restrições (B)
-	lagrangeMatrix.Slice(numMeasurements, totalDim, 0, numMeasurements).(*mat.Dense).Copy(constraints)
+	// Resolve o sistema de Lagrange sobre as variáveis medidas e as restrições reduzidas,
+	// com o lado direito projetado (zero se omitido), pela fatoração do complemento de Schur
+	// (ver solveKKT). As variáveis não medidas precisam da covariância completa de x_M. Num
+	// lote, a fatoração é compartilhada entre os conjuntos com os mesmos pesos.
+	measuredValues := make([]float64, numMeasured)
+	for k, i := range proj.measured {
+		measuredValues[k] = measurements[i]
+	}
+	full := !o.omitsCovariance() || len(proj.unmeasured) > 0
+	if o.solver == SolverSparse && len(proj.unmeasured) > 0 {
+		return nil, fmt.Errorf("%w: o método esparso não admite variáveis não medidas", ErrInvalidInput)
+	}
+	// Sem variáveis não medidas, A são as colunas medidas das restrições resolvidas, montadas
+	// por colunas a partir das restrições por linhas.
+	var sparseReduced *sparseMatrix
+	if o.solver != SolverDense && len(proj.unmeasured) == 0 && proj.reduced != nil {
+		sparseReduced = sparseConstraints.reduced(o.dropped, o.active, proj.measured)
+	}
+	var sol *kktSolution
+	if shared && proj.reduced != nil {
+		var factor *schurFactor
+		if factor, err = o.batch.factor(pattern, proj.reduced, sparseReduced, measuredCov, o.solver); err != nil {
+			return nil, err
+		}
+		sol, err = factor.solve(proj.reduced, measuredCov, measuredValues, proj.project(solveRHS), full)
+	} else {
+		sol, err = solveKKT(proj.reduced, sparseReduced, measuredCov, measuredValues, proj.project(solveRHS), full, o.solver)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	// Constrói o vetor do lado direito do sistema de equações (RHS).
-	// [ W*m ]
-	// [  0  ]
-	rhsData := make([]float64, totalDim)
-	for i := 0; i < numMeasurements; i++ {
-		// (W*m)_i = (1 / σ_i^2) * m_i
-		rhsData[i] = weightsData[i] * measurements[i]
+	// Distribui os valores reconciliados das variáveis medidas (x_M) e Cov(x_M) pelas posições
+	// das variáveis. Sem variáveis não medidas nem fixas, as linhas da covariância compartilham
+	// a memória da solução. As variáveis fixas mantêm o seu valor, com variância zero.
+	reconciled := make(Values, numMeasurements)
+	variances := make([]float64, numMeasurements)
+	var covariance []Values
+	if full {
+		covariance = make([]Values, numMeasurements)
+	}
+	xMeasured := sol.x
+	for k, i := range proj.measured {
+		reconciled[i] = xMeasured[k]
+		variances[i] = sol.variances[k]
+		if !full {
+			continue
+		}
+		row := Values(sol.covariance[k*numMeasured : (k+1)*numMeasured])
+		if numMeasured == numMeasurements {
+			covariance[i] = row
+			continue
+		}
+		covariance[i] = make(Values, numMeasurements)
+		for l, j := range proj.measured {
+			covariance[i][j] = row[l]
+		}
+	}
+	for i := range fixed {
+		reconciled[i] = measurements[i]
+		if full {
+			covariance[i] = make(Values, numMeasurements)
+		}
 	}
-	// A parte inferior do vetor (correspondente às restrições) é zero.
-	rhsVec := mat.NewVecDense(totalDim, rhsData)
 
-	// Resolve o sistema de equações lineares: lagrangeMatrix * resultVec = rhsVec
-	// A solução é: resultVec = inv(lagrangeMatrix) * rhsVec
-	var invLagrange mat.Dense
-	if err := invLagrange.Inverse(lagrangeMatrix); err != nil {
-		// Este erro ocorre se a matriz for singular, o que pode acontecer se as restrições
-		// forem linearmente dependentes ou se o sistema for mal condicionado.
-		return nil, errors.New("a matriz de lagrange é singular e não pode ser invertida, verifique as restrições")
+	// Estima as variáveis não medidas por x_U = B_U^+ * (c - B_M*x_M). Com K = -B_U^+ * B_M,
+	// as covariâncias são Cov(x_U, x_M) = K*Cov(x_M) e Cov(x_U) = K*Cov(x_M)*K^T. Variáveis
+	// não observáveis não têm estimativa nem covariância (NaN).
+	if len(proj.unmeasured) > 0 {
+		balance := make([]float64, numSolve)
+		copy(balance, solveRHS)
+		var measuredTerm mat.VecDense
+		measuredTerm.MulVec(proj.bMeasured, mat.NewVecDense(numMeasured, xMeasured))
+		for k := range balance {
+			balance[k] -= measuredTerm.AtVec(k)
+		}
+		var xUnmeasured mat.VecDense
+		xUnmeasured.MulVec(proj.pinv, mat.NewVecDense(numSolve, balance))
+
+		var gain, crossCov, unmeasuredCov mat.Dense
+		gain.Mul(proj.pinv, proj.bMeasured)
+		gain.Scale(-1, &gain)
+		crossCov.Mul(&gain, mat.NewDense(numMeasured, numMeasured, sol.covariance))
+		unmeasuredCov.Mul(&crossCov, gain.T())
+
+		for _, i := range proj.unmeasured {
+			covariance[i] = make(Values, numMeasurements)
+		}
+		for a, i := range proj.unmeasured {
+			if !proj.observable[a] {
+				variances[i] = math.NaN()
+				reconciled[i] = math.NaN()
+				for j := range covariance {
+					covariance[i][j], covariance[j][i] = math.NaN(), math.NaN()
+				}
+				continue
+			}
+			reconciled[i] = xUnmeasured.AtVec(a)
+			for l, j := range proj.measured {
+				covariance[i][j], covariance[j][i] = crossCov.At(a, l), crossCov.At(a, l)
+			}
+			for b, j := range proj.unmeasured {
+				if proj.observable[b] {
+					covariance[i][j] = unmeasuredCov.At(a, b)
+				}
+			}
+			variances[i] = unmeasuredCov.At(a, a)
+		}
 	}
 
-	var resultVec mat.VecDense
-	resultVec.MulVec(&invLagrange, rhsVec)
+	// Recupera os multiplicadores de Lagrange das restrições originais, λ = P^T * λ_A. Os
+	// multiplicadores das linhas dos limites ativos ficam no final do vetor.
+	lambda := make([]float64, numSolve)
+	if numReduced > 0 {
+		if proj.p == nil {
+			copy(lambda, sol.lambda)
+		} else {
+			var original mat.VecDense
+			original.MulVec(proj.p.T(), mat.NewVecDense(numReduced, sol.lambda))
+			for k := range lambda {
+				lambda[k] = original.AtVec(k)
+			}
+		}
+	}
 
-	// Extrai os valores reconciliados (x) do vetor de resultado.
-	// O vetor de resultado contém os valores reconciliados (x) nas primeiras `numMeasurements` posições
-	// e os multiplicadores de Lagrange (λ) nas posições restantes.
-	reconciled := make([]float64, numMeasurements)
+	// Calcula os ajustes, a função objetivo, as estatísticas derivadas da covariância e a
+	// classificação de cada variável.
+	adjustments := make(Values, numMeasurements)
+	standardized := make([]float64, numMeasurements)
+	reconciledDeviations := make(Values, numMeasurements)
+	classification := make([]VariableClass, numMeasurements)
 	for i := 0; i < numMeasurements; i++ {
-		reconciled[i] = resultVec.AtVec(i)
+		reconciledDeviations[i] = math.Sqrt(math.Max(variances[i], 0))
+		if math.IsNaN(variances[i]) {
+			reconciledDeviations[i] = math.NaN()
+		}
+
+		// Variáveis não medidas não têm ajuste.
+		if o.unmeasured[i] {
+			adjustments[i] = math.NaN()
+			classification[i] = ClassUnobservable
+			if !math.IsNaN(reconciled[i]) {
+				classification[i] = ClassObservable
+			}
+			continue
+		}
+		adjustments[i] = reconciled[i] - measurements[i]
+		if fixed[i] {
+			classification[i] = ClassFixed
+			continue
+		}
+
+		// Var(a_i) = σ_i^2 - Cov(x)_ii. Valores numericamente nulos indicam uma medição
+		// não redundante, cujo ajuste é sempre zero.
+		classification[i] = ClassNonRedundant
+		adjustmentVariance := absDeviations[i]*absDeviations[i] - variances[i]
+		if adjustmentVariance > varianceEpsilon*absDeviations[i]*absDeviations[i] {
+			standardized[i] = adjustments[i] / math.Sqrt(adjustmentVariance)
+			classification[i] = ClassRedundant
+		}
+	}
+
+	// Calcula a função objetivo ponderada, a_M^T * W * a_M, sobre as variáveis medidas.
+	measuredAdjustments := mat.NewVecDense(numMeasured, nil)
+	for k, i := range proj.measured {
+		measuredAdjustments.SetVec(k, adjustments[i])
 	}
+	var objective float64
+	if diagonal, ok := weights.(*mat.DiagDense); ok {
+		for k := 0; k < numMeasured; k++ {
+			objective += measuredAdjustments.AtVec(k) * measuredAdjustments.AtVec(k) * diagonal.At(k, k)
+		}
+	} else {
+		objective = mat.Inner(measuredAdjustments, weights, measuredAdjustments)
+	}
+
+	// Aplica o teste global sobre a função objetivo, com um grau de liberdade por restrição
+	// reduzida. Sem redundância, não há o que testar.
+	var globalTest *GlobalTestResult
+	if numReduced > 0 {
+		if globalTest, err = GlobalTest(objective, numReduced, o.confidence); err != nil {
+			return nil, err
+		}
+	}
+
+	// Aplica o teste das medidas sobre os ajustes padronizados das medições redundantes, as
+	// únicas cujo ajuste pode revelar um erro.
+	tested := make([]bool, numMeasurements)
+	for i, class := range classification {
+		tested[i] = class == ClassRedundant
+	}
+	measurementTest, err := MeasurementTest(standardized, tested, o.confidence)
+	if err != nil {
+		return nil, err
+	}
+
+	// Aplica o teste nodal sobre os resíduos das restrições, com variâncias diag(B*V*B^T).
+	// Restrições que envolvem variáveis não medidas ficam com variância zero e não são testadas.
+	residualsBefore := constraintResiduals(sparseConstraints, values, o.rhs)
+	residualVariances := make([]float64, numConstraints)
+	if diagonal, ok := measuredCov.(*mat.DiagDense); ok {
+		// Com V diagonal, (B*V*B^T)_kk = Σ_l B_kl^2 * V_ll, só sobre os coeficientes não nulos.
+		position := make([]int, numMeasurements)
+		for i := range position {
+			position[i] = -1
+		}
+		for l, i := range proj.measured {
+			position[i] = l
+		}
+		for k := 0; k < numConstraints; k++ {
+			if math.IsNaN(residualsBefore[k]) {
+				continue
+			}
+			cols, coefficients := sparseConstraints.row(k)
+			for p, j := range cols {
+				if l := position[j]; l >= 0 {
+					residualVariances[k] += coefficients[p] * coefficients[p] * diagonal.At(l, l)
+				}
+			}
+		}
+	} else {
+		nodalConstraints := proj.bMeasured
+		if len(o.dropped) > 0 {
+			nodalConstraints = columns(constraints, proj.measured)
+		}
+		var spread mat.Dense
+		spread.Mul(nodalConstraints, measuredCov)
+		for k := 0; k < numConstraints; k++ {
+			if math.IsNaN(residualsBefore[k]) {
+				continue
+			}
+			for l := 0; l < numMeasured; l++ {
+				residualVariances[k] += spread.At(k, l) * nodalConstraints.At(k, l)
+			}
+		}
+	}
+	nodalTest, err := NodalTest(residualsBefore, residualVariances, o.confidence)
+	if err != nil {
+		return nil, err
+	}
+
+	// Os multiplicadores das linhas descartadas são nulos.
+	constraintLambda := make([]float64, numConstraints)
+	for row, k := 0, 0; row < numConstraints; row++ {
+		if !o.dropped[row] {
+			constraintLambda[row] = lambda[k]
+			k++
+		}
+	}
+
+	return &Result{
+		Reconciled:              reconciled,
+		Adjustments:             adjustments,
+		StandardizedAdjustments: standardized,
+		Lambda:                  constraintLambda,
+		Objective:               objective,
+		Deviations:              absDeviations,
+		ReconciledDeviations:    reconciledDeviations,
+		Covariance:              covariance,
+		ResidualsBefore:         residualsBefore,
+		ResidualsAfter:          constraintResiduals(sparseConstraints, reconciled, o.rhs),
+		boundMultipliers:        lambda[numKept:],
+		residualVariances:       residualVariances,
+		GlobalTest:              globalTest,
+		MeasurementTest:         measurementTest,
+		NodalTest:               nodalTest,
+		Classification:          classification,
+		ConditionNumber:         sol.condition,
+		IllConditioned:          sol.condition > illConditionedThreshold,
+		Screening:               o.screening,
+	}, nil
+}
+
+// varianceEpsilon é a fração da variância de uma medição abaixo da qual a variância do seu
+// ajuste é considerada numericamente nula.
+const varianceEpsilon = 1e-10
 
-	return reconciled, nil
-}
\ No newline at end of file
+// constraintResiduals calcula os resíduos das restrições (B*v - c) para o vetor v.
+// Um lado direito nulo (nil) equivale a c = 0. Apenas os coeficientes não nulos participam
+// da soma, de forma que um valor ausente (NaN) só contamina as restrições em que aparece.
+func constraintResiduals(constraints *sparseRows, v, rhs []float64) Values {
+	out := make(Values, constraints.rows)
+	for k := range out {
+		cols, values := constraints.row(k)
+		for p, j := range cols {
+			out[k] += values[p] * v[j]
+		}
+		if rhs != nil {
+			out[k] -= rhs[k]
+		}
+	}
+	return out
+}
@@ -1,6 +1,7 @@
 package reconciliation
 
 import (
+	"errors"
 	"math"
 	"testing"
 
@@ -32,14 +33,109 @@ func TestReconcile(t *testing.T) {
 		expected := []float64{159.0383, 79.0189, 80.0194}
 
 		// Chama a função a ser testada
-		reconciled, err := Reconcile(measurements, tolerances, constraints)
+		result, err := Reconcile(measurements, tolerances, constraints)
 		if err != nil {
 			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
 		}
 
 		// Compara o resultado com os valores esperados
-		if !equal(reconciled, expected, 1e-4) {
-			t.Errorf("O resultado reconciliado estava incorreto.\nEsperado: %v\nObtido:   %v", expected, reconciled)
+		if !equal(result.Reconciled, expected, 1e-4) {
+			t.Errorf("O resultado reconciliado estava incorreto.\nEsperado: %v\nObtido:   %v", expected, result.Reconciled)
+		}
+	})
+
+	t.Run("Exemplo 2 com Lado Direito", func(t *testing.T) {
+		measurements := []float64{161, 79, 80}
+		tolerances := []float64{0.05, 0.01, 0.01}
+		constraints := mat.NewDense(1, 3, []float64{1, -1, -1})
+
+		// Com c = 2, as medições já satisfazem x1 - x2 - x3 = c e não devem ser ajustadas.
+		result, err := Reconcile(measurements, tolerances, constraints, WithRHS([]float64{2}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, measurements, 1e-9) {
+			t.Errorf("As medições não deveriam ser ajustadas.\nEsperado: %v\nObtido:   %v", measurements, result.Reconciled)
+		}
+
+		// Com c = 10, o resíduo r = B*m - c = -8 é distribuído proporcionalmente às variâncias.
+		result, err = Reconcile(measurements, tolerances, constraints, WithRHS([]float64{10}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		variances := []float64{8.05 * 8.05, 0.79 * 0.79, 0.8 * 0.8}
+		s := variances[0] + variances[1] + variances[2]
+		expected := []float64{
+			161 + 8*variances[0]/s,
+			79 - 8*variances[1]/s,
+			80 - 8*variances[2]/s,
+		}
+		if !equal(result.Reconciled, expected, 1e-9) {
+			t.Errorf("O resultado reconciliado estava incorreto.\nEsperado: %v\nObtido:   %v", expected, result.Reconciled)
+		}
+		if !equal(result.ResidualsBefore, []float64{-8}, 1e-9) || !equal(result.ResidualsAfter, []float64{0}, 1e-9) {
+			t.Errorf("Resíduos incorretos: antes %v, depois %v", result.ResidualsBefore, result.ResidualsAfter)
+		}
+	})
+
+	t.Run("Lado Direito com Dimensão Incompatível", func(t *testing.T) {
+		constraints := mat.NewDense(1, 3, []float64{1, -1, -1})
+		_, err := Reconcile([]float64{161, 79, 80}, []float64{0.05, 0.01, 0.01}, constraints, WithRHS([]float64{1, 2}))
+		if err == nil {
+			t.Error("Esperava-se um erro de incompatibilidade de dimensão, mas nenhum foi retornado")
+		}
+	})
+
+	t.Run("Estatísticas do Resultado", func(t *testing.T) {
+		measurements := []float64{161, 79, 80}
+		tolerances := []float64{0.05, 0.01, 0.01}
+		constraints := mat.NewDense(1, 3, []float64{1, -1, -1})
+
+		result, err := Reconcile(measurements, tolerances, constraints)
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+
+		// Os ajustes são a diferença entre os valores reconciliados e os medidos.
+		for i := range measurements {
+			if math.Abs(result.Adjustments[i]-(result.Reconciled[i]-measurements[i])) > 1e-9 {
+				t.Errorf("Ajuste %d incorreto: %v", i, result.Adjustments[i])
+			}
+		}
+
+		// Com uma única restrição, a função objetivo é r^2 / (B*V*B^T), onde r = B*m.
+		r := 161.0 - 79 - 80
+		s := 8.05*8.05 + 0.79*0.79 + 0.8*0.8
+		if math.Abs(result.Objective-r*r/s) > 1e-9 {
+			t.Errorf("Função objetivo incorreta.\nEsperado: %v\nObtido:   %v", r*r/s, result.Objective)
+		}
+
+		// Com uma única restrição, todos os ajustes padronizados têm o mesmo módulo, sqrt(objetivo).
+		for i, z := range result.StandardizedAdjustments {
+			if math.Abs(math.Abs(z)-math.Sqrt(result.Objective)) > 1e-9 {
+				t.Errorf("Ajuste padronizado %d incorreto: %v", i, z)
+			}
+		}
+
+		if !equal(result.ResidualsBefore, []float64{r}, 1e-9) {
+			t.Errorf("Resíduo antes da reconciliação incorreto: %v", result.ResidualsBefore)
+		}
+		if !equal(result.ResidualsAfter, []float64{0}, 1e-9) {
+			t.Errorf("Resíduo após a reconciliação deveria ser zero: %v", result.ResidualsAfter)
+		}
+
+		// A reconciliação nunca piora a precisão de uma medição.
+		for i := range measurements {
+			if result.ReconciledDeviations[i] > result.Deviations[i] {
+				t.Errorf("O desvio reconciliado %d (%v) é maior que o medido (%v)", i, result.ReconciledDeviations[i], result.Deviations[i])
+			}
+		}
+
+		// A covariância dos valores reconciliados satisfaz as restrições: B*Cov(x) = 0.
+		for j := range measurements {
+			if v := result.Covariance[0][j] - result.Covariance[1][j] - result.Covariance[2][j]; math.Abs(v) > 1e-9 {
+				t.Errorf("B*Cov(x) deveria ser zero na coluna %d: %v", j, v)
+			}
 		}
 	})
 
@@ -54,6 +150,10 @@ func TestReconcile(t *testing.T) {
 		if err == nil {
 			t.Error("Esperava-se um erro para uma matriz de pesos singular, mas nenhum foi retornado")
 		}
+		var rankErr *RankDeficiencyError
+		if !errors.As(err, &rankErr) || rankErr.Diagnosis.DependentRows[0].Row != 1 {
+			t.Errorf("O erro deveria identificar a linha dependente: %v", err)
+		}
 	})
 
 	t.Run("Incompatibilidade de Dimensão", func(t *testing.T) {
@@ -0,0 +1,157 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Estimator é o estimador da reconciliação, a função que penaliza os ajustes padronizados.
+type Estimator string
+
+const (
+	// EstimatorLeastSquares é o estimador de mínimos quadrados ponderados, ρ(u) = u^2/2. É o padrão.
+	EstimatorLeastSquares Estimator = "leastSquares"
+	// EstimatorHuber é quadrático até c e linear a partir daí; peso w(u) = min(1, c/|u|).
+	EstimatorHuber Estimator = "huber"
+	// EstimatorCauchy (lorentziano) cresce logaritmicamente; peso w(u) = 1 / (1 + (u/c)^2).
+	EstimatorCauchy Estimator = "cauchy"
+	// EstimatorFair é convexo e cresce linearmente para |u| grande; peso w(u) = 1 / (1 + |u|/c).
+	EstimatorFair Estimator = "fair"
+	// EstimatorWelsch é limitado e ignora as medições muito afastadas; peso w(u) = exp(-(u/c)^2).
+	EstimatorWelsch Estimator = "welsch"
+)
+
+// defaultTuningConstants são as constantes de ajuste de 95% de eficiência assintótica para
+// erros normais.
+var defaultTuningConstants = map[Estimator]float64{
+	EstimatorLeastSquares: 1,
+	EstimatorHuber:        1.345,
+	EstimatorCauchy:       2.3849,
+	EstimatorFair:         1.3998,
+	EstimatorWelsch:       2.9846,
+}
+
+// minRobustWeight é o menor peso robusto admitido, que evita variâncias infinitas.
+const minRobustWeight = 1e-8
+
+// RobustResult descreve a solução por um estimador robusto.
+type RobustResult struct {
+	// Estimator é o estimador usado.
+	Estimator Estimator `json:"estimator"`
+	// TuningConstant é a constante de ajuste (c) usada, em desvios padrão.
+	TuningConstant float64 `json:"tuningConstant"`
+	// Weights são os pesos finais de cada medição, entre 0 e 1. Pesos baixos indicam medições
+	// rebaixadas por estarem afastadas. Variáveis fixas têm peso 1, e as não medidas não têm peso
+	// (NaN, null em JSON).
+	Weights Values `json:"weights"`
+	// Deviations são os desvios padrão originais das medições, antes da reponderação. Os
+	// desvios de Result.Deviations são os efetivos, σ_i / sqrt(w_i).
+	Deviations Values `json:"deviations"`
+	// Convergence descreve a convergência dos mínimos quadrados iterativamente reponderados.
+	Convergence Convergence `json:"convergence"`
+}
+
+// weight calcula o peso w(u) = ψ(u)/u do estimador para o ajuste padronizado u.
+func (e Estimator) weight(u, c float64) float64 {
+	switch e {
+	case EstimatorHuber:
+		if math.Abs(u) <= c {
+			return 1
+		}
+		return c / math.Abs(u)
+	case EstimatorCauchy:
+		return 1 / (1 + (u/c)*(u/c))
+	case EstimatorFair:
+		return 1 / (1 + math.Abs(u)/c)
+	case EstimatorWelsch:
+		return math.Exp(-(u / c) * (u / c))
+	}
+	return 1
+}
+
+// validateEstimator verifica se o estimador é conhecido.
+func validateEstimator(e Estimator) error {
+	if _, ok := defaultTuningConstants[e]; !ok {
+		return fmt.Errorf("%w: estimador desconhecido: %q", ErrInvalidInput, e)
+	}
+	return nil
+}
+
+// reconcileRobust resolve a reconciliação com um estimador robusto por mínimos quadrados
+// iterativamente reponderados (IRLS).
+//
+// A primeira iteração é a solução de mínimos quadrados. A cada iteração, o ajuste de cada
+// medição é padronizado pelo seu desvio padrão original, u_i = (x_i - m_i) / σ_i, e recebe o
+// peso w_i = w(u_i) do estimador; o problema ponderado é então resolvido de novo com as
+// variâncias σ_i^2 / w_i (as covariâncias são reescaladas da mesma forma). Uma medição com
+// erro grosseiro tem u_i grande, peso pequeno e deixa de arrastar as demais. O processo
+// termina quando o maior passo relativo fica abaixo da tolerância de convergência.
+//
+// As estatísticas do resultado (covariância, testes) são as do último problema ponderado.
+func reconcileRobust(measurements, tolerances []float64, constraints *mat.Dense, o options) (*Result, error) {
+	numMeasurements := len(measurements)
+	weights := make([]float64, numMeasurements)
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	var result *Result
+	var deviations Values
+	convergence := Convergence{}
+	for convergence.Iterations < o.maxIterations {
+		convergence.Iterations++
+		o.robustWeights = append([]float64(nil), weights...)
+		next, err := dispatch(measurements, tolerances, constraints, o)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			deviations = next.Deviations
+		} else {
+			convergence.StepNorm = 0
+			for i, x := range next.Reconciled {
+				if !math.IsNaN(x) {
+					convergence.StepNorm = math.Max(convergence.StepNorm, math.Abs(x-result.Reconciled[i])/math.Max(1, math.Abs(result.Reconciled[i])))
+				}
+			}
+		}
+		result = next
+		if convergence.Iterations > 1 && convergence.StepNorm < o.convergenceTolerance {
+			convergence.Converged = true
+			break
+		}
+
+		// As variáveis não medidas e as fixas (desvio zero) não têm ajuste a ponderar: o seu
+		// peso fica em 1.
+		for i := range weights {
+			if o.unmeasured[i] || o.fixed[i] || deviations[i] == 0 {
+				continue
+			}
+			u := (result.Reconciled[i] - measurements[i]) / deviations[i]
+			weights[i] = math.Max(o.estimator.weight(u, o.tuningConstant), minRobustWeight)
+		}
+	}
+
+	for _, r := range result.ResidualsAfter {
+		if !math.IsNaN(r) {
+			convergence.ConstraintNorm = math.Max(convergence.ConstraintNorm, math.Abs(r))
+		}
+	}
+	finalWeights := make(Values, numMeasurements)
+	for i := range finalWeights {
+		finalWeights[i] = o.robustWeights[i]
+		if o.unmeasured[i] {
+			finalWeights[i] = math.NaN()
+		}
+	}
+	result.Robust = &RobustResult{
+		Estimator:      o.estimator,
+		TuningConstant: o.tuningConstant,
+		Weights:        finalWeights,
+		Deviations:     deviations,
+		Convergence:    convergence,
+	}
+	return result, nil
+}
@@ -0,0 +1,113 @@
+package reconciliation
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestRobustEstimators(t *testing.T) {
+	// A terceira medição tem um erro grosseiro (55 em vez de 40).
+	measurements := []float64{100, 60, 55, 40, 100}
+	tolerances := []float64{0.01, 0.01, 0.01, 0.01, 0.01}
+	truth := []float64{100, 60, 40, 40, 100}
+
+	leastSquares, err := Reconcile(measurements, tolerances, networkConstraints())
+	if err != nil {
+		t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+	}
+	if leastSquares.Robust != nil {
+		t.Errorf("Mínimos quadrados não deveriam informar pesos robustos")
+	}
+	lsError := 0.0
+	for i, x := range leastSquares.Reconciled {
+		lsError = math.Max(lsError, math.Abs(x-truth[i]))
+	}
+
+	for _, estimator := range []Estimator{EstimatorHuber, EstimatorCauchy, EstimatorFair, EstimatorWelsch} {
+		t.Run(string(estimator), func(t *testing.T) {
+			result, err := Reconcile(measurements, tolerances, networkConstraints(), WithEstimator(estimator))
+			if err != nil {
+				t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+			}
+			robust := result.Robust
+			if robust == nil || !robust.Convergence.Converged || robust.TuningConstant != defaultTuningConstants[estimator] {
+				t.Fatalf("Resultado robusto incorreto: %+v", robust)
+			}
+			for i, w := range robust.Weights {
+				if i != 2 && w <= robust.Weights[2] {
+					t.Errorf("A medição com erro grosseiro deveria ter o menor peso: %v", robust.Weights)
+				}
+			}
+			if robust.Weights[2] > 0.1 {
+				t.Errorf("A medição com erro grosseiro deveria ser rebaixada: %v", robust.Weights)
+			}
+			robustError := 0.0
+			for i, x := range result.Reconciled {
+				robustError = math.Max(robustError, math.Abs(x-truth[i]))
+			}
+			if robustError > lsError/3 {
+				t.Errorf("O estimador robusto deveria se aproximar dos valores verdadeiros: erro %v, mínimos quadrados %v", robustError, lsError)
+			}
+			for k, r := range result.ResidualsAfter {
+				if math.Abs(r) > 1e-9 {
+					t.Errorf("Resíduo da restrição %d após a reconciliação: %v", k, r)
+				}
+			}
+		})
+	}
+
+	t.Run("Constante de Ajuste", func(t *testing.T) {
+		// Com uma constante grande, Huber equivale a mínimos quadrados.
+		result, err := Reconcile(measurements, tolerances, networkConstraints(), WithEstimator(EstimatorHuber), WithTuningConstant(100))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, leastSquares.Reconciled, 1e-9) || result.Robust.Weights[2] != 1 {
+			t.Errorf("Huber com c grande deveria reproduzir mínimos quadrados: %v", result.Reconciled)
+		}
+	})
+
+	t.Run("Variável Fixa", func(t *testing.T) {
+		// x2 tem tolerância zero: o seu desvio é nulo e ela não recebe peso robusto.
+		constraints := mat.NewDense(2, 4, []float64{
+			1, -1, -1, 0,
+			0, 1, 0, -1,
+		})
+		fixedMeasurements := []float64{100, 60, 38, 59}
+		fixedTolerances := []float64{0.02, 0.02, 0, 0.02}
+		for _, estimator := range []Estimator{EstimatorHuber, EstimatorWelsch} {
+			result, err := Reconcile(fixedMeasurements, fixedTolerances, constraints, WithEstimator(estimator))
+			if err != nil {
+				t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+			}
+			if result.Robust.Weights[2] != 1 || result.Deviations[2] != 0 || result.Reconciled[2] != 38 {
+				t.Errorf("%s: a variável fixa deveria manter peso 1 e desvio zero: %v %v", estimator, result.Robust.Weights, result.Deviations)
+			}
+			for i, d := range result.Deviations {
+				if math.IsNaN(d) || math.IsNaN(result.Robust.Weights[i]) || math.IsNaN(result.Reconciled[i]) {
+					t.Errorf("%s: resultado com NaN: %v %v %v", estimator, result.Reconciled, result.Robust.Weights, result.Deviations)
+					break
+				}
+			}
+		}
+		// WithFixed tem o mesmo efeito.
+		result, err := Reconcile(fixedMeasurements, []float64{0.02, 0.02, 0.02, 0.02}, constraints, WithEstimator(EstimatorHuber), WithFixed([]int{2}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if result.Robust.Weights[2] != 1 || result.Reconciled[2] != 38 {
+			t.Errorf("A variável fixa deveria manter peso 1: %v", result.Robust.Weights)
+		}
+	})
+
+	t.Run("Estimador Inválido", func(t *testing.T) {
+		if _, err := Reconcile(measurements, tolerances, networkConstraints(), WithEstimator("tukey")); err == nil {
+			t.Error("Um estimador desconhecido deveria gerar um erro")
+		}
+		if _, err := Reconcile(measurements, tolerances, networkConstraints(), WithEstimator(EstimatorHuber), WithTuningConstant(-1)); err == nil {
+			t.Error("Uma constante de ajuste negativa deveria gerar um erro")
+		}
+	})
+}
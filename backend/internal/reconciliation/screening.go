@@ -0,0 +1,299 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// QualityFlag é um problema de qualidade de uma medição detectado pela triagem.
+type QualityFlag string
+
+const (
+	// FlagRange indica uma medição fora da faixa física configurada da variável.
+	FlagRange QualityFlag = "range"
+	// FlagFrozen indica um valor congelado: as últimas amostras, incluindo a medição, não variam.
+	FlagFrozen QualityFlag = "frozen"
+	// FlagStale indica uma medição cujo registro de tempo é antigo demais ou está ausente.
+	FlagStale QualityFlag = "stale"
+	// FlagSpike indica um pico: a medição se afasta demais da mediana do histórico recente.
+	FlagSpike QualityFlag = "spike"
+)
+
+// ScreeningAction é o tratamento das medições sinalizadas pela triagem na reconciliação.
+type ScreeningAction string
+
+const (
+	// ScreeningFlag apenas sinaliza as medições, sem alterar a reconciliação.
+	ScreeningFlag ScreeningAction = "flag"
+	// ScreeningExclude trata as variáveis sinalizadas como não medidas: elas são eliminadas das
+	// restrições e estimadas quando observáveis (ver WithUnmeasured). É o padrão.
+	ScreeningExclude ScreeningAction = "exclude"
+	// ScreeningDownweight multiplica o desvio padrão das medições sinalizadas por
+	// DownweightFactor, reduzindo o seu peso na reconciliação.
+	ScreeningDownweight ScreeningAction = "downweight"
+)
+
+// DefaultDownweightFactor é o fator padrão que multiplica o desvio padrão das medições
+// sinalizadas com a ação ScreeningDownweight (o peso cai 100 vezes).
+const DefaultDownweightFactor = 10
+
+// minSpikeHistory é o menor número de valores do histórico com que o teste de pico é feito;
+// com menos, a mediana e o desvio absoluto mediano não são representativos.
+const minSpikeHistory = 3
+
+// madScale converte o desvio absoluto mediano no desvio padrão de uma distribuição normal.
+const madScale = 1.4826
+
+// ScreeningConfig configura a triagem das medições antes da reconciliação. As verificações com
+// parâmetro zero (ou limites nulos) ficam desativadas.
+type ScreeningConfig struct {
+	// Lower e Upper são os limites da faixa física de cada variável, com um elemento por
+	// variável quando informados. NaN (null em JSON) ou ±Inf indicam uma variável sem aquele limite.
+	Lower Values `json:"lower,omitempty"`
+	Upper Values `json:"upper,omitempty"`
+	// FrozenSamples é o número N de amostras consecutivas, incluindo a medição, que caracteriza
+	// um valor congelado quando nenhuma delas varia. Deve ser ao menos 2.
+	FrozenSamples int `json:"frozenSamples,omitempty"`
+	// FrozenTolerance é a maior diferença entre as N amostras que ainda é considerada ausência
+	// de variação, na unidade da variável. Zero exige valores idênticos.
+	FrozenTolerance float64 `json:"frozenTolerance,omitempty"`
+	// MaxAge é a maior idade aceita do registro de tempo de uma medição, na unidade de tempo de
+	// ScreeningData.
+	MaxAge float64 `json:"maxAge,omitempty"`
+	// SpikeThreshold é o maior afastamento aceito entre a medição e a mediana do histórico, em
+	// desvios padrão robustos (1,4826 vezes o desvio absoluto mediano).
+	SpikeThreshold float64 `json:"spikeThreshold,omitempty"`
+	// Action é o tratamento das medições sinalizadas. Vazio usa ScreeningExclude.
+	Action ScreeningAction `json:"action,omitempty"`
+	// DownweightFactor multiplica o desvio padrão das medições sinalizadas com a ação
+	// ScreeningDownweight. Zero usa DefaultDownweightFactor.
+	DownweightFactor float64 `json:"downweightFactor,omitempty"`
+}
+
+// ScreeningData são os dados de historiador usados pela triagem, além das medições atuais.
+type ScreeningData struct {
+	// History são os valores anteriores de cada variável, do mais antigo ao mais recente, sem a
+	// medição atual. As séries podem ter comprimentos diferentes; valores NaN são lacunas e são
+	// ignorados. Necessário para os testes de valor congelado e de pico.
+	History [][]float64 `json:"history,omitempty"`
+	// Timestamps são os instantes em que cada medição atual foi registrada. Um elemento NaN
+	// (null em JSON) é uma medição sem registro de tempo. Necessário para o teste de idade.
+	Timestamps Values `json:"timestamps,omitempty"`
+	// Now é o instante de referência do teste de idade, na mesma unidade de Timestamps.
+	Now float64 `json:"now,omitempty"`
+}
+
+// TagQuality é o resultado da triagem de uma variável.
+type TagQuality struct {
+	// Flags são os problemas detectados, na ordem range, frozen, stale, spike. Vazio quando a
+	// medição passou em todas as verificações.
+	Flags []QualityFlag `json:"flags"`
+	// SpikeStatistic é o afastamento da medição em relação à mediana do histórico, em desvios
+	// padrão robustos. Zero quando o teste de pico não foi feito.
+	SpikeStatistic float64 `json:"spikeStatistic"`
+}
+
+// ScreeningResult é o resultado da triagem das medições.
+type ScreeningResult struct {
+	// Action é o tratamento das medições sinalizadas.
+	Action ScreeningAction `json:"action"`
+	// DownweightFactor é o fator do desvio padrão das medições sinalizadas, presente apenas com
+	// a ação ScreeningDownweight.
+	DownweightFactor float64 `json:"downweightFactor,omitempty"`
+	// Tags são os resultados de cada variável.
+	Tags []TagQuality `json:"tags"`
+	// Flagged são os índices das variáveis com algum problema, em ordem crescente.
+	Flagged []int `json:"flagged"`
+}
+
+// Screen faz a triagem de qualidade das medições atuais antes da reconciliação, com quatro
+// verificações por variável:
+//
+//   - Faixa: a medição fica fora de [Lower, Upper].
+//   - Valor congelado: a medição e as FrozenSamples-1 últimas amostras do histórico variam no
+//     máximo FrozenTolerance, típico de um transmissor travado ou de um historiador que repete
+//     o último valor. Não é feito quando o histórico é mais curto.
+//   - Idade: o registro de tempo da medição é mais antigo que Now - MaxAge, ou está ausente.
+//   - Pico: a medição se afasta da mediana do histórico mais que SpikeThreshold desvios padrão
+//     robustos, estimados pelo desvio absoluto mediano. Não é feito com menos de 3 valores no
+//     histórico ou quando o histórico é constante.
+//
+// Medições NaN ou ±Inf não são verificadas: o seu tratamento é o da política de medições
+// ausentes (ver WithMissing). O resultado é aplicado à reconciliação por WithScreening.
+func Screen(measurements []float64, data ScreeningData, config ScreeningConfig) (*ScreeningResult, error) {
+	n := len(measurements)
+	if n == 0 {
+		return nil, fmt.Errorf("%w: o slice de medições não pode estar vazio", ErrInvalidInput)
+	}
+	action := config.Action
+	switch action {
+	case "":
+		action = ScreeningExclude
+	case ScreeningFlag, ScreeningExclude, ScreeningDownweight:
+	default:
+		return nil, fmt.Errorf("%w: ação de triagem desconhecida: %q", ErrInvalidInput, action)
+	}
+	if (config.Lower != nil && len(config.Lower) != n) || (config.Upper != nil && len(config.Upper) != n) {
+		return nil, fmt.Errorf("%w: medições (%d) e limites da faixa física", ErrDimensionMismatch, n)
+	}
+	for i := 0; config.Lower != nil && config.Upper != nil && i < n; i++ {
+		if config.Lower[i] > config.Upper[i] {
+			return nil, fmt.Errorf("%w: faixa física inválida na variável %d: limite inferior %v maior que o superior %v", ErrInvalidInput, i, config.Lower[i], config.Upper[i])
+		}
+	}
+	if config.FrozenSamples < 0 || config.FrozenSamples == 1 {
+		return nil, fmt.Errorf("%w: o número de amostras do valor congelado deve ser ao menos 2, obtido %d", ErrInvalidInput, config.FrozenSamples)
+	}
+	if config.FrozenTolerance < 0 || config.MaxAge < 0 || config.SpikeThreshold < 0 {
+		return nil, fmt.Errorf("%w: a tolerância do valor congelado, a idade máxima e o limite de pico não podem ser negativos", ErrInvalidInput)
+	}
+	factor := config.DownweightFactor
+	if factor == 0 {
+		factor = DefaultDownweightFactor
+	}
+	if factor <= 1 || math.IsInf(factor, 0) {
+		return nil, fmt.Errorf("%w: o fator de redução do peso deve ser finito e maior que 1, obtido %v", ErrInvalidInput, factor)
+	}
+	if (config.FrozenSamples > 0 || config.SpikeThreshold > 0) && len(data.History) != n {
+		return nil, fmt.Errorf("%w: medições (%d) e séries do histórico (%d)", ErrDimensionMismatch, n, len(data.History))
+	}
+	if config.MaxAge > 0 && len(data.Timestamps) != n {
+		return nil, fmt.Errorf("%w: medições (%d) e registros de tempo (%d)", ErrDimensionMismatch, n, len(data.Timestamps))
+	}
+
+	result := &ScreeningResult{Action: action, Tags: make([]TagQuality, n), Flagged: []int{}}
+	if action == ScreeningDownweight {
+		result.DownweightFactor = factor
+	}
+	for i, m := range measurements {
+		tag := TagQuality{Flags: []QualityFlag{}}
+		if math.IsNaN(m) || math.IsInf(m, 0) {
+			result.Tags[i] = tag
+			continue
+		}
+		var history []float64
+		if data.History != nil {
+			for _, v := range data.History[i] {
+				if !math.IsNaN(v) && !math.IsInf(v, 0) {
+					history = append(history, v)
+				}
+			}
+		}
+
+		if (config.Lower != nil && m < config.Lower[i]) || (config.Upper != nil && m > config.Upper[i]) {
+			tag.Flags = append(tag.Flags, FlagRange)
+		}
+		if config.FrozenSamples > 0 && frozen(m, history, config.FrozenSamples, config.FrozenTolerance) {
+			tag.Flags = append(tag.Flags, FlagFrozen)
+		}
+		if config.MaxAge > 0 {
+			if t := data.Timestamps[i]; math.IsNaN(t) || data.Now-t > config.MaxAge {
+				tag.Flags = append(tag.Flags, FlagStale)
+			}
+		}
+		if config.SpikeThreshold > 0 && len(history) >= minSpikeHistory {
+			tag.SpikeStatistic = spikeStatistic(m, history)
+			if tag.SpikeStatistic > config.SpikeThreshold {
+				tag.Flags = append(tag.Flags, FlagSpike)
+			}
+		}
+
+		if len(tag.Flags) > 0 {
+			result.Flagged = append(result.Flagged, i)
+		}
+		result.Tags[i] = tag
+	}
+	return result, nil
+}
+
+// frozen verifica se a medição e as samples-1 últimas amostras do histórico variam no máximo
+// tolerance. Com um histórico mais curto, a variável não é considerada congelada.
+func frozen(m float64, history []float64, samples int, tolerance float64) bool {
+	if len(history) < samples-1 {
+		return false
+	}
+	low, high := m, m
+	for _, v := range history[len(history)-(samples-1):] {
+		low, high = math.Min(low, v), math.Max(high, v)
+	}
+	return high-low <= tolerance
+}
+
+// spikeStatistic é o afastamento de m em relação à mediana do histórico, em unidades de
+// 1,4826 vezes o desvio absoluto mediano. Um histórico constante não tem escala e retorna zero.
+func spikeStatistic(m float64, history []float64) float64 {
+	center := median(history)
+	deviations := make([]float64, len(history))
+	for k, v := range history {
+		deviations[k] = math.Abs(v - center)
+	}
+	scale := madScale * median(deviations)
+	if scale == 0 {
+		return 0
+	}
+	return math.Abs(m-center) / scale
+}
+
+// median é a mediana dos valores, que não são alterados.
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	k := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[k]
+	}
+	return (sorted[k-1] + sorted[k]) / 2
+}
+
+// applyScreening aplica o resultado da triagem (WithScreening) às opções: as variáveis
+// sinalizadas passam a ser não medidas (ScreeningExclude) ou têm o desvio padrão multiplicado
+// (ScreeningDownweight). As variáveis já não medidas e as fixas (WithFixed) apenas mantêm os
+// seus sinais.
+func applyScreening(o *options, numMeasurements int) error {
+	s := o.screening
+	if s == nil {
+		return nil
+	}
+	if len(s.Tags) != numMeasurements {
+		return fmt.Errorf("%w: medições (%d) e resultado da triagem (%d)", ErrDimensionMismatch, numMeasurements, len(s.Tags))
+	}
+	var excluded []int
+	for _, i := range s.Flagged {
+		if i < 0 || i >= numMeasurements {
+			return fmt.Errorf("%w: índice de variável sinalizada fora do intervalo: %d", ErrInvalidInput, i)
+		}
+		if o.unmeasured[i] || o.fixed[i] {
+			continue
+		}
+		switch s.Action {
+		case ScreeningFlag:
+		case ScreeningExclude:
+			excluded = append(excluded, i)
+		case ScreeningDownweight:
+			if s.DownweightFactor <= 1 {
+				return fmt.Errorf("%w: o fator de redução do peso deve ser maior que 1, obtido %v", ErrInvalidInput, s.DownweightFactor)
+			}
+			if o.inflation == nil {
+				o.inflation = make([]float64, numMeasurements)
+				for k := range o.inflation {
+					o.inflation[k] = 1
+				}
+			}
+			o.inflation[i] = s.DownweightFactor
+		default:
+			return fmt.Errorf("%w: ação de triagem desconhecida: %q", ErrInvalidInput, s.Action)
+		}
+	}
+	if len(excluded) > 0 {
+		unmeasured := make(map[int]bool, len(o.unmeasured)+len(excluded))
+		for i := range o.unmeasured {
+			unmeasured[i] = true
+		}
+		for _, i := range excluded {
+			unmeasured[i] = true
+		}
+		o.unmeasured = unmeasured
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package reconciliation
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestScreening(t *testing.T) {
+	measurements := []float64{100, 60, 40, 40, 100}
+	tolerances := []float64{1, 1, 1, 1, 1}
+	absolute := WithUncertaintyMode(UncertaintyAbsolute)
+	nan := math.NaN()
+
+	// x0 tem um pico, x1 está acima da faixa física, x2 está congelada e x3 é antiga.
+	data := ScreeningData{
+		History: [][]float64{
+			{90, 91, 89, 90, 92},
+			{58, 61, 59, 60, 62},
+			{41, 40, 40},
+			{39, 41, nan, 40, 42},
+			{98, 101, 99, 100, 102},
+		},
+		Timestamps: Values{1000, 1000, 1000, 100, 1000},
+		Now:        1010,
+	}
+	config := ScreeningConfig{
+		Lower:          Values{0, 0, 0, 0, 0},
+		Upper:          Values{nan, 50, nan, nan, nan},
+		FrozenSamples:  3,
+		MaxAge:         60,
+		SpikeThreshold: 3.5,
+	}
+
+	t.Run("Sinais de Qualidade", func(t *testing.T) {
+		screening, err := Screen(measurements, data, config)
+		if err != nil {
+			t.Fatalf("Screen retornou um erro inesperado: %v", err)
+		}
+		expected := [][]QualityFlag{{FlagSpike}, {FlagRange}, {FlagFrozen}, {FlagStale}, {}}
+		for i, tag := range screening.Tags {
+			if !slices.Equal(tag.Flags, expected[i]) {
+				t.Errorf("Sinais incorretos na variável %d: esperado %v, obtido %v", i, expected[i], tag.Flags)
+			}
+		}
+		if !slices.Equal(screening.Flagged, []int{0, 1, 2, 3}) {
+			t.Errorf("Variáveis sinalizadas incorretas: %v", screening.Flagged)
+		}
+		// Mediana 90 e desvio absoluto mediano 1: (100 - 90) / 1,4826.
+		if got := screening.Tags[0].SpikeStatistic; math.Abs(got-10/madScale) > 1e-12 {
+			t.Errorf("Estatística de pico incorreta: esperado %v, obtido %v", 10/madScale, got)
+		}
+		if screening.Action != ScreeningExclude {
+			t.Errorf("Ação padrão incorreta: %v", screening.Action)
+		}
+
+		// Uma medição sem registro de tempo é antiga.
+		stale := data
+		stale.Timestamps = Values{1000, 1000, 1000, 1000, nan}
+		screening, err = Screen(measurements, stale, ScreeningConfig{MaxAge: 60})
+		if err != nil {
+			t.Fatalf("Screen retornou um erro inesperado: %v", err)
+		}
+		if !slices.Equal(screening.Flagged, []int{4}) {
+			t.Errorf("Variáveis sinalizadas incorretas: %v", screening.Flagged)
+		}
+	})
+
+	t.Run("Exclusão", func(t *testing.T) {
+		screening, err := Screen(measurements, ScreeningData{}, ScreeningConfig{Upper: Values{nan, 50, nan, nan, nan}})
+		if err != nil {
+			t.Fatalf("Screen retornou um erro inesperado: %v", err)
+		}
+		result, err := Reconcile(measurements, tolerances, networkConstraints(), absolute, WithScreening(screening))
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		expected, err := Reconcile(measurements, tolerances, networkConstraints(), absolute, WithUnmeasured([]int{1}))
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, expected.Reconciled, 1e-12) {
+			t.Errorf("Resultado incorreto.\nEsperado: %v\nObtido:   %v", expected.Reconciled, result.Reconciled)
+		}
+		if result.Screening != screening {
+			t.Error("O resultado da triagem deveria ser informado no resultado")
+		}
+
+		// Uma variável fixa sinalizada é apenas informada.
+		result, err = Reconcile(measurements, tolerances, networkConstraints(), absolute, WithFixed([]int{1}), WithScreening(screening))
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		if result.Reconciled[1] != 60 || result.Classification[1] != ClassFixed {
+			t.Errorf("A variável fixa não deveria ser excluída: %v, %v", result.Reconciled[1], result.Classification[1])
+		}
+	})
+
+	t.Run("Redução do Peso", func(t *testing.T) {
+		screening, err := Screen(measurements, ScreeningData{}, ScreeningConfig{
+			Upper:  Values{nan, 50, nan, nan, nan},
+			Action: ScreeningDownweight,
+		})
+		if err != nil {
+			t.Fatalf("Screen retornou um erro inesperado: %v", err)
+		}
+		result, err := Reconcile(measurements, tolerances, networkConstraints(), absolute, WithScreening(screening))
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		expected, err := Reconcile(measurements, []float64{1, DefaultDownweightFactor, 1, 1, 1}, networkConstraints(), absolute)
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, expected.Reconciled, 1e-12) || result.Deviations[1] != DefaultDownweightFactor {
+			t.Errorf("Resultado incorreto.\nEsperado: %v\nObtido:   %v", expected.Reconciled, result.Reconciled)
+		}
+
+		// Com a matriz de covariância completa, a variância é multiplicada pelo quadrado do fator.
+		covariance := mat.NewDense(5, 5, nil)
+		inflated := mat.NewDense(5, 5, nil)
+		for i := 0; i < 5; i++ {
+			covariance.Set(i, i, 4)
+			inflated.Set(i, i, 4)
+		}
+		covariance.Set(0, 1, 1)
+		covariance.Set(1, 0, 1)
+		inflated.Set(0, 1, DefaultDownweightFactor)
+		inflated.Set(1, 0, DefaultDownweightFactor)
+		inflated.Set(1, 1, 4*DefaultDownweightFactor*DefaultDownweightFactor)
+		result, err = Reconcile(measurements, tolerances, networkConstraints(), WithCovariance(covariance), WithScreening(screening))
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		expected, err = Reconcile(measurements, tolerances, networkConstraints(), WithCovariance(inflated))
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, expected.Reconciled, 1e-12) {
+			t.Errorf("Resultado incorreto com covariância.\nEsperado: %v\nObtido:   %v", expected.Reconciled, result.Reconciled)
+		}
+	})
+
+	t.Run("Apenas Sinalização", func(t *testing.T) {
+		flagOnly := config
+		flagOnly.Action = ScreeningFlag
+		screening, err := Screen(measurements, data, flagOnly)
+		if err != nil {
+			t.Fatalf("Screen retornou um erro inesperado: %v", err)
+		}
+		result, err := Reconcile(measurements, tolerances, networkConstraints(), absolute, WithScreening(screening))
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		expected, err := Reconcile(measurements, tolerances, networkConstraints(), absolute)
+		if err != nil {
+			t.Fatalf("Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Reconciled, expected.Reconciled, 1e-12) || len(result.Screening.Flagged) != 4 {
+			t.Errorf("A sinalização não deveria alterar a reconciliação: %v", result.Reconciled)
+		}
+	})
+
+	t.Run("Entradas Inválidas", func(t *testing.T) {
+		invalid := []ScreeningConfig{
+			{Action: "discard"},
+			{FrozenSamples: 1},
+			{Lower: Values{0, 70, 0, 0, 0}, Upper: Values{nan, 50, nan, nan, nan}},
+			{Upper: Values{50}},
+			{SpikeThreshold: -1},
+			{Action: ScreeningDownweight, DownweightFactor: 0.5},
+		}
+		for _, c := range invalid {
+			if _, err := Screen(measurements, data, c); err == nil {
+				t.Errorf("Esperado um erro para a configuração %+v", c)
+			}
+		}
+		if _, err := Screen(measurements, ScreeningData{History: data.History[:2]}, ScreeningConfig{FrozenSamples: 3}); err == nil {
+			t.Error("Esperado um erro para um histórico incompleto")
+		}
+		if _, err := Screen(measurements, ScreeningData{}, ScreeningConfig{MaxAge: 60}); err == nil {
+			t.Error("Esperado um erro sem registros de tempo")
+		}
+		if _, err := Reconcile(measurements, tolerances, networkConstraints(), WithScreening(&ScreeningResult{Tags: make([]TagQuality, 2)})); err == nil {
+			t.Error("Esperado um erro para um resultado de triagem incompatível")
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// SensitivityResult é o mapa linear entre as medições e as saídas reconciliadas, y = G*m + b.
+//
+// As saídas são as próprias variáveis reconciliadas ou combinações lineares delas (ex: o
+// rendimento da planta, um balanço de produção), uma por linha da matriz de saídas.
+type SensitivityResult struct {
+	// Outputs são os valores das saídas na solução reconciliada.
+	Outputs Values `json:"outputs"`
+	// Deviations são os desvios padrão das saídas.
+	Deviations Values `json:"deviations"`
+	// Gain é a matriz de ganhos, G_ij = ∂y_i/∂m_j, uma linha por saída e uma coluna por
+	// variável. Variáveis não medidas e fixas não são entradas (coluna NaN, null em JSON); o
+	// efeito das fixas fica no termo independente. Uma saída que depende de uma variável não
+	// observável não tem ganhos (linha NaN).
+	Gain []Values `json:"gain"`
+	// Offset é o termo independente b do mapa, que vem do lado direito das restrições.
+	Offset Values `json:"offset"`
+	// Contributions é a fração da variância de cada saída devida a cada medição,
+	// G_ij * (V*G^T)_ji / Var(y_i). Cada linha soma 1; com medições correlacionadas, uma fração
+	// pode ser negativa.
+	Contributions []Values `json:"contributions"`
+	// Ranking lista, para cada saída, os índices das medições em ordem decrescente do módulo da
+	// contribuição, para priorizar os instrumentos de maior impacto.
+	Ranking [][]int `json:"ranking"`
+}
+
+// Sensitivity calcula a matriz de ganhos das saídas reconciliadas em relação a cada medição.
+//
+// Com restrições lineares, a solução é afim nas medições, x = A*m + a. A solução de mínimos
+// quadrados ponderados é uma projeção na métrica V^-1, de modo que Cov(x) = A*V*A^T = A*V e,
+// portanto, A = Cov(x, m)*V^-1 = Cov(x)_{:,M}*W, onde M são as variáveis medidas; o mesmo vale
+// para as variáveis não medidas observáveis. O mapa é obtido da covariância da reconciliação,
+// sem resolver um problema por medição.
+//
+// outputs (k x n) define as saídas y = outputs*x; se nulo, as saídas são as próprias variáveis
+// reconciliadas. As opções são as de Reconcile. Os pesos são mantidos fixos: com tolerâncias
+// relativas, em que σ também depende da medição, e com limites ativos ou estimadores robustos,
+// o mapa é o do problema final, válido para pequenas variações das medições.
+func Sensitivity(measurements, tolerances []float64, constraints *mat.Dense, outputs *mat.Dense, opts ...Option) (*SensitivityResult, error) {
+	n := len(measurements)
+	if outputs != nil {
+		if _, cols := outputs.Dims(); cols != n {
+			return nil, fmt.Errorf("%w: colunas das saídas (%d) e medições (%d)", ErrDimensionMismatch, cols, n)
+		}
+	} else {
+		outputs = identity(n)
+	}
+
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	result, err := Reconcile(measurements, tolerances, constraints, append(append([]Option(nil), opts...), WithReconciledCovariance(true))...)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyScreening(&o, n); err != nil {
+		return nil, err
+	}
+	if result.Robust != nil {
+		o.robustWeights = make([]float64, n)
+		for i, w := range result.Robust.Weights {
+			o.robustWeights[i] = 1
+			if !math.IsNaN(w) {
+				o.robustWeights[i] = w
+			}
+		}
+	}
+
+	var measured []int
+	for i, d := range result.Deviations {
+		if !math.IsNaN(d) && result.Classification[i] != ClassFixed {
+			measured = append(measured, i)
+		}
+	}
+	weights, covariance, err := weightMatrix(o, result.Deviations, measured)
+	if err != nil {
+		return nil, err
+	}
+
+	// A = Cov(x)_{:,M} * W, com as linhas das variáveis não observáveis em NaN.
+	numMeasured := len(measured)
+	crossCov := mat.NewDense(n, numMeasured, nil)
+	for i := 0; i < n; i++ {
+		for k, j := range measured {
+			crossCov.Set(i, k, result.Covariance[i][j])
+		}
+	}
+	var gain mat.Dense
+	gain.Mul(crossCov, weights)
+
+	// G = C*A e V*G^T, ignorando as variáveis com coeficiente nulo, que podem ser não observáveis.
+	numOutputs, _ := outputs.Dims()
+	sens := &SensitivityResult{
+		Outputs:       make(Values, numOutputs),
+		Deviations:    make(Values, numOutputs),
+		Gain:          make([]Values, numOutputs),
+		Offset:        make(Values, numOutputs),
+		Contributions: make([]Values, numOutputs),
+		Ranking:       make([][]int, numOutputs),
+	}
+	for r := 0; r < numOutputs; r++ {
+		row := make([]float64, numMeasured)
+		var value float64
+		observable := true
+		for i, c := range outputs.RawRowView(r) {
+			if c == 0 {
+				continue
+			}
+			if math.IsNaN(result.Reconciled[i]) {
+				observable = false
+				break
+			}
+			value += c * result.Reconciled[i]
+			for k := range row {
+				row[k] += c * gain.At(i, k)
+			}
+		}
+		sens.Gain[r] = nanValues(n)
+		sens.Contributions[r] = nanValues(n)
+		sens.Ranking[r] = []int{}
+		if !observable {
+			sens.Outputs[r], sens.Deviations[r], sens.Offset[r] = math.NaN(), math.NaN(), math.NaN()
+			continue
+		}
+
+		var spread mat.VecDense
+		spread.MulVec(covariance, mat.NewVecDense(numMeasured, row))
+		variance := mat.Dot(&spread, mat.NewVecDense(numMeasured, row))
+		offset := value
+		for k, j := range measured {
+			sens.Gain[r][j] = row[k]
+			offset -= row[k] * measurements[j]
+			sens.Contributions[r][j] = 0
+			if variance > 0 {
+				sens.Contributions[r][j] = row[k] * spread.AtVec(k) / variance
+			}
+		}
+		sens.Outputs[r] = value
+		sens.Deviations[r] = math.Sqrt(math.Max(variance, 0))
+		sens.Offset[r] = offset
+
+		ranking := append([]int(nil), measured...)
+		sort.SliceStable(ranking, func(a, b int) bool {
+			return math.Abs(sens.Contributions[r][ranking[a]]) > math.Abs(sens.Contributions[r][ranking[b]])
+		})
+		sens.Ranking[r] = ranking
+	}
+	return sens, nil
+}
+
+// identity retorna a matriz identidade de ordem n.
+func identity(n int) *mat.Dense {
+	m := mat.NewDense(n, n, nil)
+	for i := 0; i < n; i++ {
+		m.Set(i, i, 1)
+	}
+	return m
+}
+
+// nanValues retorna um vetor de n valores NaN.
+func nanValues(n int) Values {
+	v := make(Values, n)
+	for i := range v {
+		v[i] = math.NaN()
+	}
+	return v
+}
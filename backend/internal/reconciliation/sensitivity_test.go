@@ -0,0 +1,115 @@
+package reconciliation
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSensitivity(t *testing.T) {
+	measurements := []float64{100, 60, 42, 40, 101}
+	// Tolerâncias absolutas: com tolerâncias relativas, os pesos mudam com as medições e o mapa
+	// só é linear para pequenas variações.
+	tolerances := []float64{1, 1.2, 0.4, 1.2, 1}
+	absolute := WithUncertaintyMode(UncertaintyAbsolute)
+	rhs := []float64{0, 0, 1}
+
+	// checkGain compara cada coluna da matriz de ganhos com a variação da solução quando uma
+	// medição é perturbada, e confere o mapa afim y = G*m + b na solução.
+	checkGain := func(t *testing.T, result *SensitivityResult, opts ...Option) {
+		base, err := Reconcile(measurements, tolerances, networkConstraints(), opts...)
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		for j := range measurements {
+			if math.IsNaN(base.Deviations[j]) {
+				continue
+			}
+			perturbed := append([]float64(nil), measurements...)
+			perturbed[j] += 1
+			shifted, err := Reconcile(perturbed, tolerances, networkConstraints(), opts...)
+			if err != nil {
+				t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+			}
+			for i := range measurements {
+				if got := shifted.Reconciled[i] - base.Reconciled[i]; math.Abs(got-result.Gain[i][j]) > 1e-6 {
+					t.Errorf("Ganho (%d, %d): esperado %v, obtido %v", i, j, got, result.Gain[i][j])
+				}
+			}
+		}
+		for i, x := range base.Reconciled {
+			y := result.Offset[i]
+			for j, g := range result.Gain[i] {
+				if !math.IsNaN(g) {
+					y += g * measurements[j]
+				}
+			}
+			if math.Abs(y-x) > 1e-8 || math.Abs(result.Outputs[i]-x) > 1e-9 {
+				t.Errorf("O mapa afim não reproduz a variável %d: %v, %v", i, y, x)
+			}
+		}
+	}
+
+	t.Run("Ganhos das Variáveis", func(t *testing.T) {
+		result, err := Sensitivity(measurements, tolerances, networkConstraints(), nil, absolute, WithRHS(rhs))
+		if err != nil {
+			t.Fatalf("A função Sensitivity retornou um erro inesperado: %v", err)
+		}
+		checkGain(t, result, absolute, WithRHS(rhs))
+		base, _ := Reconcile(measurements, tolerances, networkConstraints(), absolute, WithRHS(rhs))
+		if !equal(result.Deviations, base.ReconciledDeviations, 1e-9) {
+			t.Errorf("Os desvios das saídas deveriam ser os desvios reconciliados: %v, %v", result.Deviations, base.ReconciledDeviations)
+		}
+		for i, row := range result.Contributions {
+			sum := 0.0
+			for _, c := range row {
+				sum += c
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Errorf("As contribuições da saída %d deveriam somar 1: %v", i, sum)
+			}
+			if len(result.Ranking[i]) != len(measurements) || math.Abs(row[result.Ranking[i][0]]) < math.Abs(row[result.Ranking[i][4]]) {
+				t.Errorf("Classificação incorreta da saída %d: %v", i, result.Ranking[i])
+			}
+		}
+	})
+
+	t.Run("Não Medidas e Correlacionadas", func(t *testing.T) {
+		opts := []Option{absolute, WithUnmeasured([]int{3}), WithCovariances([]CovarianceEntry{{Row: 0, Col: 4, Value: 0.5}})}
+		result, err := Sensitivity(measurements, tolerances, networkConstraints(), nil, opts...)
+		if err != nil {
+			t.Fatalf("A função Sensitivity retornou um erro inesperado: %v", err)
+		}
+		checkGain(t, result, opts...)
+		for i := range measurements {
+			if !math.IsNaN(result.Gain[i][3]) {
+				t.Errorf("A variável não medida não deveria ser uma entrada: %v", result.Gain[i])
+			}
+		}
+	})
+
+	t.Run("Saídas Combinadas", func(t *testing.T) {
+		// Saída: produção líquida, x4 - x0.
+		outputs := mat.NewDense(1, 5, []float64{-1, 0, 0, 0, 1})
+		result, err := Sensitivity(measurements, tolerances, networkConstraints(), outputs)
+		if err != nil {
+			t.Fatalf("A função Sensitivity retornou um erro inesperado: %v", err)
+		}
+		variables, _ := Sensitivity(measurements, tolerances, networkConstraints(), nil)
+		for j := range measurements {
+			if want := variables.Gain[4][j] - variables.Gain[0][j]; math.Abs(result.Gain[0][j]-want) > 1e-12 {
+				t.Errorf("Ganho da saída combinada em %d: esperado %v, obtido %v", j, want, result.Gain[0][j])
+			}
+		}
+		if math.Abs(result.Outputs[0]-(variables.Outputs[4]-variables.Outputs[0])) > 1e-12 {
+			t.Errorf("Valor da saída combinada incorreto: %v", result.Outputs[0])
+		}
+	})
+
+	t.Run("Entradas Inválidas", func(t *testing.T) {
+		if _, err := Sensitivity(measurements, tolerances, networkConstraints(), mat.NewDense(1, 4, nil)); err == nil {
+			t.Error("Saídas com dimensão incorreta deveriam gerar um erro")
+		}
+	})
+}
@@ -0,0 +1,648 @@
+package reconciliation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Solver é o método de solução do sistema de Lagrange.
+type Solver string
+
+const (
+	// SolverAuto escolhe o método esparso para restrições grandes e esparsas, quando ele se
+	// aplica, e o denso nos demais casos. É o padrão.
+	SolverAuto Solver = "auto"
+	// SolverDense fatora o complemento de Schur A*V*A^T por Cholesky denso.
+	SolverDense Solver = "dense"
+	// SolverSparse fatora o complemento de Schur por Cholesky de envelope, após uma
+	// reordenação de Cuthill-McKee reversa. Exige pesos diagonais e nenhuma variável não medida.
+	SolverSparse Solver = "sparse"
+)
+
+const (
+	// sparseMinVariables é o número mínimo de variáveis medidas para que SolverAuto use o
+	// método esparso; abaixo dele, o método denso é mais rápido.
+	sparseMinVariables = 200
+	// sparseMaxDensity é a fração máxima de coeficientes não nulos das restrições para que
+	// SolverAuto use o método esparso.
+	sparseMaxDensity = 0.05
+	// maxConditionNumber é o número de condição acima do qual o complemento de Schur é
+	// considerado singular (restrições linearmente dependentes ou mal condicionadas).
+	maxConditionNumber = 1e12
+)
+
+// errSingular é o erro retornado quando as restrições reduzidas são linearmente dependentes.
+var errSingular = errors.New("a matriz de lagrange é singular e não pode ser invertida, verifique as restrições")
+
+// kktSolution é a solução do sistema de Lagrange sobre as variáveis medidas.
+type kktSolution struct {
+	// x são os valores reconciliados das variáveis medidas (x_M).
+	x []float64
+	// lambda são os multiplicadores das restrições reduzidas.
+	lambda []float64
+	// covariance é Cov(x_M), completa e em ordem de linhas (n x n); nula se não solicitada.
+	covariance []float64
+	// variances é a diagonal de Cov(x_M).
+	variances []float64
+	// condition é a estimativa do número de condição (norma 1) do complemento de Schur.
+	condition float64
+}
+
+// solveKKT resolve o sistema de Lagrange
+//
+// | W   A^T | | x |   | W*m |
+// | A    0  | | λ | = |  c  |
+//
+// sem formar nem inverter a matriz aumentada. Eliminando x = m - V*A^T*λ, com V = W^-1,
+// restam as equações normais do complemento de Schur, (A*V*A^T)*λ = A*m - c, cuja matriz é
+// simétrica e definida positiva quando as restrições são independentes. A covariância das
+// estimativas é Cov(x) = V - V*A^T*(A*V*A^T)^-1*A*V.
+//
+// A matriz A é nula quando não há restrições (reduzidas); nesse caso x = m e Cov(x) = V.
+// Com full falso, apenas a diagonal de Cov(x) é calculada.
+// Se já disponível, sparse é A armazenada por colunas, o que evita percorrer A densa.
+func solveKKT(a *mat.Dense, sparse *sparseMatrix, covariance mat.Symmetric, m, c []float64, full bool, solver Solver) (*kktSolution, error) {
+	n := len(m)
+	if a == nil {
+		sol := &kktSolution{x: append([]float64(nil), m...), variances: make([]float64, n)}
+		for i := range sol.variances {
+			sol.variances[i] = covariance.At(i, i)
+		}
+		if full {
+			sol.covariance = make([]float64, n*n)
+			for i := 0; i < n; i++ {
+				for j := 0; j < n; j++ {
+					sol.covariance[i*n+j] = covariance.At(i, j)
+				}
+			}
+		}
+		return sol, nil
+	}
+	f, err := factorSchur(a, sparse, covariance, solver)
+	if err != nil {
+		return nil, err
+	}
+	return f.solve(a, covariance, m, c, full)
+}
+
+// schurFactor é a fatoração do complemento de Schur S = A*V*A^T, que só depende das
+// restrições reduzidas e da covariância das medições, e não dos valores medidos. Pode ser
+// reutilizada por conjuntos de medições com os mesmos pesos (ver ReconcileBatch).
+type schurFactor struct {
+	// vat é V*A^T, chol é o fator de Cholesky denso de S e z é S^-1*A*V (método denso).
+	vat  *mat.Dense
+	chol *mat.Cholesky
+	z    *mat.Dense
+	// sparse é A por colunas e envelope é o fator de Cholesky de envelope de S (método esparso).
+	sparse   *sparseMatrix
+	envelope *envelopeCholesky
+	// condition é a estimativa do número de condição (norma 1) de S.
+	condition float64
+}
+
+// factorSchur fatora o complemento de Schur pelo método escolhido. SolverAuto usa o método
+// esparso para restrições grandes e esparsas com pesos diagonais, e o denso nos demais casos.
+// Se sparse for nulo e o método esparso for considerado, A é convertida a partir da matriz densa.
+func factorSchur(a *mat.Dense, sparse *sparseMatrix, covariance mat.Symmetric, solver Solver) (*schurFactor, error) {
+	_, n := a.Dims()
+	diagonal, isDiagonal := covariance.(*mat.DiagDense)
+	if solver == SolverAuto {
+		solver = SolverDense
+		if isDiagonal && n >= sparseMinVariables {
+			if sparse == nil {
+				sparse = newSparseMatrix(a)
+			}
+			if sparse.density() <= sparseMaxDensity {
+				solver = SolverSparse
+			}
+		}
+	}
+	if solver == SolverSparse {
+		if !isDiagonal {
+			return nil, fmt.Errorf("%w: o método esparso exige pesos diagonais, sem covariâncias entre medições", ErrInvalidInput)
+		}
+		if sparse == nil {
+			sparse = newSparseMatrix(a)
+		}
+		return factorSparse(sparse, diagonal)
+	}
+	return factorDense(a, covariance)
+}
+
+// solve resolve o sistema de Lagrange para as medições m e o lado direito c com o fator já
+// calculado das mesmas restrições a e da mesma covariância.
+func (f *schurFactor) solve(a *mat.Dense, covariance mat.Symmetric, m, c []float64, full bool) (*kktSolution, error) {
+	if f.envelope != nil {
+		return f.solveSparse(covariance, m, c, full), nil
+	}
+	return f.solveDense(a, covariance, m, c, full)
+}
+
+// factorDense fatora o complemento de Schur por Cholesky denso.
+func factorDense(a *mat.Dense, covariance mat.Symmetric) (*schurFactor, error) {
+	r, n := a.Dims()
+
+	// V*A^T, com um atalho para V diagonal.
+	var vat mat.Dense
+	if diagonal, ok := covariance.(*mat.DiagDense); ok {
+		vat.CloneFrom(a.T())
+		for i := 0; i < n; i++ {
+			row := vat.RawRowView(i)
+			for k := range row {
+				row[k] *= diagonal.At(i, i)
+			}
+		}
+	} else {
+		vat.Mul(covariance, a.T())
+	}
+
+	// S = A*V*A^T, simetrizada para absorver os erros de arredondamento.
+	var product mat.Dense
+	product.Mul(a, &vat)
+	schur := mat.NewSymDense(r, nil)
+	for k := 0; k < r; k++ {
+		for l := k; l < r; l++ {
+			schur.SetSym(k, l, (product.At(k, l)+product.At(l, k))/2)
+		}
+	}
+	var chol mat.Cholesky
+	if ok := chol.Factorize(schur); !ok || chol.Cond() > maxConditionNumber {
+		return nil, errSingular
+	}
+
+	// Z = S^-1 * A*V.
+	var z mat.Dense
+	if err := chol.SolveTo(&z, vat.T()); err != nil {
+		return nil, errSingular
+	}
+	return &schurFactor{vat: &vat, chol: &chol, z: &z, condition: chol.Cond()}, nil
+}
+
+// solveDense resolve o sistema de Lagrange com o fator de Cholesky denso do complemento de Schur.
+func (f *schurFactor) solveDense(a *mat.Dense, covariance mat.Symmetric, m, c []float64, full bool) (*kktSolution, error) {
+	r, n := a.Dims()
+
+	// λ = S^-1 * (A*m - c) e x = m - V*A^T*λ.
+	residual := mat.NewVecDense(r, nil)
+	residual.MulVec(a, mat.NewVecDense(n, m))
+	for k := 0; k < r && c != nil; k++ {
+		residual.SetVec(k, residual.AtVec(k)-c[k])
+	}
+	lambda := mat.NewVecDense(r, nil)
+	if err := f.chol.SolveVecTo(lambda, residual); err != nil {
+		return nil, errSingular
+	}
+	var correction mat.VecDense
+	correction.MulVec(f.vat, lambda)
+	sol := &kktSolution{x: make([]float64, n), lambda: lambda.RawVector().Data, variances: make([]float64, n), condition: f.condition}
+	for i := range sol.x {
+		sol.x[i] = m[i] - correction.AtVec(i)
+	}
+
+	// Cov(x) = V - V*A^T*Z.
+	if full {
+		cov := mat.NewDense(n, n, nil)
+		cov.Mul(f.vat, f.z)
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				cov.Set(i, j, covariance.At(i, j)-cov.At(i, j))
+			}
+			sol.variances[i] = cov.At(i, i)
+		}
+		sol.covariance = cov.RawMatrix().Data
+		return sol, nil
+	}
+	for i := 0; i < n; i++ {
+		sol.variances[i] = covariance.At(i, i) - mat.Dot(f.vat.RowView(i), f.z.ColView(i))
+	}
+	return sol, nil
+}
+
+// factorSparse fatora o complemento de Schur com V diagonal explorando a esparsidade de A.
+//
+// O complemento de Schur S = A*V*A^T é montado coluna a coluna, tem a estrutura do grafo
+// das restrições (duas restrições são vizinhas se compartilham uma variável) e é fatorado
+// por Cholesky de envelope após a reordenação de Cuthill-McKee reversa, que reduz a largura
+// de banda.
+func factorSparse(a *sparseMatrix, covariance *mat.DiagDense) (*schurFactor, error) {
+	r, n := a.rows, a.cols
+
+	// Monta S = A*V*A^T: cada variável i contribui a_ki*a_li*v_i para o par de restrições (k, l).
+	entries := make([]map[int]float64, r)
+	for k := range entries {
+		entries[k] = make(map[int]float64)
+	}
+	for i := 0; i < n; i++ {
+		rows, values := a.column(i)
+		for p, k := range rows {
+			for q, l := range rows {
+				entries[k][l] += values[p] * values[q] * covariance.At(i, i)
+			}
+		}
+	}
+	factor, err := newEnvelopeCholesky(entries)
+	if err != nil {
+		return nil, err
+	}
+	norm := 0.0
+	for k := range entries {
+		column := 0.0
+		for _, v := range entries[k] {
+			column += math.Abs(v)
+		}
+		norm = math.Max(norm, column)
+	}
+	condition := norm * estimateInverseNorm1(r, factor.solve)
+	if condition > maxConditionNumber {
+		return nil, errSingular
+	}
+	return &schurFactor{sparse: a, envelope: factor, condition: condition}, nil
+}
+
+// solveSparse resolve o sistema de Lagrange com o fator de envelope do complemento de Schur.
+// Cada coluna de Cov(x) custa uma solução com o fator e uma passada por A.
+func (f *schurFactor) solveSparse(covariance mat.Symmetric, m, c []float64, full bool) *kktSolution {
+	a := f.sparse
+	r, n := a.rows, a.cols
+	variances := make([]float64, n)
+	for i := range variances {
+		variances[i] = covariance.At(i, i)
+	}
+
+	// λ = S^-1 * (A*m - c) e x = m - V*A^T*λ.
+	residual := make([]float64, r)
+	a.mulVec(residual, m)
+	for k := 0; k < r && c != nil; k++ {
+		residual[k] -= c[k]
+	}
+	sol := &kktSolution{x: make([]float64, n), lambda: f.envelope.solve(residual), variances: make([]float64, n), condition: f.condition}
+	for i := range sol.x {
+		rows, values := a.column(i)
+		correction := 0.0
+		for p, k := range rows {
+			correction += values[p] * sol.lambda[k]
+		}
+		sol.x[i] = m[i] - variances[i]*correction
+	}
+
+	// Para cada variável i, z = S^-1 * A*V*e_i e a coluna i de Cov(x) é V*e_i - V*A^T*z.
+	if full {
+		sol.covariance = make([]float64, n*n)
+	}
+	column := make([]float64, r)
+	for i := 0; i < n; i++ {
+		for k := range column {
+			column[k] = 0
+		}
+		rows, values := a.column(i)
+		if !full {
+			// Cov(x)_ii = v_i - v_i^2 * a_i^T*S^-1*a_i, com a_i a coluna i de A.
+			sol.variances[i] = variances[i] - variances[i]*variances[i]*f.envelope.quadratic(rows, values)
+			continue
+		}
+		for p, k := range rows {
+			column[k] = values[p] * variances[i]
+		}
+		z := f.envelope.solve(column)
+		for j := 0; j < n; j++ {
+			rowsJ, valuesJ := a.column(j)
+			reduction := 0.0
+			for p, k := range rowsJ {
+				reduction += valuesJ[p] * z[k]
+			}
+			value := -variances[j] * reduction
+			if j == i {
+				value += variances[i]
+			}
+			sol.covariance[j*n+i] = value
+		}
+		sol.variances[i] = sol.covariance[i*n+i]
+	}
+	return sol
+}
+
+// sparseMatrix é uma matriz esparsa armazenada por colunas (CSC).
+type sparseMatrix struct {
+	rows, cols int
+	// colStart[j]..colStart[j+1] delimitam, em rowIndex e values, os coeficientes da coluna j.
+	colStart []int
+	rowIndex []int
+	values   []float64
+}
+
+// newSparseMatrix converte uma matriz densa para o armazenamento por colunas.
+func newSparseMatrix(a *mat.Dense) *sparseMatrix {
+	rows, cols := a.Dims()
+	s := &sparseMatrix{rows: rows, cols: cols, colStart: make([]int, cols+1)}
+	// Conta os não nulos de cada coluna e depois os distribui, percorrendo a matriz por linhas.
+	for k := 0; k < rows; k++ {
+		for j, v := range a.RawRowView(k) {
+			if v != 0 {
+				s.colStart[j+1]++
+			}
+		}
+	}
+	for j := 0; j < cols; j++ {
+		s.colStart[j+1] += s.colStart[j]
+	}
+	s.rowIndex = make([]int, s.colStart[cols])
+	s.values = make([]float64, s.colStart[cols])
+	next := append([]int(nil), s.colStart[:cols]...)
+	for k := 0; k < rows; k++ {
+		for j, v := range a.RawRowView(k) {
+			if v != 0 {
+				s.rowIndex[next[j]], s.values[next[j]] = k, v
+				next[j]++
+			}
+		}
+	}
+	return s
+}
+
+// density retorna a fração de coeficientes não nulos da matriz.
+func (s *sparseMatrix) density() float64 {
+	return float64(len(s.values)) / float64(s.rows*s.cols)
+}
+
+// column retorna os índices das linhas e os coeficientes não nulos da coluna j.
+func (s *sparseMatrix) column(j int) ([]int, []float64) {
+	return s.rowIndex[s.colStart[j]:s.colStart[j+1]], s.values[s.colStart[j]:s.colStart[j+1]]
+}
+
+// mulVec calcula dst = A*x.
+func (s *sparseMatrix) mulVec(dst, x []float64) {
+	for k := range dst {
+		dst[k] = 0
+	}
+	for j := 0; j < s.cols; j++ {
+		rows, values := s.column(j)
+		for p, k := range rows {
+			dst[k] += values[p] * x[j]
+		}
+	}
+}
+
+// sparseRows é uma matriz esparsa armazenada por linhas (CSR). Reconcile monta as restrições
+// nesse formato uma única vez e o usa nos resíduos, no teste nodal e na montagem de A para o
+// método esparso, sem voltar a percorrer a matriz densa.
+type sparseRows struct {
+	rows, cols int
+	// rowStart[k]..rowStart[k+1] delimitam, em colIndex e values, os coeficientes da linha k.
+	rowStart []int
+	colIndex []int
+	values   []float64
+}
+
+// newSparseRows converte uma matriz densa para o armazenamento por linhas.
+func newSparseRows(a *mat.Dense) *sparseRows {
+	rows, cols := a.Dims()
+	s := &sparseRows{rows: rows, cols: cols, rowStart: make([]int, rows+1)}
+	for k := 0; k < rows; k++ {
+		for j, v := range a.RawRowView(k) {
+			if v != 0 {
+				s.colIndex = append(s.colIndex, j)
+				s.values = append(s.values, v)
+			}
+		}
+		s.rowStart[k+1] = len(s.values)
+	}
+	return s
+}
+
+// row retorna os índices das colunas e os coeficientes não nulos da linha k.
+func (s *sparseRows) row(k int) ([]int, []float64) {
+	return s.colIndex[s.rowStart[k]:s.rowStart[k+1]], s.values[s.rowStart[k]:s.rowStart[k+1]]
+}
+
+// reduced monta, por colunas, as restrições como são resolvidas: as linhas fora de dropped
+// seguidas de uma linha x_i = limite por limite ativo (ver withActiveBounds), restritas às
+// colunas indices, na ordem de indices (ver columns).
+func (s *sparseRows) reduced(dropped map[int]bool, active []activeBound, indices []int) *sparseMatrix {
+	position := make([]int, s.cols)
+	for j := range position {
+		position[j] = -1
+	}
+	for k, j := range indices {
+		position[j] = k
+	}
+	kept := make([]int, 0, s.rows)
+	for k := 0; k < s.rows; k++ {
+		if !dropped[k] {
+			kept = append(kept, k)
+		}
+	}
+
+	out := &sparseMatrix{rows: len(kept) + len(active), cols: len(indices), colStart: make([]int, len(indices)+1)}
+	for _, k := range kept {
+		cols, _ := s.row(k)
+		for _, j := range cols {
+			if position[j] >= 0 {
+				out.colStart[position[j]+1]++
+			}
+		}
+	}
+	for _, b := range active {
+		if position[b.index] >= 0 {
+			out.colStart[position[b.index]+1]++
+		}
+	}
+	for j := 0; j < out.cols; j++ {
+		out.colStart[j+1] += out.colStart[j]
+	}
+	out.rowIndex = make([]int, out.colStart[out.cols])
+	out.values = make([]float64, out.colStart[out.cols])
+	next := append([]int(nil), out.colStart[:out.cols]...)
+	add := func(row, j int, v float64) {
+		if l := position[j]; l >= 0 {
+			out.rowIndex[next[l]], out.values[next[l]] = row, v
+			next[l]++
+		}
+	}
+	for row, k := range kept {
+		cols, values := s.row(k)
+		for p, j := range cols {
+			add(row, j, values[p])
+		}
+	}
+	for l, b := range active {
+		add(len(kept)+l, b.index, 1)
+	}
+	return out
+}
+
+// envelopeCholesky é o fator de Cholesky L de uma matriz simétrica esparsa, armazenado por
+// linhas do primeiro coeficiente não nulo até a diagonal (envelope), na ordem permutada.
+type envelopeCholesky struct {
+	// perm[i] é o índice original da linha i permutada; inverse é a permutação inversa.
+	perm, inverse []int
+	// first[i] é a coluna do primeiro coeficiente do envelope da linha i.
+	first []int
+	// rowStart[i] é a posição da linha i em values, que guarda L_i,first[i] .. L_ii.
+	rowStart []int
+	values   []float64
+}
+
+// newEnvelopeCholesky fatora a matriz simétrica descrita por entries (entries[k][l] = S_kl),
+// reordenada por Cuthill-McKee reversa. Retorna errSingular se um pivô for numericamente
+// nulo em relação ao elemento diagonal original.
+func newEnvelopeCholesky(entries []map[int]float64) (*envelopeCholesky, error) {
+	r := len(entries)
+	f := &envelopeCholesky{perm: reverseCuthillMcKee(entries), inverse: make([]int, r), first: make([]int, r), rowStart: make([]int, r+1)}
+	for i, k := range f.perm {
+		f.inverse[k] = i
+	}
+	for i, k := range f.perm {
+		f.first[i] = i
+		for l := range entries[k] {
+			if j := f.inverse[l]; j < f.first[i] {
+				f.first[i] = j
+			}
+		}
+		f.rowStart[i+1] = f.rowStart[i] + i - f.first[i] + 1
+	}
+	f.values = make([]float64, f.rowStart[r])
+	for i, k := range f.perm {
+		for l, v := range entries[k] {
+			if j := f.inverse[l]; j <= i {
+				f.values[f.rowStart[i]+j-f.first[i]] = v
+			}
+		}
+	}
+
+	for i := 0; i < r; i++ {
+		row := f.row(i)
+		diagonal := row[i-f.first[i]]
+		for j := f.first[i]; j <= i; j++ {
+			rowJ := f.row(j)
+			sum := row[j-f.first[i]]
+			for k := max(f.first[i], f.first[j]); k < j; k++ {
+				sum -= row[k-f.first[i]] * rowJ[k-f.first[j]]
+			}
+			if j < i {
+				row[j-f.first[i]] = sum / rowJ[j-f.first[j]]
+				continue
+			}
+			if sum <= diagonal/maxConditionNumber {
+				return nil, errSingular
+			}
+			row[j-f.first[i]] = math.Sqrt(sum)
+		}
+	}
+	return f, nil
+}
+
+// row retorna os coeficientes do envelope da linha i, das colunas first[i] a i.
+func (f *envelopeCholesky) row(i int) []float64 {
+	return f.values[f.rowStart[i]:f.rowStart[i+1]]
+}
+
+// solve resolve S*x = b por substituição direta (L*y = b) e inversa (L^T*x = y).
+func (f *envelopeCholesky) solve(b []float64) []float64 {
+	r := len(f.perm)
+	y := make([]float64, r)
+	for i, k := range f.perm {
+		y[i] = b[k]
+	}
+	for i := 0; i < r; i++ {
+		row := f.row(i)
+		sum := y[i]
+		for j := f.first[i]; j < i; j++ {
+			sum -= row[j-f.first[i]] * y[j]
+		}
+		y[i] = sum / row[i-f.first[i]]
+	}
+	for i := r - 1; i >= 0; i-- {
+		row := f.row(i)
+		y[i] /= row[i-f.first[i]]
+		for j := f.first[i]; j < i; j++ {
+			y[j] -= row[j-f.first[i]] * y[i]
+		}
+	}
+	x := make([]float64, r)
+	for i, k := range f.perm {
+		x[k] = y[i]
+	}
+	return x
+}
+
+// quadratic calcula b^T*S^-1*b = |L^-1*P*b|^2 para um vetor esparso b, com os índices e os
+// valores não nulos informados. A substituição direta começa no primeiro não nulo permutado.
+func (f *envelopeCholesky) quadratic(indices []int, values []float64) float64 {
+	r := len(f.perm)
+	start := r
+	y := make([]float64, r)
+	for p, k := range indices {
+		i := f.inverse[k]
+		y[i] = values[p]
+		start = min(start, i)
+	}
+	sum := 0.0
+	for i := start; i < r; i++ {
+		row := f.row(i)
+		value := y[i]
+		for j := max(f.first[i], start); j < i; j++ {
+			value -= row[j-f.first[i]] * y[j]
+		}
+		y[i] = value / row[i-f.first[i]]
+		sum += y[i] * y[i]
+	}
+	return sum
+}
+
+// reverseCuthillMcKee retorna a ordenação de Cuthill-McKee reversa do grafo da matriz
+// simétrica descrita por entries. Cada componente conexo é percorrido em largura a partir
+// do seu vértice de menor grau, visitando os vizinhos em ordem crescente de grau.
+func reverseCuthillMcKee(entries []map[int]float64) []int {
+	r := len(entries)
+	degree := func(k int) int { return len(entries[k]) }
+	visited := make([]bool, r)
+	order := make([]int, 0, r)
+	byDegree := make([]int, r)
+	for k := range byDegree {
+		byDegree[k] = k
+	}
+	sort.SliceStable(byDegree, func(a, b int) bool { return degree(byDegree[a]) < degree(byDegree[b]) })
+
+	var neighbors []int
+	for _, start := range byDegree {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+		head := len(order)
+		order = append(order, start)
+		for ; head < len(order); head++ {
+			neighbors = neighbors[:0]
+			for l := range entries[order[head]] {
+				if !visited[l] {
+					visited[l] = true
+					neighbors = append(neighbors, l)
+				}
+			}
+			sort.Slice(neighbors, func(a, b int) bool {
+				if degree(neighbors[a]) != degree(neighbors[b]) {
+					return degree(neighbors[a]) < degree(neighbors[b])
+				}
+				return neighbors[a] < neighbors[b]
+			})
+			order = append(order, neighbors...)
+		}
+	}
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
+
+// validateSolver verifica se o método de solução é conhecido.
+func validateSolver(s Solver) error {
+	switch s {
+	case SolverAuto, SolverDense, SolverSparse:
+		return nil
+	}
+	return fmt.Errorf("%w: método de solução desconhecido: %q", ErrInvalidInput, s)
+}
@@ -0,0 +1,205 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// syntheticNetwork gera uma planta sintética com numNodes nós em série. Cada nó k recebe uma
+// alimentação externa e a corrente do nó anterior e envia uma corrente ao nó seguinte (a do
+// último nó sai da planta); a cada 10 nós, um reciclo volta 7 nós. As medições são sorteadas
+// entre 50 e 150, de forma que as restrições não fecham.
+func syntheticNetwork(numNodes int, seed int64) (*mat.Dense, []float64, []float64) {
+	type stream struct{ from, to int }
+	var streams []stream
+	for k := 0; k < numNodes; k++ {
+		streams = append(streams, stream{-1, k})
+		if k+1 < numNodes {
+			streams = append(streams, stream{k, k + 1})
+		} else {
+			streams = append(streams, stream{k, -1})
+		}
+		if k >= 7 && k%10 == 0 {
+			streams = append(streams, stream{k, k - 7})
+		}
+	}
+
+	constraints := mat.NewDense(numNodes, len(streams), nil)
+	rng := rand.New(rand.NewSource(seed))
+	measurements := make([]float64, len(streams))
+	tolerances := make([]float64, len(streams))
+	for j, s := range streams {
+		if s.to >= 0 {
+			constraints.Set(s.to, j, 1)
+		}
+		if s.from >= 0 {
+			constraints.Set(s.from, j, -1)
+		}
+		measurements[j] = 50 + 100*rng.Float64()
+		tolerances[j] = 0.02
+	}
+	return constraints, measurements, tolerances
+}
+
+func TestSolvers(t *testing.T) {
+	constraints, measurements, tolerances := syntheticNetwork(150, 1)
+
+	dense, err := Reconcile(measurements, tolerances, constraints, WithSolver(SolverDense))
+	if err != nil {
+		t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+	}
+	sparse, err := Reconcile(measurements, tolerances, constraints, WithSolver(SolverSparse), WithReconciledCovariance(true))
+	if err != nil {
+		t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+	}
+
+	t.Run("Esparso Igual ao Denso", func(t *testing.T) {
+		if !equal(sparse.Reconciled, dense.Reconciled, 1e-9) || !equal(sparse.Lambda, dense.Lambda, 1e-9) {
+			t.Errorf("Os métodos esparso e denso deveriam produzir a mesma solução")
+		}
+		if !equal(sparse.ReconciledDeviations, dense.ReconciledDeviations, 1e-9) || math.Abs(sparse.Objective-dense.Objective) > 1e-9 {
+			t.Errorf("Os métodos esparso e denso deveriam produzir as mesmas estatísticas")
+		}
+		for i := range dense.Covariance {
+			if !equal(sparse.Covariance[i], dense.Covariance[i], 1e-9) {
+				t.Fatalf("Linha %d da covariância diferente entre os métodos", i)
+			}
+		}
+		for k, r := range sparse.ResidualsAfter {
+			if math.Abs(r) > 1e-9 {
+				t.Fatalf("Resíduo da restrição %d após a reconciliação: %v", k, r)
+			}
+		}
+	})
+
+	t.Run("Sem Covariância Completa", func(t *testing.T) {
+		for _, solver := range []Solver{SolverDense, SolverSparse} {
+			result, err := Reconcile(measurements, tolerances, constraints, WithSolver(solver), WithReconciledCovariance(false))
+			if err != nil {
+				t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+			}
+			if result.Covariance != nil {
+				t.Errorf("%s: a covariância completa não deveria ser calculada", solver)
+			}
+			if !equal(result.ReconciledDeviations, dense.ReconciledDeviations, 1e-9) || !equal(result.StandardizedAdjustments, dense.StandardizedAdjustments, 1e-9) {
+				t.Errorf("%s: a diagonal da covariância deveria ser a mesma", solver)
+			}
+		}
+
+		// Com o método esparso escolhido, a covariância completa é dispensada por padrão.
+		result, err := Reconcile(measurements, tolerances, constraints, WithSolver(SolverSparse))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if result.Covariance != nil || !equal(result.ReconciledDeviations, dense.ReconciledDeviations, 1e-9) {
+			t.Error("O método esparso não deveria calcular a covariância completa por padrão")
+		}
+	})
+
+	t.Run("Restrições por Linhas", func(t *testing.T) {
+		// Sem a linha 1, com um limite ativo em x4 e sem a coluna da variável fixa x2.
+		b := mat.NewDense(3, 5, []float64{1, -1, -1, 0, 0, 0, 0, 1, -1, 0, 0, 1, 0, 1, -1})
+		dropped := map[int]bool{1: true}
+		active := []activeBound{{index: 4, side: BoundLower, value: 0}}
+		measured := []int{0, 1, 3, 4}
+		kept, _ := withoutRows(b, nil, dropped)
+		solved, _ := withActiveBounds(kept, nil, active)
+		expected := newSparseMatrix(columns(solved, measured))
+		got := newSparseRows(b).reduced(dropped, active, measured)
+		if got.rows != expected.rows || got.cols != expected.cols || !slices.Equal(got.colStart, expected.colStart) ||
+			!slices.Equal(got.rowIndex, expected.rowIndex) || !slices.Equal(got.values, expected.values) {
+			t.Errorf("Restrições reduzidas incorretas.\nEsperado: %+v\nObtido:   %+v", expected, got)
+		}
+		if residuals := constraintResiduals(newSparseRows(b), []float64{3, 1, 2, 2, 2}, []float64{0, 0, 1}); !equal(residuals, []float64{0, 0, 0}, 0) {
+			t.Errorf("Resíduos incorretos: %v", residuals)
+		}
+	})
+
+	t.Run("Esparso Singular", func(t *testing.T) {
+		_, err := Reconcile([]float64{100, 100}, []float64{0.01, 0.01}, mat.NewDense(2, 2, []float64{1, 1, 1, 1}), WithSolver(SolverSparse))
+		if err == nil {
+			t.Error("Esperava-se um erro para restrições dependentes, mas nenhum foi retornado")
+		}
+	})
+
+	t.Run("Esparso Não Aplicável", func(t *testing.T) {
+		if _, err := Reconcile(measurements, tolerances, constraints, WithSolver(SolverSparse), WithUnmeasured([]int{3})); err == nil {
+			t.Error("O método esparso não deveria aceitar variáveis não medidas")
+		}
+		if _, err := Reconcile(measurements, tolerances, constraints, WithSolver(SolverSparse),
+			WithCovariances([]CovarianceEntry{{Row: 0, Col: 1, Value: 0.1}})); err == nil {
+			t.Error("O método esparso não deveria aceitar covariâncias entre medições")
+		}
+		if _, err := Reconcile(measurements, tolerances, constraints, WithSolver("lu")); err == nil {
+			t.Error("Um método de solução desconhecido deveria gerar um erro")
+		}
+	})
+}
+
+// solveByInverse resolve o sistema de Lagrange como Reconcile fazia antes da fatoração do
+// complemento de Schur: monta a matriz aumentada [W A^T; A 0], inverte-a explicitamente e
+// copia Cov(x), o bloco superior esquerdo da inversa. É a referência de BenchmarkReconcile.
+func solveByInverse(constraints *mat.Dense, measurements, tolerances []float64) ([]float64, []Values, error) {
+	numConstraints, n := constraints.Dims()
+	total := n + numConstraints
+	lagrange := mat.NewDense(total, total, nil)
+	rhs := make([]float64, total)
+	for i, m := range measurements {
+		sigma := math.Abs(m) * tolerances[i]
+		lagrange.Set(i, i, 1/(sigma*sigma))
+		rhs[i] = m / (sigma * sigma)
+	}
+	lagrange.Slice(0, n, n, total).(*mat.Dense).Copy(constraints.T())
+	lagrange.Slice(n, total, 0, n).(*mat.Dense).Copy(constraints)
+
+	var inverse mat.Dense
+	if err := inverse.Inverse(lagrange); err != nil {
+		return nil, nil, err
+	}
+	var solution mat.VecDense
+	solution.MulVec(&inverse, mat.NewVecDense(total, rhs))
+	covariance := make([]Values, n)
+	for i := range covariance {
+		covariance[i] = make(Values, n)
+		for j := range covariance[i] {
+			covariance[i][j] = inverse.At(i, j)
+		}
+	}
+	return solution.RawVector().Data[:n], covariance, nil
+}
+
+// BenchmarkReconcile compara os métodos denso e esparso com a inversão explícita da matriz
+// aumentada (solveByInverse), o método anterior, em plantas sintéticas de até cerca de 5.000
+// correntes. A referência mede apenas a solução do sistema, que domina o custo. Na maior
+// planta a inversão leva mais de um minuto e o método denso alguns segundos; use
+// -benchtime=1x para uma comparação rápida.
+func BenchmarkReconcile(b *testing.B) {
+	for _, numNodes := range []int{250, 1000, 2400} {
+		constraints, measurements, tolerances := syntheticNetwork(numNodes, 1)
+		_, numStreams := constraints.Dims()
+		b.Run(fmt.Sprintf("inversa/correntes=%d/covariância=true", numStreams), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, err := solveByInverse(constraints, measurements, tolerances); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		for _, solver := range []Solver{SolverDense, SolverSparse} {
+			for _, full := range []bool{true, false} {
+				name := fmt.Sprintf("%s/correntes=%d/covariância=%v", solver, numStreams, full)
+				b.Run(name, func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						if _, err := Reconcile(measurements, tolerances, constraints, WithSolver(solver), WithReconciledCovariance(full)); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// minSteadyStateWindow é o menor número de amostras por variável aceito pelo teste de regime
+// permanente; com menos, a variância da inclinação não tem graus de liberdade suficientes.
+const minSteadyStateWindow = 5
+
+// TagSteadyState é o resultado do teste de regime permanente de uma variável.
+type TagSteadyState struct {
+	// RStatistic é a razão entre a variância da janela e a metade do quadrado médio das
+	// diferenças sucessivas (estatística R em lote). Perto de 1 em regime permanente; cresce com
+	// tendências, degraus e oscilações lentas.
+	RStatistic float64 `json:"rStatistic"`
+	// Slope é a inclinação da reta ajustada aos valores da janela, por amostra.
+	Slope float64 `json:"slope"`
+	// SlopeStatistic é a inclinação dividida pelo seu erro padrão (estatística t).
+	SlopeStatistic float64 `json:"slopeStatistic"`
+	// Steady indica se a variável passou nos dois testes.
+	Steady bool `json:"steady"`
+}
+
+// SteadyStateResult é o resultado do teste de regime permanente de uma janela de valores.
+type SteadyStateResult struct {
+	// Confidence é o nível de confiança global usado no teste.
+	Confidence float64 `json:"confidence"`
+	// CriticalR é o valor crítico da estatística R, já com a correção de Šidák.
+	CriticalR float64 `json:"criticalR"`
+	// CriticalSlope é o valor crítico bilateral da estatística t da inclinação, já com a correção de Šidák.
+	CriticalSlope float64 `json:"criticalSlope"`
+	// Tags são os resultados de cada variável.
+	Tags []TagSteadyState `json:"tags"`
+	// Transient são os índices das variáveis reprovadas, em ordem crescente.
+	Transient []int `json:"transient"`
+	// Steady indica se todas as variáveis estão em regime permanente.
+	Steady bool `json:"steady"`
+}
+
+// SteadyStateTest verifica se uma janela de valores recentes de cada variável está em regime
+// permanente. window[i] é a série de valores da variável i, igualmente espaçados no tempo e
+// todos com o mesmo número de amostras. Cada variável passa por dois testes:
+//
+//   - Razão de variâncias (estatística R em lote): R = s^2 / (δ^2/2), onde s^2 é a variância da
+//     janela e δ^2 o quadrado médio das diferenças sucessivas. Com ruído branco em torno de um
+//     valor constante, as duas estimam a mesma variância; uma tendência ou uma oscilação lenta
+//     infla s^2 mas quase não altera δ^2. O teste usa a razão de von Neumann η = δ^2/s^2 = 2/R,
+//     com média 2 e variância 4(N-2)/((N-1)(N+1)) em regime permanente, e reprova a variável
+//     quando η é significativamente menor que 2 (teste unilateral pela aproximação normal).
+//   - Inclinação: a inclinação da reta de mínimos quadrados é comparada com o seu erro padrão
+//     pela distribuição t de Student com N-2 graus de liberdade (teste bilateral).
+//
+// Como 2n testes são feitos ao mesmo tempo, o nível de significância de cada um é corrigido
+// pela fórmula de Šidák, para que a probabilidade de um falso alarme em qualquer variável de
+// uma planta em regime permanente seja 1 - confidence. Uma variável constante está em regime
+// permanente.
+func SteadyStateTest(window [][]float64, confidence float64) (*SteadyStateResult, error) {
+	if len(window) == 0 {
+		return nil, fmt.Errorf("%w: a janela de valores não pode estar vazia", ErrInvalidInput)
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return nil, fmt.Errorf("%w: o nível de confiança deve estar entre 0 e 1, obtido %v", ErrInvalidInput, confidence)
+	}
+	numSamples := len(window[0])
+	if numSamples < minSteadyStateWindow {
+		return nil, fmt.Errorf("%w: a janela deve ter ao menos %d amostras por variável, obtido %d", ErrInvalidInput, minSteadyStateWindow, numSamples)
+	}
+	for i, series := range window {
+		if len(series) != numSamples {
+			return nil, fmt.Errorf("%w: janela da variável %d (%d) e da variável 0 (%d)", ErrDimensionMismatch, i, len(series), numSamples)
+		}
+		for _, v := range series {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("%w: a janela da variável %d contém um valor inválido: %v", ErrInvalidInput, i, v)
+			}
+		}
+	}
+
+	n := float64(numSamples)
+	beta := 1 - math.Pow(confidence, 1/float64(2*len(window)))
+	ratioDeviation := math.Sqrt(4 * (n - 2) / ((n - 1) * (n + 1)))
+	// η crítico, abaixo do qual a variável é reprovada; limitado a um valor positivo para que
+	// o R crítico seja finito em janelas curtas.
+	criticalRatio := math.Max(2+distuv.UnitNormal.Quantile(beta)*ratioDeviation, 1e-12)
+	criticalSlope := distuv.StudentsT{Mu: 0, Sigma: 1, Nu: n - 2}.Quantile(1 - beta/2)
+
+	result := &SteadyStateResult{
+		Confidence:    confidence,
+		CriticalR:     2 / criticalRatio,
+		CriticalSlope: criticalSlope,
+		Tags:          make([]TagSteadyState, len(window)),
+		Transient:     []int{},
+		Steady:        true,
+	}
+	for i, series := range window {
+		tag := steadyStateStatistics(series)
+		tag.Steady = tag.RStatistic <= result.CriticalR && math.Abs(tag.SlopeStatistic) <= criticalSlope
+		if !tag.Steady {
+			result.Transient = append(result.Transient, i)
+			result.Steady = false
+		}
+		result.Tags[i] = tag
+	}
+	return result, nil
+}
+
+// steadyStateStatistics calcula a estatística R e a inclinação de uma série. Uma série
+// constante tem R = 1 e inclinação nula.
+func steadyStateStatistics(series []float64) TagSteadyState {
+	n := float64(len(series))
+	var mean float64
+	for _, v := range series {
+		mean += v
+	}
+	mean /= n
+
+	// Somas centradas em torno da média e do instante médio (N-1)/2.
+	var sumSquares, successive, sxy float64
+	center := (n - 1) / 2
+	for k, v := range series {
+		d := v - mean
+		sumSquares += d * d
+		sxy += (float64(k) - center) * d
+		if k > 0 {
+			step := v - series[k-1]
+			successive += step * step
+		}
+	}
+	tag := TagSteadyState{RStatistic: 1}
+	if sumSquares == 0 {
+		return tag
+	}
+	// s^2 = Σ(v-média)^2/(N-1) e δ^2 = Σ(Δv)^2/(N-1), logo R = 2*Σ(v-média)^2/Σ(Δv)^2. Uma
+	// série não constante tem ao menos uma diferença sucessiva não nula.
+	tag.RStatistic = 2 * sumSquares / successive
+
+	sxx := n * (n*n - 1) / 12
+	tag.Slope = sxy / sxx
+	residualVariance := (sumSquares - tag.Slope*sxy) / (n - 2)
+	if residualVariance <= 0 {
+		// Uma reta perfeita: a inclinação é certamente diferente de zero. Recebe o maior valor
+		// finito, que ainda pode ser codificado em JSON.
+		tag.SlopeStatistic = math.Copysign(math.MaxFloat64, tag.Slope)
+		return tag
+	}
+	tag.SlopeStatistic = tag.Slope / math.Sqrt(residualVariance/sxx)
+	return tag
+}
@@ -0,0 +1,86 @@
+package reconciliation
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestSteadyStateTest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	noisy := func(mean float64, trend func(k int) float64) []float64 {
+		series := make([]float64, 60)
+		for k := range series {
+			series[k] = mean + trend(k) + rng.NormFloat64()
+		}
+		return series
+	}
+	flat := func(int) float64 { return 0 }
+
+	t.Run("Regime Permanente", func(t *testing.T) {
+		constant := make([]float64, 60)
+		for k := range constant {
+			constant[k] = 5
+		}
+		window := [][]float64{noisy(100, flat), noisy(60, flat), noisy(40, flat), constant}
+		result, err := SteadyStateTest(window, 0.95)
+		if err != nil {
+			t.Fatalf("A função SteadyStateTest retornou um erro inesperado: %v", err)
+		}
+		if !result.Steady || len(result.Transient) != 0 || len(result.Tags) != 4 {
+			t.Errorf("Uma janela em regime permanente deveria passar no teste: %+v", result)
+		}
+		if result.Tags[3].RStatistic != 1 || result.Tags[3].Slope != 0 {
+			t.Errorf("Uma variável constante deveria ter R = 1 e inclinação nula: %+v", result.Tags[3])
+		}
+		for i, tag := range result.Tags[:3] {
+			if math.Abs(tag.RStatistic-1) > 0.5 {
+				t.Errorf("A estatística R da variável %d deveria estar perto de 1: %v", i, tag.RStatistic)
+			}
+		}
+	})
+
+	t.Run("Transitório", func(t *testing.T) {
+		ramp := noisy(100, func(k int) float64 { return 0.2 * float64(k) })
+		step := noisy(60, func(k int) float64 {
+			if k >= 30 {
+				return 5
+			}
+			return 0
+		})
+		oscillation := noisy(40, func(k int) float64 { return 4 * math.Sin(2*math.Pi*float64(k)/60) })
+		window := [][]float64{noisy(10, flat), ramp, step, oscillation}
+		result, err := SteadyStateTest(window, 0.95)
+		if err != nil {
+			t.Fatalf("A função SteadyStateTest retornou um erro inesperado: %v", err)
+		}
+		if result.Steady || !slices.Equal(result.Transient, []int{1, 2, 3}) {
+			t.Errorf("As variáveis 1, 2 e 3 deveriam ser reprovadas: %v", result.Transient)
+		}
+		if result.Tags[1].Slope < 0.15 || result.Tags[1].Slope > 0.25 {
+			t.Errorf("A inclinação da rampa deveria ser cerca de 0.2: %v", result.Tags[1].Slope)
+		}
+		if result.Tags[3].RStatistic <= result.CriticalR {
+			t.Errorf("A oscilação deveria ser reprovada pela estatística R: %v", result.Tags[3].RStatistic)
+		}
+	})
+
+	t.Run("Entradas Inválidas", func(t *testing.T) {
+		if _, err := SteadyStateTest(nil, 0.95); err == nil {
+			t.Error("Uma janela vazia deveria gerar um erro")
+		}
+		if _, err := SteadyStateTest([][]float64{{1, 2, 3}}, 0.95); err == nil {
+			t.Error("Uma janela curta deveria gerar um erro")
+		}
+		if _, err := SteadyStateTest([][]float64{{1, 2, 3, 4, 5}, {1, 2, 3, 4}}, 0.95); err == nil {
+			t.Error("Janelas de comprimentos diferentes deveriam gerar um erro")
+		}
+		if _, err := SteadyStateTest([][]float64{{1, 2, math.NaN(), 4, 5}}, 0.95); err == nil {
+			t.Error("Um valor inválido deveria gerar um erro")
+		}
+		if _, err := SteadyStateTest([][]float64{{1, 2, 3, 4, 5}}, 1); err == nil {
+			t.Error("Um nível de confiança inválido deveria gerar um erro")
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+)
+
+// UncertaintyMode define como a tolerância de uma medição é convertida em desvio padrão (σ).
+type UncertaintyMode string
+
+const (
+	// UncertaintyRelative interpreta a tolerância como um desvio padrão relativo: σ = |m| * p.
+	// É o modo padrão, compatível com o comportamento original de Reconcile.
+	UncertaintyRelative UncertaintyMode = "relative"
+	// UncertaintyAbsolute interpreta a tolerância como o próprio desvio padrão, na unidade da medição: σ = p.
+	UncertaintyAbsolute UncertaintyMode = "absolute"
+	// UncertaintySpan interpreta a tolerância como uma fração da faixa (span) do instrumento:
+	// σ = p * (RangeMax - RangeMin).
+	UncertaintySpan UncertaintyMode = "span"
+)
+
+// Uncertainty descreve a incerteza de uma medição individual.
+type Uncertainty struct {
+	// Mode é o modo de interpretação da tolerância. Se vazio, usa o modo global (WithUncertaintyMode).
+	Mode UncertaintyMode `json:"mode,omitempty"`
+	// RangeMin é o limite inferior da faixa do instrumento, usado no modo UncertaintySpan.
+	RangeMin float64 `json:"rangeMin,omitempty"`
+	// RangeMax é o limite superior da faixa do instrumento, usado no modo UncertaintySpan.
+	RangeMax float64 `json:"rangeMax,omitempty"`
+	// CoverageFactor é o fator de abrangência (k) da tolerância desta medição.
+	// Se zero, usa o fator global (WithCoverageFactor).
+	CoverageFactor float64 `json:"coverageFactor,omitempty"`
+}
+
+// Fatores de abrangência usuais para tolerâncias declaradas como intervalos de confiança
+// de uma distribuição normal.
+const (
+	// CoverageOneSigma trata a tolerância como o próprio desvio padrão (~68,3%). É o padrão.
+	CoverageOneSigma = 1.0
+	// CoverageTwoSigma corresponde a uma faixa de 95% (2σ).
+	CoverageTwoSigma = 2.0
+	// CoverageThreeSigma corresponde a uma faixa de 99,7% (3σ).
+	CoverageThreeSigma = 3.0
+)
+
+// deviation converte a tolerância de uma medição em desvio padrão, de acordo com o modo de
+// incerteza, e divide o resultado pelo fator de abrangência: uma tolerância de folha de dados
+// declarada como faixa de 95% (k = 2) corresponde a σ = tolerância / 2. O desvio padrão
+// retornado é sempre positivo: leituras negativas (ex: fluxo reverso) no modo relativo usam
+// o módulo da medição, e desvios nulos resultam em erro.
+func deviation(index int, measurement, tolerance float64, u Uncertainty) (float64, error) {
+	if tolerance < 0 {
+		return 0, fmt.Errorf("%w: a tolerância da medição %d é negativa: %v", ErrInvalidInput, index, tolerance)
+	}
+	if math.IsNaN(tolerance) || math.IsInf(tolerance, 0) {
+		return 0, fmt.Errorf("%w: a tolerância da medição %d é inválida: %v", ErrInvalidInput, index, tolerance)
+	}
+	if u.CoverageFactor <= 0 {
+		return 0, fmt.Errorf("%w: o fator de abrangência da medição %d deve ser positivo: %v", ErrInvalidInput, index, u.CoverageFactor)
+	}
+
+	var sigma float64
+	switch u.Mode {
+	case UncertaintyRelative:
+		sigma = math.Abs(measurement) * tolerance
+		if measurement == 0 {
+			return 0, fmt.Errorf("%w: a medição %d é zero e não admite tolerância relativa, use o modo absoluto ou de faixa", ErrInvalidInput, index)
+		}
+	case UncertaintyAbsolute:
+		sigma = tolerance
+	case UncertaintySpan:
+		if u.RangeMax <= u.RangeMin {
+			return 0, fmt.Errorf("%w: a faixa do instrumento da medição %d é inválida: [%v, %v]", ErrInvalidInput, index, u.RangeMin, u.RangeMax)
+		}
+		sigma = tolerance * (u.RangeMax - u.RangeMin)
+	default:
+		return 0, fmt.Errorf("%w: modo de incerteza desconhecido para a medição %d: %q", ErrInvalidInput, index, u.Mode)
+	}
+
+	if sigma == 0 {
+		// Evita divisão por zero ao construir a matriz de pesos.
+		return 0, fmt.Errorf("%w: a tolerância absoluta para a medição %d é zero, causando divisão por zero", ErrInvalidInput, index)
+	}
+	return sigma / u.CoverageFactor, nil
+}
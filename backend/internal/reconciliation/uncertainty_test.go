@@ -0,0 +1,140 @@
+package reconciliation
+
+import (
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestUncertaintyModes(t *testing.T) {
+	constraints := mat.NewDense(1, 3, []float64{1, -1, -1})
+
+	t.Run("Modos Global e por Medição", func(t *testing.T) {
+		measurements := []float64{161, 79, 80}
+		cases := []struct {
+			name     string
+			tol      []float64
+			opts     []Option
+			expected []float64
+		}{
+			{"Relativo", []float64{0.05, 0.01, 0.01}, nil, []float64{8.05, 0.79, 0.8}},
+			{"Absoluto", []float64{8, 1, 1}, []Option{WithUncertaintyMode(UncertaintyAbsolute)}, []float64{8, 1, 1}},
+			{"Faixa", []float64{0.02, 0.01, 0.01}, []Option{WithUncertainties([]Uncertainty{
+				{Mode: UncertaintySpan, RangeMin: 0, RangeMax: 200},
+				{Mode: UncertaintySpan, RangeMin: 0, RangeMax: 100},
+				{Mode: UncertaintySpan, RangeMin: 50, RangeMax: 150},
+			})}, []float64{4, 1, 1}},
+			// O terceiro elemento não define o modo e herda o modo global de faixa.
+			{"Misto", []float64{0.05, 1, 0.01}, []Option{WithUncertaintyMode(UncertaintySpan), WithUncertainties([]Uncertainty{
+				{Mode: UncertaintyRelative},
+				{Mode: UncertaintyAbsolute},
+				{RangeMin: 0, RangeMax: 100},
+			})}, []float64{8.05, 1, 1}},
+		}
+		for _, c := range cases {
+			result, err := Reconcile(measurements, c.tol, constraints, c.opts...)
+			if err != nil {
+				t.Fatalf("%s: a função Reconcile retornou um erro inesperado: %v", c.name, err)
+			}
+			if !equal(result.Deviations, c.expected, 1e-9) {
+				t.Errorf("%s: desvios padrão incorretos.\nEsperado: %v\nObtido:   %v", c.name, c.expected, result.Deviations)
+			}
+		}
+	})
+
+	t.Run("Leitura Zero no Modo Absoluto", func(t *testing.T) {
+		// Uma corrente parada (leitura zero) é válida quando σ é absoluto.
+		result, err := Reconcile([]float64{80, 79, 0}, []float64{1, 1, 0.5}, constraints, WithUncertaintyMode(UncertaintyAbsolute))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.ResidualsAfter, []float64{0}, 1e-9) {
+			t.Errorf("As restrições não foram satisfeitas: %v", result.ResidualsAfter)
+		}
+
+		// No modo relativo, a mesma leitura zero é rejeitada com uma mensagem clara.
+		if _, err := Reconcile([]float64{80, 79, 0}, []float64{0.01, 0.01, 0.01}, constraints); err == nil {
+			t.Error("Esperava-se um erro para uma leitura zero no modo relativo, mas nenhum foi retornado")
+		}
+	})
+
+	t.Run("Leitura Negativa no Modo Relativo", func(t *testing.T) {
+		// Um fluxo reverso tem leitura negativa, mas o seu desvio padrão continua positivo.
+		result, err := Reconcile([]float64{70, 80, -9}, []float64{0.01, 0.01, 0.1}, constraints)
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if result.Deviations[2] <= 0 {
+			t.Errorf("O desvio padrão deveria ser positivo: %v", result.Deviations[2])
+		}
+	})
+
+	t.Run("Entradas Inválidas", func(t *testing.T) {
+		measurements := []float64{161, 79, 80}
+		tolerances := []float64{0.05, 0.01, 0.01}
+		invalid := map[string][]Option{
+			"Modo Desconhecido":     {WithUncertaintyMode("percentil")},
+			"Faixa Inválida":        {WithUncertainties([]Uncertainty{{Mode: UncertaintySpan, RangeMin: 10, RangeMax: 10}, {}, {}})},
+			"Dimensão Incompatível": {WithUncertainties([]Uncertainty{{}})},
+		}
+		for name, opts := range invalid {
+			if _, err := Reconcile(measurements, tolerances, constraints, opts...); err == nil {
+				t.Errorf("%s: esperava-se um erro, mas nenhum foi retornado", name)
+			}
+		}
+		if _, err := Reconcile(measurements, []float64{0.05, -0.01, 0.01}, constraints); err == nil {
+			t.Error("Esperava-se um erro para uma tolerância negativa, mas nenhum foi retornado")
+		}
+	})
+}
+
+func TestCoverageFactor(t *testing.T) {
+	measurements := []float64{161, 79, 80}
+	tolerances := []float64{0.05, 0.01, 0.01}
+	constraints := mat.NewDense(1, 3, []float64{1, -1, -1})
+
+	base, err := Reconcile(measurements, tolerances, constraints)
+	if err != nil {
+		t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+	}
+
+	t.Run("Fator Global", func(t *testing.T) {
+		result, err := Reconcile(measurements, tolerances, constraints, WithCoverageFactor(CoverageTwoSigma))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Deviations, []float64{4.025, 0.395, 0.4}, 1e-9) {
+			t.Errorf("Desvios padrão efetivos incorretos: %v", result.Deviations)
+		}
+		// Escalar todos os desvios igualmente não altera a solução, mas multiplica a função objetivo por k^2.
+		if !equal(result.Reconciled, base.Reconciled, 1e-9) {
+			t.Errorf("Os valores reconciliados não deveriam mudar.\nEsperado: %v\nObtido:   %v", base.Reconciled, result.Reconciled)
+		}
+		if !equal([]float64{result.Objective}, []float64{4 * base.Objective}, 1e-9) {
+			t.Errorf("A função objetivo deveria ser multiplicada por 4: %v", result.Objective)
+		}
+	})
+
+	t.Run("Fator por Medição", func(t *testing.T) {
+		result, err := Reconcile(measurements, tolerances, constraints, WithCoverageFactor(CoverageTwoSigma), WithUncertainties([]Uncertainty{
+			{CoverageFactor: CoverageThreeSigma},
+			{},
+			{CoverageFactor: CoverageOneSigma},
+		}))
+		if err != nil {
+			t.Fatalf("A função Reconcile retornou um erro inesperado: %v", err)
+		}
+		if !equal(result.Deviations, []float64{8.05 / 3, 0.395, 0.8}, 1e-9) {
+			t.Errorf("Desvios padrão efetivos incorretos: %v", result.Deviations)
+		}
+	})
+
+	t.Run("Fator Inválido", func(t *testing.T) {
+		if _, err := Reconcile(measurements, tolerances, constraints, WithCoverageFactor(-2)); err == nil {
+			t.Error("Esperava-se um erro para um fator global negativo, mas nenhum foi retornado")
+		}
+		if _, err := Reconcile(measurements, tolerances, constraints, WithUncertainties([]Uncertainty{{CoverageFactor: -1}, {}, {}})); err == nil {
+			t.Error("Esperava-se um erro para um fator por medição negativo, mas nenhum foi retornado")
+		}
+	})
+}
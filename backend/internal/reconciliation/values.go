@@ -0,0 +1,47 @@
+package reconciliation
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Values é um vetor de valores em que NaN representa um valor ausente, como a medição de
+// uma variável não medida ou a estimativa de uma variável não observável. Em JSON, os
+// valores ausentes (NaN e ±Inf) são serializados como null, e null é lido como NaN.
+type Values []float64
+
+// MarshalJSON serializa o vetor, escrevendo null no lugar dos valores ausentes.
+func (v Values) MarshalJSON() ([]byte, error) {
+	if v == nil {
+		return []byte("null"), nil
+	}
+	out := make([]*float64, len(v))
+	for i := range v {
+		if !math.IsNaN(v[i]) && !math.IsInf(v[i], 0) {
+			out[i] = &v[i]
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON lê o vetor, convertendo os elementos null em NaN.
+func (v *Values) UnmarshalJSON(data []byte) error {
+	var in []*float64
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in == nil {
+		*v = nil
+		return nil
+	}
+	out := make(Values, len(in))
+	for i, x := range in {
+		if x == nil {
+			out[i] = math.NaN()
+		} else {
+			out[i] = *x
+		}
+	}
+	*v = out
+	return nil
+}
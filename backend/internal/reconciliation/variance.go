@@ -0,0 +1,295 @@
+package reconciliation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// minVarianceSamples é o menor número de vetores do histórico aceito pela estimação das
+// variâncias; com menos, a covariância amostral não tem graus de liberdade.
+const minVarianceSamples = 2
+
+// identifiabilityTolerance é a norma, na base do núcleo da matriz do método indireto, abaixo da
+// qual a variância de uma medição é considerada identificável.
+const identifiabilityTolerance = 1e-8
+
+// VarianceEstimate é o resultado da estimação das variâncias dos erros de medição a partir de um
+// histórico. Os desvios padrão são na unidade das medições; as variáveis não medidas não têm
+// estimativa (NaN, null em JSON).
+type VarianceEstimate struct {
+	// Samples é o número de vetores do histórico.
+	Samples int `json:"samples"`
+	// Confidence é o nível de confiança dos intervalos do método direto.
+	Confidence float64 `json:"confidence"`
+	// Means são as médias das medições no histórico.
+	Means Values `json:"means"`
+	// Configured são os desvios padrão efetivos das tolerâncias configuradas, calculados sobre as
+	// médias como em Reconcile. Variáveis fixas têm desvio zero.
+	Configured Values `json:"configured"`
+	// Direct são os desvios padrão amostrais de cada medição (método direto).
+	Direct Values `json:"direct"`
+	// DirectLower e DirectUpper são os limites do intervalo de confiança de cada desvio padrão
+	// direto, pela distribuição qui-quadrado com Samples-1 graus de liberdade.
+	DirectLower Values `json:"directLower"`
+	DirectUpper Values `json:"directUpper"`
+	// DirectCovariance é a matriz de covariância amostral das medições (método direto).
+	DirectCovariance []Values `json:"directCovariance"`
+	// Indirect são os desvios padrão estimados pelos resíduos das restrições (método indireto).
+	// As medições cuja variância não é identificável pelas restrições não têm estimativa (NaN).
+	Indirect Values `json:"indirect"`
+	// Unidentifiable são os índices das medições sem estimativa indireta, em ordem crescente.
+	Unidentifiable []int `json:"unidentifiable"`
+	// Suggested são os desvios padrão sugeridos: o indireto quando identificável, senão o direto.
+	Suggested Values `json:"suggested"`
+	// Ratios são as razões entre o desvio sugerido e o configurado. Acima de 1, a tolerância
+	// configurada é otimista; abaixo, pessimista. Sem desvio configurado, não há razão (NaN).
+	Ratios Values `json:"ratios"`
+	// SuggestedTolerances são as tolerâncias que reproduzem os desvios sugeridos, no modo de
+	// incerteza e com o fator de abrangência de cada medição. NaN com a matriz de covariância
+	// completa, que dispensa as tolerâncias, e no modo relativo com média zero.
+	SuggestedTolerances Values `json:"suggestedTolerances"`
+}
+
+// EstimateVariances estima a covariância dos erros de medição a partir de um histórico de
+// vetores de medição, history[t][i] sendo a medição i no instante t, e compara os desvios
+// padrão estimados com os das tolerâncias configuradas. Dois métodos são usados:
+//
+//   - Direto: a covariância amostral das medições. Só é válido em regime permanente: a
+//     variação do processo entre as amostras é confundida com o erro de medição.
+//   - Indireto (Almasy e Mah; Keller et al.): os resíduos das restrições r_t = P*B_M*m_t, com a
+//     projeção P das variáveis não medidas, não dependem do estado do processo, pois os valores
+//     verdadeiros satisfazem as restrições em todo instante. Com erros independentes,
+//     Cov(r) = A*V*A^T, com A = P*B_M, é linear nas variâncias: cada elemento (k, l), k <= l,
+//     da covariância amostral dos resíduos fornece uma equação Σ_i A_ki*A_li*σ_i^2 = S_kl,
+//     resolvidas por mínimos quadrados. Uma variância é identificável quando não pertence ao
+//     núcleo do sistema (ex: duas medições em série de uma só restrição só têm a soma das
+//     variâncias identificável). Estimativas negativas, efeito do ruído amostral, viram zero.
+//
+// As médias são subtraídas nos dois métodos, o que também elimina o lado direito das restrições
+// e os erros sistemáticos constantes. Os valores das variáveis não medidas (WithUnmeasured) são
+// ignorados; as demais medições devem ser finitas. As tolerâncias e as opções de incerteza são
+// as de Reconcile, e WithConfidence define o nível dos intervalos.
+func EstimateVariances(history [][]float64, tolerances []float64, constraints *mat.Dense, opts ...Option) (*VarianceEstimate, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	numSamples := len(history)
+	if numSamples < minVarianceSamples {
+		return nil, fmt.Errorf("%w: o histórico deve ter ao menos %d vetores de medição, obtido %d", ErrInvalidInput, minVarianceSamples, numSamples)
+	}
+	_, n := constraints.Dims()
+	if len(tolerances) != n {
+		return nil, fmt.Errorf("%w: colunas das restrições (%d) e tolerâncias (%d)", ErrDimensionMismatch, n, len(tolerances))
+	}
+	if o.uncertainties != nil && len(o.uncertainties) != n {
+		return nil, fmt.Errorf("%w: colunas das restrições (%d) e incertezas (%d)", ErrDimensionMismatch, n, len(o.uncertainties))
+	}
+	if err := validateCovariance(o, n); err != nil {
+		return nil, err
+	}
+	for i := range o.unmeasured {
+		if i < 0 || i >= n {
+			return nil, fmt.Errorf("%w: índice de variável não medida fora do intervalo: %d", ErrInvalidInput, i)
+		}
+	}
+	var measured []int
+	for i := 0; i < n; i++ {
+		if !o.unmeasured[i] {
+			measured = append(measured, i)
+		}
+	}
+	if len(measured) == 0 {
+		return nil, fmt.Errorf("%w: ao menos uma variável deve ser medida", ErrInvalidInput)
+	}
+	for t, m := range history {
+		if len(m) != n {
+			return nil, fmt.Errorf("%w: vetor %d do histórico (%d) e colunas das restrições (%d)", ErrDimensionMismatch, t, len(m), n)
+		}
+		for _, i := range measured {
+			if math.IsNaN(m[i]) || math.IsInf(m[i], 0) {
+				return nil, fmt.Errorf("%w: o vetor %d do histórico tem um valor inválido na medição %d: %v", ErrInvalidInput, t, i, m[i])
+			}
+		}
+	}
+
+	est := &VarianceEstimate{
+		Samples:             numSamples,
+		Confidence:          o.confidence,
+		Means:               nanValues(n),
+		Configured:          nanValues(n),
+		Direct:              nanValues(n),
+		DirectLower:         nanValues(n),
+		DirectUpper:         nanValues(n),
+		DirectCovariance:    make([]Values, n),
+		Indirect:            nanValues(n),
+		Unidentifiable:      []int{},
+		Suggested:           nanValues(n),
+		Ratios:              nanValues(n),
+		SuggestedTolerances: nanValues(n),
+	}
+
+	// Método direto: médias e covariância amostral das medições.
+	dof := float64(numSamples - 1)
+	for _, i := range measured {
+		var mean float64
+		for _, m := range history {
+			mean += m[i]
+		}
+		est.Means[i] = mean / float64(numSamples)
+	}
+	for i := range est.DirectCovariance {
+		est.DirectCovariance[i] = nanValues(n)
+	}
+	for _, i := range measured {
+		for _, j := range measured {
+			var cov float64
+			for _, m := range history {
+				cov += (m[i] - est.Means[i]) * (m[j] - est.Means[j])
+			}
+			est.DirectCovariance[i][j] = cov / dof
+		}
+	}
+	alpha := 1 - o.confidence
+	chi := distuv.ChiSquared{K: dof}
+	upperQuantile, lowerQuantile := chi.Quantile(1-alpha/2), chi.Quantile(alpha/2)
+	for _, i := range measured {
+		variance := est.DirectCovariance[i][i]
+		est.Direct[i] = math.Sqrt(variance)
+		est.DirectLower[i] = math.Sqrt(dof * variance / upperQuantile)
+		est.DirectUpper[i] = math.Sqrt(dof * variance / lowerQuantile)
+	}
+
+	// Método indireto: variâncias a partir da covariância amostral dos resíduos projetados.
+	identifiable, err := indirectVariances(history, constraints, o, est)
+	if err != nil {
+		return nil, err
+	}
+
+	// Comparação com as tolerâncias configuradas.
+	for _, i := range measured {
+		if identifiable[i] {
+			est.Suggested[i] = est.Indirect[i]
+		} else {
+			est.Suggested[i] = est.Direct[i]
+			est.Unidentifiable = append(est.Unidentifiable, i)
+		}
+		u := o.uncertainty(i)
+		switch {
+		case o.covariance != nil:
+			est.Configured[i] = math.Sqrt(o.covariance.At(i, i))
+		case o.isFixed(i, tolerances[i]):
+			est.Configured[i] = 0
+		default:
+			if est.Configured[i], err = deviation(i, est.Means[i], tolerances[i], u); err != nil {
+				return nil, err
+			}
+		}
+		if est.Configured[i] > 0 {
+			est.Ratios[i] = est.Suggested[i] / est.Configured[i]
+		}
+		if o.covariance == nil {
+			est.SuggestedTolerances[i] = tolerance(est.Suggested[i], est.Means[i], u)
+		}
+	}
+	return est, nil
+}
+
+// indirectVariances estima as variâncias pelo método indireto e as grava em est.Indirect. O
+// retorno indica, para cada variável, se a sua variância é identificável.
+func indirectVariances(history [][]float64, constraints *mat.Dense, o options, est *VarianceEstimate) ([]bool, error) {
+	numConstraints, n := constraints.Dims()
+	identifiable := make([]bool, n)
+	pr := newProjection(constraints, o.unmeasured, nil)
+	if pr.reduced == nil {
+		return identifiable, nil
+	}
+	numReduced := pr.rows()
+	numMeasured := len(pr.measured)
+
+	// Resíduos projetados r_t = P*B_M*(m_t - média), e a sua covariância amostral S.
+	residuals := make([][]float64, len(history))
+	for t, m := range history {
+		raw := make([]float64, numConstraints)
+		for k := range raw {
+			for a, j := range pr.measured {
+				raw[k] += pr.bMeasured.At(k, a) * (m[j] - est.Means[j])
+			}
+		}
+		residuals[t] = pr.project(raw)
+	}
+	dof := float64(len(history) - 1)
+
+	// Uma equação por elemento (k, l), k <= l, de S: Σ_a A_ka*A_la*σ_a^2 = S_kl.
+	numEquations := numReduced * (numReduced + 1) / 2
+	design := mat.NewDense(numEquations, numMeasured, nil)
+	sampled := mat.NewVecDense(numEquations, nil)
+	row := 0
+	for k := 0; k < numReduced; k++ {
+		for l := k; l < numReduced; l++ {
+			var s float64
+			for _, r := range residuals {
+				s += r[k] * r[l]
+			}
+			sampled.SetVec(row, s/dof)
+			for a := 0; a < numMeasured; a++ {
+				design.Set(row, a, pr.reduced.At(k, a)*pr.reduced.At(l, a))
+			}
+			row++
+		}
+	}
+
+	// Solução de norma mínima pela pseudoinversa; só as componentes fora do núcleo são únicas.
+	var svd mat.SVD
+	if ok := svd.Factorize(design, mat.SVDFull); !ok {
+		return nil, errors.New("falha na decomposição em valores singulares do método indireto")
+	}
+	var u, v mat.Dense
+	svd.UTo(&u)
+	svd.VTo(&v)
+	singular := svd.Values(nil)
+	rank := numericalRank(singular)
+	variances := make([]float64, numMeasured)
+	for r := 0; r < rank; r++ {
+		coefficient := mat.Dot(u.ColView(r), sampled) / singular[r]
+		for a := range variances {
+			variances[a] += coefficient * v.At(a, r)
+		}
+	}
+	for a, i := range pr.measured {
+		norm := 0.0
+		for r := rank; r < numMeasured; r++ {
+			norm += v.At(a, r) * v.At(a, r)
+		}
+		if math.Sqrt(norm) >= identifiabilityTolerance {
+			continue
+		}
+		identifiable[i] = true
+		est.Indirect[i] = math.Sqrt(math.Max(variances[a], 0))
+	}
+	return identifiable, nil
+}
+
+// tolerance é a inversa de deviation: a tolerância que, no modo de incerteza e com o fator de
+// abrangência de u, corresponde ao desvio padrão sigma. NaN quando não há tolerância
+// equivalente (modo relativo com medição zero ou faixa inválida).
+func tolerance(sigma, measurement float64, u Uncertainty) float64 {
+	scaled := sigma * u.CoverageFactor
+	switch u.Mode {
+	case UncertaintyRelative:
+		if measurement != 0 {
+			return scaled / math.Abs(measurement)
+		}
+	case UncertaintyAbsolute:
+		return scaled
+	case UncertaintySpan:
+		if u.RangeMax > u.RangeMin {
+			return scaled / (u.RangeMax - u.RangeMin)
+		}
+	}
+	return math.NaN()
+}
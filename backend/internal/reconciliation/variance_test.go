@@ -0,0 +1,126 @@
+package reconciliation
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// varianceHistory simula um histórico da rede de networkConstraints com variação do processo
+// (a alimentação e a divisão mudam a cada amostra) e erros de medição de desvios sigma.
+func varianceHistory(numSamples int, sigma []float64) [][]float64 {
+	rng := rand.New(rand.NewSource(1))
+	history := make([][]float64, numSamples)
+	for t := range history {
+		feed := 100 + 15*rng.NormFloat64()
+		split := 0.6 + 0.05*rng.NormFloat64()
+		x := []float64{feed, split * feed, (1 - split) * feed, (1 - split) * feed, feed}
+		history[t] = make([]float64, len(x))
+		for i := range x {
+			history[t][i] = x[i] + sigma[i]*rng.NormFloat64()
+		}
+	}
+	return history
+}
+
+func TestEstimateVariances(t *testing.T) {
+	sigma := []float64{1, 0.5, 0.8, 0.4, 1.2}
+	tolerances := []float64{1, 1, 1, 1, 1}
+	absolute := WithUncertaintyMode(UncertaintyAbsolute)
+
+	t.Run("Método Indireto com Variação do Processo", func(t *testing.T) {
+		est, err := EstimateVariances(varianceHistory(4000, sigma), tolerances, networkConstraints(), absolute)
+		if err != nil {
+			t.Fatalf("EstimateVariances retornou um erro inesperado: %v", err)
+		}
+		if len(est.Unidentifiable) != 0 {
+			t.Fatalf("Todas as variâncias deveriam ser identificáveis: %v", est.Unidentifiable)
+		}
+		for i, s := range sigma {
+			if math.Abs(est.Indirect[i]-s) > 0.1*s {
+				t.Errorf("Desvio indireto incorreto na medição %d: esperado ~%v, obtido %v", i, s, est.Indirect[i])
+			}
+			// A variação do processo infla o método direto.
+			if est.Direct[i] < 3*s {
+				t.Errorf("O desvio direto da medição %d deveria incluir a variação do processo: %v", i, est.Direct[i])
+			}
+			if est.Suggested[i] != est.Indirect[i] || est.Ratios[i] != est.Suggested[i] || est.SuggestedTolerances[i] != est.Suggested[i] {
+				t.Errorf("Comparação incorreta na medição %d: sugerido %v, razão %v, tolerância %v", i, est.Suggested[i], est.Ratios[i], est.SuggestedTolerances[i])
+			}
+		}
+	})
+
+	t.Run("Método Direto em Regime Permanente", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(2))
+		history := make([][]float64, 1000)
+		for k := range history {
+			history[k] = []float64{100 + 2*rng.NormFloat64(), 100 + 0.5*rng.NormFloat64()}
+		}
+		est, err := EstimateVariances(history, []float64{0.01, 0.01}, mat.NewDense(1, 2, []float64{1, -1}), WithConfidence(0.99))
+		if err != nil {
+			t.Fatalf("EstimateVariances retornou um erro inesperado: %v", err)
+		}
+		// Duas medições em série de uma só restrição: só a soma das variâncias é identificável.
+		if !slices.Equal(est.Unidentifiable, []int{0, 1}) || !math.IsNaN(est.Indirect[0]) {
+			t.Fatalf("As variâncias não deveriam ser identificáveis: %v %v", est.Unidentifiable, est.Indirect)
+		}
+		for i, s := range []float64{2, 0.5} {
+			if est.Suggested[i] != est.Direct[i] || est.DirectLower[i] > s || est.DirectUpper[i] < s {
+				t.Errorf("Intervalo direto incorreto na medição %d: %v [%v, %v]", i, est.Direct[i], est.DirectLower[i], est.DirectUpper[i])
+			}
+		}
+		// Tolerância relativa de 1% sobre a média 100: σ configurado 1.
+		if math.Abs(est.Configured[0]-1) > 0.01 || math.Abs(est.Ratios[0]-est.Direct[0]/est.Configured[0]) > 1e-12 ||
+			math.Abs(est.SuggestedTolerances[0]-est.Direct[0]/math.Abs(est.Means[0])) > 1e-12 {
+			t.Errorf("Comparação incorreta: configurado %v, razão %v, tolerância %v", est.Configured[0], est.Ratios[0], est.SuggestedTolerances[0])
+		}
+		if math.Abs(est.DirectCovariance[0][1]-est.DirectCovariance[1][0]) > 1e-12 {
+			t.Errorf("A covariância direta deveria ser simétrica: %v", est.DirectCovariance)
+		}
+	})
+
+	t.Run("Variáveis Não Medidas", func(t *testing.T) {
+		history := varianceHistory(4000, sigma)
+		for _, m := range history {
+			m[2] = math.NaN()
+		}
+		est, err := EstimateVariances(history, tolerances, networkConstraints(), absolute, WithUnmeasured([]int{2}))
+		if err != nil {
+			t.Fatalf("EstimateVariances retornou um erro inesperado: %v", err)
+		}
+		if !math.IsNaN(est.Means[2]) || !math.IsNaN(est.Direct[2]) || !math.IsNaN(est.Suggested[2]) {
+			t.Errorf("A variável não medida não deveria ter estimativa: %v %v", est.Means[2], est.Suggested[2])
+		}
+		// Sem x2, restam as restrições x0 = x1 + x3 e x1 + x3 = x4: x0 e x4 são identificáveis.
+		for _, i := range []int{0, 4} {
+			if math.Abs(est.Indirect[i]-sigma[i]) > 0.1*sigma[i] {
+				t.Errorf("Desvio indireto incorreto na medição %d: esperado ~%v, obtido %v", i, sigma[i], est.Indirect[i])
+			}
+		}
+		if !slices.Equal(est.Unidentifiable, []int{1, 3}) {
+			t.Errorf("Medições não identificáveis incorretas: %v", est.Unidentifiable)
+		}
+	})
+
+	t.Run("Entradas Inválidas", func(t *testing.T) {
+		history := varianceHistory(10, sigma)
+		if _, err := EstimateVariances(history[:1], tolerances, networkConstraints()); err == nil {
+			t.Error("Esperado um erro para um histórico curto")
+		}
+		if _, err := EstimateVariances(history, tolerances[:4], networkConstraints()); err == nil {
+			t.Error("Esperado um erro para tolerâncias incompatíveis")
+		}
+		history[3] = history[3][:4]
+		if _, err := EstimateVariances(history, tolerances, networkConstraints()); err == nil {
+			t.Error("Esperado um erro para um vetor incompatível")
+		}
+		history = varianceHistory(10, sigma)
+		history[5][1] = math.NaN()
+		if _, err := EstimateVariances(history, tolerances, networkConstraints()); err == nil {
+			t.Error("Esperado um erro para uma medição NaN")
+		}
+	})
+}
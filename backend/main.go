@@ -28,6 +28,11 @@ func main() {
 	http.Handle("/api/login", middleware.LoggingMiddleware(middleware.ErrorHandler(handlers.Login)))
 	http.Handle("/api/current-values", middleware.LoggingMiddleware(middleware.ErrorHandler(handlers.GetCurrentValues)))
 	http.Handle("/api/reconcile", middleware.LoggingMiddleware(middleware.AuthMiddleware(middleware.ErrorHandler(handlers.ReconcileData))))
+	http.Handle("/api/reconcile/sensitivity", middleware.LoggingMiddleware(middleware.AuthMiddleware(middleware.ErrorHandler(handlers.Sensitivity))))
+	http.Handle("/api/reconcile/design", middleware.LoggingMiddleware(middleware.AuthMiddleware(middleware.ErrorHandler(handlers.DesignSensors))))
+	http.Handle("/api/reconcile/batch", middleware.LoggingMiddleware(middleware.AuthMiddleware(middleware.ErrorHandler(handlers.ReconcileBatch))))
+	http.Handle("/api/reconcile/dynamic", middleware.LoggingMiddleware(middleware.AuthMiddleware(middleware.ErrorHandler(handlers.ReconcileDynamicData))))
+	http.Handle("/api/reconcile/variance", middleware.LoggingMiddleware(middleware.AuthMiddleware(middleware.ErrorHandler(handlers.EstimateVariances))))
 	http.Handle("/healthz", middleware.LoggingMiddleware(middleware.ErrorHandler(handlers.HealthCheck)))
 
 	// Obtém a porta da variável de ambiente PORT ou usa "8080" como padrão.